@@ -0,0 +1,418 @@
+package alexa
+
+// Directive types
+const (
+	DialogDelegateType              = "Dialog.Delegate"
+	DialogElicitSlotType            = "Dialog.ElicitSlot"
+	DialogConfirmSlotType           = "Dialog.ConfirmSlot"
+	DialogConfirmIntentType         = "Dialog.ConfirmIntent"
+	DialogUpdateDynamicEntitiesType = "Dialog.UpdateDynamicEntities"
+	APLRenderDocumentType           = "Alexa.Presentation.APL.RenderDocument"
+	APLExecuteCommandsType          = "Alexa.Presentation.APL.ExecuteCommands"
+	APLSendIndexListDataType        = "Alexa.Presentation.APL.SendIndexListData"
+	ConnectionsSendRequestType      = "Connections.SendRequest"
+	ConnectionsStartConnectionType  = "Connections.StartConnection"
+	AudioPlayerPlayType             = "AudioPlayer.Play"
+	AudioPlayerStopType             = "AudioPlayer.Stop"
+	AudioPlayerClearQueueType       = "AudioPlayer.ClearQueue"
+	VideoAppLaunchType              = "VideoApp.Launch"
+)
+
+// A directive sent in a response. The Type field of every directive must be
+// set; the New... constructors do this.
+type Directive interface {
+	DirectiveType() string
+}
+
+// Returns an empty directive of the given type to unmarshal into, or nil for
+// types this package doesn't model
+func newDirective(directiveType string) Directive {
+	switch directiveType {
+	case DialogDelegateType:
+		return &DialogDelegate{}
+	case DialogElicitSlotType:
+		return &DialogElicitSlot{}
+	case DialogConfirmSlotType:
+		return &DialogConfirmSlot{}
+	case DialogConfirmIntentType:
+		return &DialogConfirmIntent{}
+	case DialogUpdateDynamicEntitiesType:
+		return &DialogUpdateDynamicEntities{}
+	case APLRenderDocumentType:
+		return &APLRenderDocument{}
+	case APLExecuteCommandsType:
+		return &APLExecuteCommands{}
+	case APLSendIndexListDataType:
+		return &APLSendIndexListData{}
+	case ConnectionsSendRequestType:
+		return &ConnectionsSendRequest{}
+	case ConnectionsStartConnectionType:
+		return &ConnectionsStartConnection{}
+	case AudioPlayerPlayType:
+		return &AudioPlayerPlay{}
+	case AudioPlayerStopType:
+		return &AudioPlayerStop{}
+	case AudioPlayerClearQueueType:
+		return &AudioPlayerClearQueue{}
+	case VideoAppLaunchType:
+		return &VideoAppLaunch{}
+	}
+	return nil
+}
+
+// A directive of a type this package doesn't model, kept field by field
+type UnknownDirective map[string]Value
+
+func (directive UnknownDirective) DirectiveType() string {
+	return directive["type"].String
+}
+
+// Hands the next turn of the dialog back to Alexa
+type DialogDelegate struct {
+	Type          string  `json:"type"`
+	UpdatedIntent *Intent `json:"updatedIntent,omitempty"`
+}
+
+func NewDialogDelegate(updatedIntent *Intent) *DialogDelegate {
+	return &DialogDelegate{Type: DialogDelegateType, UpdatedIntent: updatedIntent}
+}
+
+func (directive *DialogDelegate) DirectiveType() string { return directive.Type }
+
+// Asks the user for the value of a slot
+type DialogElicitSlot struct {
+	Type          string  `json:"type"`
+	SlotToElicit  string  `json:"slotToElicit"`
+	UpdatedIntent *Intent `json:"updatedIntent,omitempty"`
+}
+
+func NewDialogElicitSlot(slot string, updatedIntent *Intent) *DialogElicitSlot {
+	return &DialogElicitSlot{Type: DialogElicitSlotType, SlotToElicit: slot, UpdatedIntent: updatedIntent}
+}
+
+func (directive *DialogElicitSlot) DirectiveType() string { return directive.Type }
+
+// Asks the user to confirm the value of a slot
+type DialogConfirmSlot struct {
+	Type          string  `json:"type"`
+	SlotToConfirm string  `json:"slotToConfirm"`
+	UpdatedIntent *Intent `json:"updatedIntent,omitempty"`
+}
+
+func NewDialogConfirmSlot(slot string, updatedIntent *Intent) *DialogConfirmSlot {
+	return &DialogConfirmSlot{Type: DialogConfirmSlotType, SlotToConfirm: slot, UpdatedIntent: updatedIntent}
+}
+
+func (directive *DialogConfirmSlot) DirectiveType() string { return directive.Type }
+
+// Asks the user to confirm the whole intent before it is fulfilled
+type DialogConfirmIntent struct {
+	Type          string  `json:"type"`
+	UpdatedIntent *Intent `json:"updatedIntent,omitempty"`
+}
+
+func NewDialogConfirmIntent(updatedIntent *Intent) *DialogConfirmIntent {
+	return &DialogConfirmIntent{Type: DialogConfirmIntentType, UpdatedIntent: updatedIntent}
+}
+
+func (directive *DialogConfirmIntent) DirectiveType() string { return directive.Type }
+
+// Replaces or clears the dynamic entities of slot types for the session
+type DialogUpdateDynamicEntities struct {
+	Type           string       `json:"type"`
+	UpdateBehavior string       `json:"updateBehavior"`
+	Types          []EntityType `json:"types,omitempty"`
+}
+
+type EntityType struct {
+	Name   string        `json:"name"`
+	Values []EntityValue `json:"values"`
+}
+
+type EntityValue struct {
+	ID   string     `json:"id,omitempty"`
+	Name EntityName `json:"name"`
+}
+
+type EntityName struct {
+	Value    string   `json:"value"`
+	Synonyms []string `json:"synonyms,omitempty"`
+}
+
+// Replaces the dynamic entities of the given slot types with these values
+func NewDialogReplaceDynamicEntities(types ...EntityType) *DialogUpdateDynamicEntities {
+	return &DialogUpdateDynamicEntities{Type: DialogUpdateDynamicEntitiesType, UpdateBehavior: "REPLACE", Types: types}
+}
+
+func (directive *DialogUpdateDynamicEntities) DirectiveType() string { return directive.Type }
+
+// Renders an APL document on a screen device
+type APLRenderDocument struct {
+	Type        string                 `json:"type"`
+	Token       string                 `json:"token,omitempty"`
+	Document    APLDocument            `json:"document"`
+	Datasources map[string]Value       `json:"datasources,omitempty"`
+	Sources     map[string]APLDocument `json:"sources,omitempty"`
+}
+
+func NewAPLRenderDocument(token string, document APLDocument, datasources map[string]Value) *APLRenderDocument {
+	return &APLRenderDocument{Type: APLRenderDocumentType, Token: token, Document: document, Datasources: datasources}
+}
+
+func (directive *APLRenderDocument) DirectiveType() string { return directive.Type }
+
+// An APL document, or with Type "Link" a reference by Src to one saved in
+// the developer console. Styles, layouts, resources and the other parts of a
+// document are kept in Properties.
+type APLDocument struct {
+	Type         string           `json:"type"`
+	Version      string           `json:"version,omitempty"`
+	Src          string           `json:"src,omitempty"`
+	Description  string           `json:"description,omitempty"`
+	Theme        string           `json:"theme,omitempty"`
+	Import       []APLImport      `json:"import,omitempty"`
+	OnMount      []APLCommand     `json:"onMount,omitempty"`
+	MainTemplate *APLTemplate     `json:"mainTemplate,omitempty"`
+	Properties   map[string]Value `json:"-"`
+}
+
+type aplDocument APLDocument
+
+func (document APLDocument) MarshalJSON() ([]byte, error) {
+	return marshalWithProperties(aplDocument(document), document.Properties)
+}
+
+func (document *APLDocument) UnmarshalJSON(data []byte) error {
+	return unmarshalWithProperties(data, (*aplDocument)(document), &document.Properties,
+		"type", "version", "src", "description", "theme", "import", "onMount", "mainTemplate")
+}
+
+// A package of layouts and styles an APL document imports, such as alexa-layouts
+type APLImport struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Source  string `json:"source,omitempty"`
+}
+
+// The layout an APL document is rendered with. Parameters name the data
+// sources bound into the components.
+type APLTemplate struct {
+	Parameters []string       `json:"parameters,omitempty"`
+	Item       *APLComponent  `json:"item,omitempty"`
+	Items      []APLComponent `json:"items,omitempty"`
+}
+
+// A component of an APL document, such as a Container, Text or Image. The
+// properties that depend on Type, such as text or source, are in Properties.
+type APLComponent struct {
+	Type       string           `json:"type"`
+	ID         string           `json:"id,omitempty"`
+	When       string           `json:"when,omitempty"`
+	Item       *APLComponent    `json:"item,omitempty"`
+	Items      []APLComponent   `json:"items,omitempty"`
+	Data       *Value           `json:"data,omitempty"`
+	OnPress    []APLCommand     `json:"onPress,omitempty"`
+	Properties map[string]Value `json:"-"`
+}
+
+type aplComponent APLComponent
+
+func (component APLComponent) MarshalJSON() ([]byte, error) {
+	return marshalWithProperties(aplComponent(component), component.Properties)
+}
+
+func (component *APLComponent) UnmarshalJSON(data []byte) error {
+	return unmarshalWithProperties(data, (*aplComponent)(component), &component.Properties,
+		"type", "id", "when", "item", "items", "data", "onPress")
+}
+
+// An APL command, such as SendEvent, SpeakItem or SetValue. The properties
+// that depend on Type, such as property and value, are in Properties.
+type APLCommand struct {
+	Type        string           `json:"type"`
+	ComponentID string           `json:"componentId,omitempty"`
+	When        string           `json:"when,omitempty"`
+	Delay       int              `json:"delay,omitempty"`
+	Arguments   []Value          `json:"arguments,omitempty"`
+	Commands    []APLCommand     `json:"commands,omitempty"`
+	Properties  map[string]Value `json:"-"`
+}
+
+type aplCommand APLCommand
+
+func (command APLCommand) MarshalJSON() ([]byte, error) {
+	return marshalWithProperties(aplCommand(command), command.Properties)
+}
+
+func (command *APLCommand) UnmarshalJSON(data []byte) error {
+	return unmarshalWithProperties(data, (*aplCommand)(command), &command.Properties,
+		"type", "componentId", "when", "delay", "arguments", "commands")
+}
+
+// Runs APL commands against the document identified by Token
+type APLExecuteCommands struct {
+	Type     string       `json:"type"`
+	Token    string       `json:"token"`
+	Commands []APLCommand `json:"commands"`
+}
+
+func NewAPLExecuteCommands(token string, commands ...APLCommand) *APLExecuteCommands {
+	return &APLExecuteCommands{Type: APLExecuteCommandsType, Token: token, Commands: commands}
+}
+
+func (directive *APLExecuteCommands) DirectiveType() string { return directive.Type }
+
+// Answers an Alexa.Presentation.APL.LoadIndexListData request
+type APLSendIndexListData struct {
+	Type                  string  `json:"type"`
+	CorrelationToken      string  `json:"correlationToken,omitempty"`
+	ListID                string  `json:"listId"`
+	ListVersion           int     `json:"listVersion,omitempty"`
+	StartIndex            int     `json:"startIndex"`
+	MinimumInclusiveIndex *int    `json:"minimumInclusiveIndex,omitempty"`
+	MaximumExclusiveIndex *int    `json:"maximumExclusiveIndex,omitempty"`
+	Items                 []Value `json:"items"`
+}
+
+func (directive *APLSendIndexListData) DirectiveType() string { return directive.Type }
+
+// Hands the user off to a skill connection or Amazon task, such as purchases
+type ConnectionsSendRequest struct {
+	Type    string     `json:"type"`
+	Name    string     `json:"name"`
+	Payload *TaskInput `json:"payload,omitempty"`
+	Token   string     `json:"token,omitempty"`
+}
+
+func NewConnectionsSendRequest(name string, payload *TaskInput, token string) *ConnectionsSendRequest {
+	return &ConnectionsSendRequest{Type: ConnectionsSendRequestType, Name: name, Payload: payload, Token: token}
+}
+
+func (directive *ConnectionsSendRequest) DirectiveType() string { return directive.Type }
+
+// Starts a task, such as scheduling a reminder, identified by URI
+type ConnectionsStartConnection struct {
+	Type         string     `json:"type"`
+	URI          string     `json:"uri"`
+	Input        *TaskInput `json:"input,omitempty"`
+	Token        string     `json:"token,omitempty"`
+	OnCompletion string     `json:"onCompletion,omitempty"`
+}
+
+func NewConnectionsStartConnection(uri string, input *TaskInput, token string) *ConnectionsStartConnection {
+	return &ConnectionsStartConnection{Type: ConnectionsStartConnectionType, URI: uri, Input: input, Token: token}
+}
+
+func (directive *ConnectionsStartConnection) DirectiveType() string { return directive.Type }
+
+// What a Connections directive passes to the purchase, permission request or
+// other task it starts. The fields of tasks this package doesn't model are in
+// Properties.
+type TaskInput struct {
+	// AskForPermissionsConsent
+	Type             string            `json:"@type,omitempty"`
+	Version          string            `json:"@version,omitempty"`
+	PermissionScope  string            `json:"permissionScope,omitempty"`
+	PermissionScopes []PermissionScope `json:"permissionScopes,omitempty"`
+
+	// Buy, Upsell and Cancel
+	InSkillProduct *InSkillProduct `json:"InSkillProduct,omitempty"`
+	UpsellMessage  string          `json:"upsellMessage,omitempty"`
+
+	Properties map[string]Value `json:"-"`
+}
+
+type PermissionScope struct {
+	PermissionScope string `json:"permissionScope"`
+	ConsentLevel    string `json:"consentLevel,omitempty"`
+}
+
+type InSkillProduct struct {
+	ProductID string `json:"productId"`
+}
+
+type taskInput TaskInput
+
+func (input TaskInput) MarshalJSON() ([]byte, error) {
+	return marshalWithProperties(taskInput(input), input.Properties)
+}
+
+func (input *TaskInput) UnmarshalJSON(data []byte) error {
+	return unmarshalWithProperties(data, (*taskInput)(input), &input.Properties,
+		"@type", "@version", "permissionScope", "permissionScopes", "InSkillProduct", "upsellMessage")
+}
+
+// Plays an audio stream
+type AudioPlayerPlay struct {
+	Type         string    `json:"type"`
+	PlayBehavior string    `json:"playBehavior"`
+	AudioItem    AudioItem `json:"audioItem"`
+}
+
+type AudioItem struct {
+	Stream   AudioStream    `json:"stream"`
+	Metadata *AudioMetadata `json:"metadata,omitempty"`
+}
+
+type AudioStream struct {
+	URL                   string `json:"url"`
+	Token                 string `json:"token"`
+	ExpectedPreviousToken string `json:"expectedPreviousToken,omitempty"`
+	OffsetInMilliseconds  int64  `json:"offsetInMilliseconds"`
+}
+
+type AudioMetadata struct {
+	Title    string `json:"title,omitempty"`
+	Subtitle string `json:"subtitle,omitempty"`
+}
+
+func NewAudioPlayerPlay(playBehavior string, url string, token string, offset int64) *AudioPlayerPlay {
+	return &AudioPlayerPlay{
+		Type:         AudioPlayerPlayType,
+		PlayBehavior: playBehavior,
+		AudioItem:    AudioItem{Stream: AudioStream{URL: url, Token: token, OffsetInMilliseconds: offset}},
+	}
+}
+
+func (directive *AudioPlayerPlay) DirectiveType() string { return directive.Type }
+
+type AudioPlayerStop struct {
+	Type string `json:"type"`
+}
+
+func NewAudioPlayerStop() *AudioPlayerStop {
+	return &AudioPlayerStop{Type: AudioPlayerStopType}
+}
+
+func (directive *AudioPlayerStop) DirectiveType() string { return directive.Type }
+
+type AudioPlayerClearQueue struct {
+	Type          string `json:"type"`
+	ClearBehavior string `json:"clearBehavior"`
+}
+
+func NewAudioPlayerClearQueue(clearBehavior string) *AudioPlayerClearQueue {
+	return &AudioPlayerClearQueue{Type: AudioPlayerClearQueueType, ClearBehavior: clearBehavior}
+}
+
+func (directive *AudioPlayerClearQueue) DirectiveType() string { return directive.Type }
+
+// Plays a video on devices that support VideoApp
+type VideoAppLaunch struct {
+	Type      string    `json:"type"`
+	VideoItem VideoItem `json:"videoItem"`
+}
+
+type VideoItem struct {
+	Source   string         `json:"source"`
+	Metadata *AudioMetadata `json:"metadata,omitempty"`
+}
+
+func NewVideoAppLaunch(source string, title string, subtitle string) *VideoAppLaunch {
+	return &VideoAppLaunch{
+		Type:      VideoAppLaunchType,
+		VideoItem: VideoItem{Source: source, Metadata: &AudioMetadata{Title: title, Subtitle: subtitle}},
+	}
+}
+
+func (directive *VideoAppLaunch) DirectiveType() string { return directive.Type }
@@ -0,0 +1,120 @@
+package alexa
+
+// The name of the intent, or an empty string for requests without one
+func (body RequestBody) IntentName() string {
+	if body.Intent == nil {
+		return ""
+	}
+	return body.Intent.Name
+}
+
+// The spoken value of a slot, or an empty string when it wasn't filled
+func (body RequestBody) SlotValue(name string) string {
+	if body.Intent == nil {
+		return ""
+	}
+	return body.Intent.Slots[name].Value
+}
+
+// The canonical value entity resolution matched the slot to, falling back to
+// the spoken value when nothing matched
+func (slot Slot) ResolvedValue() string {
+	if slot.Resolutions != nil {
+		for _, resolution := range slot.Resolutions.ResolutionsPerAuthority {
+			if resolution.Status.Code == "ER_SUCCESS_MATCH" && len(resolution.Values) > 0 {
+				return resolution.Values[0].Value.Name
+			}
+		}
+	}
+	return slot.Value
+}
+
+// The application the request was sent to, read from the context when the
+// request has no session
+func (request Request) ApplicationID() string {
+	if request.Context != nil && request.Context.System.Application.ApplicationID != "" {
+		return request.Context.System.Application.ApplicationID
+	}
+	if request.Session != nil {
+		return request.Session.Application.ApplicationID
+	}
+	return ""
+}
+
+// The user the request was made on behalf of
+func (request Request) User() User {
+	if request.Context != nil && request.Context.System.User.UserID != "" {
+		return request.Context.System.User
+	}
+	if request.Session != nil {
+		return request.Session.User
+	}
+	return User{}
+}
+
+// The supported interfaces of the device that made the request
+func (request Request) SupportedInterfaces() SupportedInterfaces {
+	if request.Context == nil {
+		return SupportedInterfaces{}
+	}
+	return request.Context.System.Device.SupportedInterfaces
+}
+
+// Reports whether the device can render APL documents
+func (request Request) SupportsAPL() bool {
+	return request.SupportedInterfaces().APL != nil
+}
+
+// Reports whether the device can play long-form audio
+func (request Request) SupportsAudioPlayer() bool {
+	return request.SupportedInterfaces().AudioPlayer != nil
+}
+
+// Reports whether the device can play video
+func (request Request) SupportsVideo() bool {
+	return request.SupportedInterfaces().VideoApp != nil
+}
+
+// The screen of the device, or nil for devices without one
+func (request Request) Viewport() *Viewport {
+	if request.Context == nil {
+		return nil
+	}
+	return request.Context.Viewport
+}
+
+// Viewport shapes and modes
+const (
+	ShapeRound        = "ROUND"
+	ShapeRectangle    = "RECTANGLE"
+	ModeHub           = "HUB"
+	ModeTV            = "TV"
+	ModeMobile        = "MOBILE"
+	ModePC            = "PC"
+	ModeAuto          = "AUTO"
+	TouchSingle       = "SINGLE"
+	KeyboardDirection = "DIRECTION"
+)
+
+// Reports whether the screen is round, as on the Echo Spot
+func (viewport *Viewport) IsRound() bool {
+	return viewport != nil && viewport.Shape == ShapeRound
+}
+
+// Reports whether the screen accepts touch input
+func (viewport *Viewport) IsTouch() bool {
+	if viewport == nil {
+		return false
+	}
+	for _, touch := range viewport.Touch {
+		if touch == TouchSingle {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether the screen is currently wider than it is tall
+func (viewport *Viewport) IsLandscape() bool {
+	return viewport != nil && viewport.CurrentPixelWidth > viewport.CurrentPixelHeight
+}
@@ -0,0 +1,414 @@
+// Package alexa models the JSON requests Alexa sends to a custom skill and
+// the responses the skill sends back.
+package alexa
+
+// Request types sent to custom skills
+const (
+	LaunchRequest                 = "LaunchRequest"
+	IntentRequest                 = "IntentRequest"
+	SessionEndedRequest           = "SessionEndedRequest"
+	SessionResumedRequest         = "SessionResumedRequest"
+	CanFulfillIntentRequest       = "CanFulfillIntentRequest"
+	ConnectionsResponse           = "Connections.Response"
+	APLUserEvent                  = "Alexa.Presentation.APL.UserEvent"
+	APLLoadIndexListData          = "Alexa.Presentation.APL.LoadIndexListData"
+	AudioPlaybackStarted          = "AudioPlayer.PlaybackStarted"
+	AudioPlaybackFinished         = "AudioPlayer.PlaybackFinished"
+	AudioPlaybackStopped          = "AudioPlayer.PlaybackStopped"
+	AudioPlaybackNearlyFinished   = "AudioPlayer.PlaybackNearlyFinished"
+	AudioPlaybackFailed           = "AudioPlayer.PlaybackFailed"
+	PlaybackNextCommandIssued     = "PlaybackController.NextCommandIssued"
+	PlaybackPauseCommandIssued    = "PlaybackController.PauseCommandIssued"
+	PlaybackPlayCommandIssued     = "PlaybackController.PlayCommandIssued"
+	PlaybackPreviousCommandIssued = "PlaybackController.PreviousCommandIssued"
+	MessagingMessageReceived      = "Messaging.MessageReceived"
+	SystemExceptionEncountered    = "System.ExceptionEncountered"
+)
+
+// Dialog states of an IntentRequest
+const (
+	DialogStarted    = "STARTED"
+	DialogInProgress = "IN_PROGRESS"
+	DialogCompleted  = "COMPLETED"
+)
+
+// Confirmation statuses of intents and slots
+const (
+	ConfirmationNone      = "NONE"
+	ConfirmationConfirmed = "CONFIRMED"
+	ConfirmationDenied    = "DENIED"
+)
+
+// The envelope of every request sent to the skill
+type Request struct {
+	Version string      `json:"version"`
+	Session *Session    `json:"session,omitempty"`
+	Context *Context    `json:"context,omitempty"`
+	Body    RequestBody `json:"request"`
+}
+
+// Describes the conversation the request belongs to. Requests sent outside
+// of a session, such as AudioPlayer events, don't carry one.
+type Session struct {
+	New         bool             `json:"new"`
+	SessionID   string           `json:"sessionId"`
+	Application Application      `json:"application"`
+	Attributes  map[string]Value `json:"attributes,omitempty"`
+	User        User             `json:"user"`
+}
+
+type Application struct {
+	ApplicationID string `json:"applicationId"`
+}
+
+// The Amazon account that enabled the skill
+type User struct {
+	UserID      string       `json:"userId"`
+	AccessToken string       `json:"accessToken,omitempty"`
+	Permissions *Permissions `json:"permissions,omitempty"`
+}
+
+type Permissions struct {
+	ConsentToken string `json:"consentToken,omitempty"`
+}
+
+// A voice profile recognized by the device
+type Person struct {
+	PersonID    string `json:"personId"`
+	AccessToken string `json:"accessToken,omitempty"`
+}
+
+// The state of the device and its interfaces when the request was made
+type Context struct {
+	System      System               `json:"System"`
+	AudioPlayer *AudioPlayerState    `json:"AudioPlayer,omitempty"`
+	Viewport    *Viewport            `json:"Viewport,omitempty"`
+	Viewports   []ViewportDescriptor `json:"Viewports,omitempty"`
+	Geolocation *Geolocation         `json:"Geolocation,omitempty"`
+	APL         *APLContext          `json:"Alexa.Presentation.APL,omitempty"`
+}
+
+type System struct {
+	Application    Application `json:"application"`
+	User           User        `json:"user"`
+	Person         *Person     `json:"person,omitempty"`
+	Unit           *Unit       `json:"unit,omitempty"`
+	Device         Device      `json:"device"`
+	APIEndpoint    string      `json:"apiEndpoint"`
+	APIAccessToken string      `json:"apiAccessToken,omitempty"`
+}
+
+// The shared space, such as a hotel room, a device is registered to
+type Unit struct {
+	UnitID           string `json:"unitId"`
+	PersistentUnitID string `json:"persistentUnitId,omitempty"`
+}
+
+type Device struct {
+	DeviceID            string              `json:"deviceId"`
+	SupportedInterfaces SupportedInterfaces `json:"supportedInterfaces"`
+}
+
+// The interfaces a device supports. An interface is supported when its field
+// is present, even if it carries no details.
+type SupportedInterfaces struct {
+	AudioPlayer *struct{}              `json:"AudioPlayer,omitempty"`
+	Display     *DisplayInterface      `json:"Display,omitempty"`
+	VideoApp    *struct{}              `json:"VideoApp,omitempty"`
+	Geolocation *struct{}              `json:"Geolocation,omitempty"`
+	APL         *PresentationInterface `json:"Alexa.Presentation.APL,omitempty"`
+	APLT        *PresentationInterface `json:"Alexa.Presentation.APLT,omitempty"`
+	HTML        *PresentationInterface `json:"Alexa.Presentation.HTML,omitempty"`
+}
+
+type DisplayInterface struct {
+	TemplateVersion string `json:"templateVersion,omitempty"`
+	MarkupVersion   string `json:"markupVersion,omitempty"`
+}
+
+type PresentationInterface struct {
+	Runtime *PresentationRuntime `json:"runtime,omitempty"`
+}
+
+type PresentationRuntime struct {
+	MaxVersion string `json:"maxVersion"`
+}
+
+type AudioPlayerState struct {
+	Token                string `json:"token,omitempty"`
+	OffsetInMilliseconds int64  `json:"offsetInMilliseconds"`
+	PlayerActivity       string `json:"playerActivity"`
+}
+
+// The screen of a multimodal device
+type Viewport struct {
+	Experiences        []ViewportExperience `json:"experiences,omitempty"`
+	Mode               string               `json:"mode,omitempty"`
+	Shape              string               `json:"shape,omitempty"`
+	PixelWidth         int                  `json:"pixelWidth"`
+	PixelHeight        int                  `json:"pixelHeight"`
+	DPI                int                  `json:"dpi"`
+	CurrentPixelWidth  int                  `json:"currentPixelWidth"`
+	CurrentPixelHeight int                  `json:"currentPixelHeight"`
+	Touch              []string             `json:"touch,omitempty"`
+	Keyboard           []string             `json:"keyboard,omitempty"`
+	Video              *ViewportVideo       `json:"video,omitempty"`
+}
+
+type ViewportExperience struct {
+	ArcMinuteWidth  int  `json:"arcMinuteWidth"`
+	ArcMinuteHeight int  `json:"arcMinuteHeight"`
+	CanRotate       bool `json:"canRotate"`
+	CanResize       bool `json:"canResize"`
+}
+
+type ViewportVideo struct {
+	Codecs []string `json:"codecs"`
+}
+
+// One of the viewports listed in Context.Viewports, such as an APL or APLT screen
+type ViewportDescriptor struct {
+	Type              string                 `json:"type"`
+	ID                string                 `json:"id,omitempty"`
+	Shape             string                 `json:"shape,omitempty"`
+	DPI               int                    `json:"dpi,omitempty"`
+	PresentationType  string                 `json:"presentationType,omitempty"`
+	CanRotate         *bool                  `json:"canRotate,omitempty"`
+	Configuration     *ViewportConfiguration `json:"configuration,omitempty"`
+	LineLength        int                    `json:"lineLength,omitempty"`
+	LineCount         int                    `json:"lineCount,omitempty"`
+	Format            string                 `json:"format,omitempty"`
+	InterSegments     []InterSegment         `json:"interSegments,omitempty"`
+	SupportedProfiles []string               `json:"supportedProfiles,omitempty"`
+}
+
+type ViewportConfiguration struct {
+	Current *ViewportMode `json:"current,omitempty"`
+}
+
+// The mode, video support and size an APL viewport currently has
+type ViewportMode struct {
+	Mode  string         `json:"mode,omitempty"`
+	Video *ViewportVideo `json:"video,omitempty"`
+	Size  *ViewportSize  `json:"size,omitempty"`
+}
+
+// The size of a viewport. DISCRETE viewports have a fixed size; CONTINUOUS
+// ones can be resized between the minimum and maximum.
+type ViewportSize struct {
+	Type               string `json:"type"`
+	PixelWidth         int    `json:"pixelWidth,omitempty"`
+	PixelHeight        int    `json:"pixelHeight,omitempty"`
+	MinimumPixelWidth  int    `json:"minimumPixelWidth,omitempty"`
+	MinimumPixelHeight int    `json:"minimumPixelHeight,omitempty"`
+	MaximumPixelWidth  int    `json:"maximumPixelWidth,omitempty"`
+	MaximumPixelHeight int    `json:"maximumPixelHeight,omitempty"`
+}
+
+// A separator between the segments of a character display, such as the
+// colon of a clock
+type InterSegment struct {
+	X          int    `json:"x"`
+	Y          int    `json:"y"`
+	Characters string `json:"characters"`
+}
+
+type Geolocation struct {
+	Timestamp  string      `json:"timestamp"`
+	Coordinate *Coordinate `json:"coordinate,omitempty"`
+}
+
+type Coordinate struct {
+	LatitudeInDegrees  float64 `json:"latitudeInDegrees"`
+	LongitudeInDegrees float64 `json:"longitudeInDegrees"`
+	AccuracyInMeters   float64 `json:"accuracyInMeters"`
+}
+
+// The APL document currently shown on the device
+type APLContext struct {
+	Token                     string             `json:"token,omitempty"`
+	Version                   string             `json:"version,omitempty"`
+	ComponentsVisibleOnScreen []VisibleComponent `json:"componentsVisibleOnScreen,omitempty"`
+}
+
+// A component of the APL document on screen, with the visible components it holds
+type VisibleComponent struct {
+	ID       string             `json:"id,omitempty"`
+	UID      string             `json:"uid"`
+	Type     string             `json:"type"`
+	Position string             `json:"position,omitempty"`
+	Tags     ComponentTags      `json:"tags"`
+	Children []VisibleComponent `json:"children,omitempty"`
+	Entities []Value            `json:"entities,omitempty"`
+}
+
+// What the user can do with a visible component. Flags are only sent when set.
+type ComponentTags struct {
+	Checked    bool           `json:"checked,omitempty"`
+	Clickable  bool           `json:"clickable,omitempty"`
+	Disabled   bool           `json:"disabled,omitempty"`
+	Focused    bool           `json:"focused,omitempty"`
+	Spoken     bool           `json:"spoken,omitempty"`
+	Ordinal    int            `json:"ordinal,omitempty"`
+	List       *ListTag       `json:"list,omitempty"`
+	Media      *MediaTag      `json:"media,omitempty"`
+	Pager      *PagerTag      `json:"pager,omitempty"`
+	Scrollable *ScrollableTag `json:"scrollable,omitempty"`
+	Viewport   *struct{}      `json:"viewport,omitempty"`
+}
+
+type ListTag struct {
+	ItemCount          int `json:"itemCount,omitempty"`
+	LowestIndexSeen    int `json:"lowestIndexSeen"`
+	HighestIndexSeen   int `json:"highestIndexSeen"`
+	LowestOrdinalSeen  int `json:"lowestOrdinalSeen,omitempty"`
+	HighestOrdinalSeen int `json:"highestOrdinalSeen,omitempty"`
+}
+
+type MediaTag struct {
+	PositionInMilliseconds          int64  `json:"positionInMilliseconds"`
+	State                           string `json:"state"`
+	AllowAdjustSeekPositionForward  bool   `json:"allowAdjustSeekPositionForward"`
+	AllowAdjustSeekPositionBackward bool   `json:"allowAdjustSeekPositionBackwards"`
+	AllowNext                       bool   `json:"allowNext"`
+	AllowPrevious                   bool   `json:"allowPrevious"`
+	URL                             string `json:"url,omitempty"`
+}
+
+type PagerTag struct {
+	Index         int  `json:"index"`
+	PageCount     int  `json:"pageCount"`
+	AllowForward  bool `json:"allowForward"`
+	AllowBackward bool `json:"allowBackwards"`
+}
+
+type ScrollableTag struct {
+	Direction     string `json:"direction"`
+	AllowForward  bool   `json:"allowForward"`
+	AllowBackward bool   `json:"allowBackward"`
+}
+
+// The body of a request. Which fields are set depends on Type.
+type RequestBody struct {
+	Type        string  `json:"type"`
+	RequestID   string  `json:"requestId"`
+	Timestamp   string  `json:"timestamp"`
+	Locale      string  `json:"locale,omitempty"`
+	Intent      *Intent `json:"intent,omitempty"`
+	DialogState string  `json:"dialogState,omitempty"`
+
+	// SessionEndedRequest and System.ExceptionEncountered
+	Reason string `json:"reason,omitempty"`
+	Error  *Error `json:"error,omitempty"`
+	Cause  *Cause `json:"cause,omitempty"`
+
+	// Connections.Response and SessionResumedRequest
+	Name    string      `json:"name,omitempty"`
+	Status  *Status     `json:"status,omitempty"`
+	Payload *TaskResult `json:"payload,omitempty"`
+
+	// Alexa.Presentation.APL.UserEvent
+	Token      string           `json:"token,omitempty"`
+	Arguments  []Value          `json:"arguments,omitempty"`
+	Source     *EventSource     `json:"source,omitempty"`
+	Components map[string]Value `json:"components,omitempty"`
+
+	// Alexa.Presentation.APL.LoadIndexListData
+	CorrelationToken string `json:"correlationToken,omitempty"`
+	ListID           string `json:"listId,omitempty"`
+	StartIndex       *int   `json:"startIndex,omitempty"`
+	Count            *int   `json:"count,omitempty"`
+
+	// AudioPlayer events
+	OffsetInMilliseconds *int64            `json:"offsetInMilliseconds,omitempty"`
+	CurrentPlaybackState *AudioPlayerState `json:"currentPlaybackState,omitempty"`
+
+	// Messaging.MessageReceived
+	Message map[string]Value `json:"message,omitempty"`
+}
+
+type Intent struct {
+	Name               string          `json:"name"`
+	ConfirmationStatus string          `json:"confirmationStatus,omitempty"`
+	Slots              map[string]Slot `json:"slots,omitempty"`
+}
+
+type Slot struct {
+	Name               string       `json:"name"`
+	Value              string       `json:"value,omitempty"`
+	ConfirmationStatus string       `json:"confirmationStatus,omitempty"`
+	Source             string       `json:"source,omitempty"`
+	Resolutions        *Resolutions `json:"resolutions,omitempty"`
+	SlotValue          *SlotValue   `json:"slotValue,omitempty"`
+}
+
+// The value of a slot, which holds a list of values for multiple-value slots
+type SlotValue struct {
+	Type        string       `json:"type"`
+	Value       string       `json:"value,omitempty"`
+	Values      []SlotValue  `json:"values,omitempty"`
+	Resolutions *Resolutions `json:"resolutions,omitempty"`
+}
+
+// Entity resolution results for a slot value
+type Resolutions struct {
+	ResolutionsPerAuthority []Resolution `json:"resolutionsPerAuthority"`
+}
+
+type Resolution struct {
+	Authority string                 `json:"authority"`
+	Status    ResolutionStatus       `json:"status"`
+	Values    []ResolutionValueEntry `json:"values,omitempty"`
+}
+
+type ResolutionStatus struct {
+	Code string `json:"code"`
+}
+
+type ResolutionValueEntry struct {
+	Value ResolutionValue `json:"value"`
+}
+
+type ResolutionValue struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
+
+type Error struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+type Cause struct {
+	RequestID string      `json:"requestId,omitempty"`
+	Type      string      `json:"type,omitempty"`
+	Token     string      `json:"token,omitempty"`
+	Status    *Status     `json:"status,omitempty"`
+	Result    *TaskResult `json:"result,omitempty"`
+}
+
+// The component of an APL document that sent a UserEvent
+type EventSource struct {
+	Type    string `json:"type"`
+	Handler string `json:"handler"`
+	ID      string `json:"id,omitempty"`
+	Value   *Value `json:"value,omitempty"`
+}
+
+// What a purchase, permission request or other task the skill started with
+// a Connections directive returned. Which fields are set depends on the task.
+type TaskResult struct {
+	// Buy, Upsell and Cancel
+	PurchaseResult string `json:"purchaseResult,omitempty"`
+	ProductID      string `json:"productId,omitempty"`
+	Message        string `json:"message,omitempty"`
+
+	// AskForPermissionsConsent
+	PermissionScope string `json:"permissionScope,omitempty"`
+	Status          string `json:"status,omitempty"`
+}
+
+type Status struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
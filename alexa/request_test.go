@@ -0,0 +1,132 @@
+package alexa
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Unmarshals the sample in testdata into into, marshals it again and fails
+// unless the result holds the same JSON as the sample
+func roundTrip(t *testing.T, sample string, into interface{}) {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join("testdata", sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, into); err != nil {
+		t.Fatalf("%s: %v", sample, err)
+	}
+	marshalled, err := json.Marshal(into)
+	if err != nil {
+		t.Fatalf("%s: %v", sample, err)
+	}
+	var want, got interface{}
+	if err := json.Unmarshal(data, &want); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(marshalled, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("%s changed in the round trip:\ngot  %s\nwant %s", sample, marshalled, compact(t, data))
+	}
+}
+
+func compact(t *testing.T, data []byte) []byte {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		t.Fatal(err)
+	}
+	compacted, _ := json.Marshal(value)
+	return compacted
+}
+
+func TestLaunchRequestRoundTrip(t *testing.T) {
+	var request Request
+	roundTrip(t, "launch_request.json", &request)
+
+	if request.Body.Type != LaunchRequest || !request.Session.New {
+		t.Errorf("got a %s request, new session %v, want a LaunchRequest starting a session", request.Body.Type, request.Session.New)
+	}
+	if !request.SupportsAPL() || !request.SupportsAudioPlayer() || request.SupportsVideo() {
+		t.Error("got the wrong supported interfaces")
+	}
+	if viewport := request.Viewport(); !viewport.IsTouch() || !viewport.IsLandscape() || viewport.IsRound() {
+		t.Errorf("got viewport %+v, want a rectangular landscape touch screen", viewport)
+	}
+	if size := request.Context.Viewports[0].Configuration.Current.Size; size.PixelWidth != 1280 {
+		t.Errorf("got the current size %+v, want 1280 pixels wide", size)
+	}
+	if segment := request.Context.Viewports[1].InterSegments[0]; segment.Characters != "':" {
+		t.Errorf("got inter-segment %+v, want the clock's colon", segment)
+	}
+	list := request.Context.APL.ComponentsVisibleOnScreen[0].Children[0]
+	if list.ID != "recipes" || list.Tags.List.ItemCount != 12 || !list.Tags.Scrollable.AllowForward {
+		t.Errorf("got visible component %+v, want the scrollable list of 12 recipes", list)
+	}
+	if name := list.Children[0].Entities[0].Field("name").String; name != "Chicken Soup" {
+		t.Errorf("got entity name %q, want Chicken Soup", name)
+	}
+}
+
+func TestIntentRequestRoundTrip(t *testing.T) {
+	var request Request
+	roundTrip(t, "intent_request.json", &request)
+
+	if name := request.Body.IntentName(); name != "RecipesByIngredientsIntent" {
+		t.Errorf("got intent %q", name)
+	}
+	if value := request.Body.Intent.Slots["ingredient"].ResolvedValue(); value != "chicken" {
+		t.Errorf("got resolved value %q, want chicken", value)
+	}
+	if values := request.Body.Intent.Slots["extras"].SlotValue.Values; len(values) != 2 || values[1].Value != "leeks" {
+		t.Errorf("got list values %+v, want rice and leeks", values)
+	}
+	if value := request.Body.SlotValue("cuisine"); value != "" {
+		t.Errorf("got %q for an unfilled slot", value)
+	}
+	attributes := request.Session.Attributes
+	if attributes["cookingStep"].Number != 2 || attributes["stepTerms"].Array[1].String != "onion" || attributes["pendingPurchase"].Kind != NullValue {
+		t.Errorf("got session attributes %+v", attributes)
+	}
+	if recipe, ok := attributes["cookingRecipe"].Interface().(string); !ok || recipe != "Chicken Soup" {
+		t.Errorf("got recipe attribute %v, want the string Chicken Soup", attributes["cookingRecipe"].Interface())
+	}
+}
+
+func TestSessionEndedRequestRoundTrip(t *testing.T) {
+	var request Request
+	roundTrip(t, "session_ended_request.json", &request)
+
+	if request.Body.Reason != "ERROR" || request.Body.Error.Type != "INVALID_RESPONSE" {
+		t.Errorf("got reason %q and error %+v", request.Body.Reason, request.Body.Error)
+	}
+}
+
+func TestAPLUserEventRoundTrip(t *testing.T) {
+	var request Request
+	roundTrip(t, "apl_user_event.json", &request)
+
+	arguments := request.Body.Arguments
+	if len(arguments) != 5 || arguments[1].String != "Chicken Soup" || arguments[2].Number != 2 || !arguments[3].Bool || arguments[4].Field("servings").Number != 4 {
+		t.Errorf("got arguments %+v", arguments)
+	}
+	if source := request.Body.Source; source.Handler != "Press" || source.Value.Kind != BoolValue {
+		t.Errorf("got source %+v", source)
+	}
+	if components := request.Body.Components; components["servings"].String != "4" || !components["vegetarian"].Bool {
+		t.Errorf("got components %+v", components)
+	}
+}
+
+func TestConnectionsResponseRoundTrip(t *testing.T) {
+	var request Request
+	roundTrip(t, "connections_response.json", &request)
+
+	if payload := request.Body.Payload; payload.PurchaseResult != "ACCEPTED" || request.Body.Status.Code != "200" {
+		t.Errorf("got payload %+v and status %+v, want an accepted purchase", payload, request.Body.Status)
+	}
+}
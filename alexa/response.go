@@ -0,0 +1,134 @@
+package alexa
+
+import "encoding/json"
+
+// Output speech types
+const (
+	PlainText = "PlainText"
+	SSML      = "SSML"
+)
+
+// Card types
+const (
+	SimpleCard      = "Simple"
+	StandardCard    = "Standard"
+	LinkAccountCard = "LinkAccount"
+	PermissionsCard = "AskForPermissionsConsent"
+)
+
+// The envelope of every response sent back to Alexa
+type Response struct {
+	Version           string           `json:"version"`
+	SessionAttributes map[string]Value `json:"sessionAttributes,omitempty"`
+	Body              ResponseBody     `json:"response"`
+}
+
+type ResponseBody struct {
+	OutputSpeech     *OutputSpeech     `json:"outputSpeech,omitempty"`
+	Card             *Card             `json:"card,omitempty"`
+	Reprompt         *Reprompt         `json:"reprompt,omitempty"`
+	Directives       Directives        `json:"directives,omitempty"`
+	ShouldEndSession *bool             `json:"shouldEndSession,omitempty"`
+	CanFulfillIntent *CanFulfillIntent `json:"canFulfillIntent,omitempty"`
+}
+
+type OutputSpeech struct {
+	Type         string `json:"type"`
+	Text         string `json:"text,omitempty"`
+	SSML         string `json:"ssml,omitempty"`
+	PlayBehavior string `json:"playBehavior,omitempty"`
+}
+
+type Card struct {
+	Type        string   `json:"type"`
+	Title       string   `json:"title,omitempty"`
+	Content     string   `json:"content,omitempty"`
+	Text        string   `json:"text,omitempty"`
+	Image       *Image   `json:"image,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+type Image struct {
+	SmallImageURL string `json:"smallImageUrl,omitempty"`
+	LargeImageURL string `json:"largeImageUrl,omitempty"`
+}
+
+type Reprompt struct {
+	OutputSpeech *OutputSpeech `json:"outputSpeech,omitempty"`
+	Directives   Directives    `json:"directives,omitempty"`
+}
+
+// The answer to a CanFulfillIntentRequest
+type CanFulfillIntent struct {
+	CanFulfill string                    `json:"canFulfill"`
+	Slots      map[string]CanFulfillSlot `json:"slots,omitempty"`
+}
+
+type CanFulfillSlot struct {
+	CanUnderstand string `json:"canUnderstand"`
+	CanFulfill    string `json:"canFulfill"`
+}
+
+// Creates a plain text response with a simple card that ends the session
+func NewSimpleResponse(title string, text string) Response {
+	return Response{
+		Version: "1.0",
+		Body: ResponseBody{
+			OutputSpeech:     &OutputSpeech{Type: PlainText, Text: text},
+			Card:             &Card{Type: SimpleCard, Title: title, Content: text},
+			ShouldEndSession: Bool(true),
+		},
+	}
+}
+
+// Creates an SSML response that ends the session. The speech is wrapped in
+// <speak> tags.
+func NewSSMLResponse(title string, ssml string, cardText string) Response {
+	return Response{
+		Version: "1.0",
+		Body: ResponseBody{
+			OutputSpeech:     &OutputSpeech{Type: SSML, SSML: "<speak>" + ssml + "</speak>"},
+			Card:             &Card{Type: SimpleCard, Title: title, Content: cardText},
+			ShouldEndSession: Bool(true),
+		},
+	}
+}
+
+// Returns a pointer to value, for the optional booleans in responses
+func Bool(value bool) *bool {
+	return &value
+}
+
+// Directives in a response. Unmarshalling picks the concrete directive type
+// from the type field and keeps unknown directives as UnknownDirective.
+type Directives []Directive
+
+func (directives *Directives) UnmarshalJSON(data []byte) error {
+	var raws []json.RawMessage
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return err
+	}
+	*directives = nil
+	for _, raw := range raws {
+		var header struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(raw, &header); err != nil {
+			return err
+		}
+		directive := newDirective(header.Type)
+		if directive == nil {
+			unknown := UnknownDirective{}
+			if err := json.Unmarshal(raw, &unknown); err != nil {
+				return err
+			}
+			*directives = append(*directives, unknown)
+			continue
+		}
+		if err := json.Unmarshal(raw, directive); err != nil {
+			return err
+		}
+		*directives = append(*directives, directive)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package alexa
+
+import "testing"
+
+func TestResponseRoundTrip(t *testing.T) {
+	var response Response
+	roundTrip(t, "response.json", &response)
+
+	directives := response.Body.Directives
+	if len(directives) != 6 {
+		t.Fatalf("got %d directives, want 6", len(directives))
+	}
+	if elicit, ok := directives[0].(*DialogElicitSlot); !ok || elicit.SlotToElicit != "cuisine" {
+		t.Errorf("got %#v, want the cuisine slot elicited", directives[0])
+	}
+	render, ok := directives[1].(*APLRenderDocument)
+	if !ok {
+		t.Fatalf("got %#v, want an APL document", directives[1])
+	}
+	container := render.Document.MainTemplate.Items[0]
+	if container.Properties["width"].String != "100vw" || container.Items[1].OnPress[0].Arguments[1].Number != 3 {
+		t.Errorf("got container %+v", container)
+	}
+	if text := container.Items[0].Properties["text"].String; text != "${payload.step.text}" {
+		t.Errorf("got text %q", text)
+	}
+	commands := directives[2].(*APLExecuteCommands).Commands[0].Commands
+	if commands[1].Delay != 500 || commands[1].Properties["value"].String != "#FFD400" {
+		t.Errorf("got commands %+v", commands)
+	}
+	if upsell := directives[3].(*ConnectionsSendRequest); upsell.Payload.InSkillProduct.ProductID != "amzn1.adg.product.premium-recipes" {
+		t.Errorf("got upsell %+v", upsell.Payload)
+	}
+	if permissions := directives[4].(*ConnectionsStartConnection); permissions.Input.PermissionScopes[0].ConsentLevel != "ACCOUNT" {
+		t.Errorf("got permission request %+v", permissions.Input)
+	}
+	if unknown := directives[5]; unknown.DirectiveType() != "Alexa.Presentation.APLA.RenderDocument" {
+		t.Errorf("got %#v, want the APLA directive kept as it was", unknown)
+	}
+}
@@ -0,0 +1,203 @@
+package alexa
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Kinds of Value
+const (
+	NullValue   = "null"
+	StringValue = "string"
+	NumberValue = "number"
+	BoolValue   = "bool"
+	ObjectValue = "object"
+	ArrayValue  = "array"
+)
+
+// A JSON value whose shape is decided by the skill rather than by Alexa, such
+// as the arguments of an APL SendEvent command or the data sources of an APL
+// document. Kind says which of the other fields holds it.
+type Value struct {
+	Kind   string
+	String string
+	Number float64
+	Bool   bool
+	Object map[string]Value
+	Array  []Value
+}
+
+func StringOf(value string) Value {
+	return Value{Kind: StringValue, String: value}
+}
+
+func NumberOf(value float64) Value {
+	return Value{Kind: NumberValue, Number: value}
+}
+
+func BoolOf(value bool) Value {
+	return Value{Kind: BoolValue, Bool: value}
+}
+
+func ObjectOf(fields map[string]Value) Value {
+	return Value{Kind: ObjectValue, Object: fields}
+}
+
+func ArrayOf(values ...Value) Value {
+	return Value{Kind: ArrayValue, Array: values}
+}
+
+// The field of an object value, or a null value when it has none
+func (value Value) Field(name string) Value {
+	if field, ok := value.Object[name]; ok {
+		return field
+	}
+	return Value{Kind: NullValue}
+}
+
+func (value Value) MarshalJSON() ([]byte, error) {
+	switch value.Kind {
+	case StringValue:
+		return json.Marshal(value.String)
+	case NumberValue:
+		return json.Marshal(value.Number)
+	case BoolValue:
+		return json.Marshal(value.Bool)
+	case ObjectValue:
+		names := make([]string, 0, len(value.Object))
+		for name := range value.Object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var buffer bytes.Buffer
+		buffer.WriteByte('{')
+		for i, name := range names {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			key, _ := json.Marshal(name)
+			field, err := value.Object[name].MarshalJSON()
+			if err != nil {
+				return nil, err
+			}
+			buffer.Write(key)
+			buffer.WriteByte(':')
+			buffer.Write(field)
+		}
+		buffer.WriteByte('}')
+		return buffer.Bytes(), nil
+	case ArrayValue:
+		if value.Array == nil {
+			return []byte("[]"), nil
+		}
+		return json.Marshal(value.Array)
+	case NullValue, "":
+		return []byte("null"), nil
+	}
+	return nil, fmt.Errorf("alexa: unknown value kind %q", value.Kind)
+}
+
+func (value *Value) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return fmt.Errorf("alexa: empty value")
+	}
+	switch data[0] {
+	case 'n':
+		*value = Value{Kind: NullValue}
+		return nil
+	case '"':
+		*value = Value{Kind: StringValue}
+		return json.Unmarshal(data, &value.String)
+	case 't', 'f':
+		*value = Value{Kind: BoolValue}
+		return json.Unmarshal(data, &value.Bool)
+	case '{':
+		*value = Value{Kind: ObjectValue}
+		return json.Unmarshal(data, &value.Object)
+	case '[':
+		*value = Value{Kind: ArrayValue, Array: []Value{}}
+		return json.Unmarshal(data, &value.Array)
+	}
+	*value = Value{Kind: NumberValue}
+	return json.Unmarshal(data, &value.Number)
+}
+
+// Converts anything encoding/json can marshal, such as the attributes a skill
+// keeps, to a Value
+func ValueOf(from interface{}) (Value, error) {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return Value{}, err
+	}
+	var value Value
+	err = json.Unmarshal(data, &value)
+	return value, err
+}
+
+// The value as encoding/json decodes JSON into an interface{}: a string,
+// float64, bool, map[string]interface{}, []interface{} or nil
+func (value Value) Interface() interface{} {
+	switch value.Kind {
+	case StringValue:
+		return value.String
+	case NumberValue:
+		return value.Number
+	case BoolValue:
+		return value.Bool
+	case ObjectValue:
+		fields := make(map[string]interface{}, len(value.Object))
+		for name, field := range value.Object {
+			fields[name] = field.Interface()
+		}
+		return fields
+	case ArrayValue:
+		values := make([]interface{}, len(value.Array))
+		for i, element := range value.Array {
+			values[i] = element.Interface()
+		}
+		return values
+	}
+	return nil
+}
+
+// Marshals the fields of known merged with properties, which hold the JSON
+// fields known has no field for
+func marshalWithProperties(known interface{}, properties map[string]Value) ([]byte, error) {
+	data, err := json.Marshal(known)
+	if err != nil || len(properties) == 0 {
+		return data, err
+	}
+	var fields map[string]Value
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range properties {
+		if _, ok := fields[name]; !ok {
+			fields[name] = value
+		}
+	}
+	return ObjectOf(fields).MarshalJSON()
+}
+
+// Unmarshals data into known and the fields other than the named ones into
+// properties
+func unmarshalWithProperties(data []byte, known interface{}, properties *map[string]Value, names ...string) error {
+	if err := json.Unmarshal(data, known); err != nil {
+		return err
+	}
+	var fields map[string]Value
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for _, name := range names {
+		delete(fields, name)
+	}
+	*properties = nil
+	if len(fields) > 0 {
+		*properties = fields
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+// Command lexicon maintains the pronunciation lexicon the recipe skill uses
+// when it speaks recipe names and ingredients.
+//
+// Add or replace an entry with its IPA or X-SAMPA phonemes, or the language
+// the word comes from, or record that Alexa already says it well:
+//
+//	go run ./cmd/lexicon add -ipa ˈnjoʊki gnocchi
+//	go run ./cmd/lexicon add -lang it-IT bruschetta
+//	go run ./cmd/lexicon add -fine onion
+//
+// List the words of the recipe names and ingredients in a snapshot that have
+// no entry, the most used first:
+//
+//	go run ./cmd/lexicon missing -snapshot recipes.snapshot.json.gz
+//
+// missing exits with status 1 when any word is missing, so it can be run as a
+// check before a release. Both commands take -lexicon, the lexicon file,
+// pronunciations.json by default.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mongodb-developer/alexa-golang-example/recipes"
+	"github.com/mongodb-developer/alexa-golang-example/skill"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "add":
+		add(os.Args[2:])
+	case "missing":
+		missing(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: lexicon add [-lexicon file] (-ipa phonemes | -x-sampa phonemes | -lang locale | -fine) word")
+	fmt.Fprintln(os.Stderr, "       lexicon missing [-lexicon file] -snapshot file")
+	os.Exit(2)
+}
+
+// Reads the lexicon, or starts an empty one when the file doesn't exist yet
+func open(path string) *skill.Lexicon {
+	lexicon, err := skill.LoadLexicon(path)
+	if os.IsNotExist(err) {
+		return skill.NewLexicon(nil)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		os.Exit(1)
+	}
+	return lexicon
+}
+
+func add(args []string) {
+	flags := flag.NewFlagSet("add", flag.ExitOnError)
+	path := flags.String("lexicon", skill.DefaultLexiconPath, "the lexicon file")
+	ipa := flags.String("ipa", "", "the pronunciation in the International Phonetic Alphabet")
+	xsampa := flags.String("x-sampa", "", "the pronunciation in X-SAMPA")
+	lang := flags.String("lang", "", "the locale the word comes from, such as it-IT")
+	fine := flags.Bool("fine", false, "record that Alexa's own pronunciation is fine")
+	flags.Parse(args)
+
+	word := strings.Join(flags.Args(), " ")
+	given := 0
+	for _, value := range []string{*ipa, *xsampa, *lang} {
+		if value != "" {
+			given++
+		}
+	}
+	if *fine {
+		given++
+	}
+	if word == "" || given != 1 {
+		usage()
+	}
+	lexicon := open(*path)
+	lexicon.Set(skill.Pronunciation{Word: word, IPA: *ipa, XSAMPA: *xsampa, Lang: *lang})
+	if err := lexicon.Save(*path); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *path, err)
+		os.Exit(1)
+	}
+}
+
+func missing(args []string) {
+	flags := flag.NewFlagSet("missing", flag.ExitOnError)
+	path := flags.String("lexicon", skill.DefaultLexiconPath, "the lexicon file")
+	snapshotPath := flags.String("snapshot", recipes.DefaultSnapshotPath, "the recipe snapshot to read names and ingredients from")
+	flags.Parse(args)
+
+	lexicon := open(*path)
+	snapshot, err := recipes.LoadSnapshot(*snapshotPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *snapshotPath, err)
+		os.Exit(1)
+	}
+	var groups [][]string
+	for _, recipe := range snapshot.Recipes() {
+		groups = append(groups, append([]string{recipe.Name}, recipe.Ingredients...))
+	}
+	words := lexicon.Missing(groups)
+	for _, word := range words {
+		fmt.Printf("%s\t%d\n", word.Word, word.Uses)
+	}
+	if len(words) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,310 @@
+// Command loadgen replays synthetic Alexa traffic against the skill and
+// reports latency percentiles, error rates and database operation counts, for
+// sizing the Atlas tier and Lambda memory.
+//
+// Traffic goes to a server started with -http -unsigned when -target is set,
+// and its database counters are read from its -admin listener with -metrics:
+//
+//	go run ./cmd/loadgen -target http://localhost:8080/alexa -metrics http://localhost:9090/debug/vars -snapshot recipes.snapshot.json.gz
+//
+// Otherwise the recipe skill runs in this process, against the snapshot or,
+// with -database, against the cluster in ATLAS_URI:
+//
+//	go run ./cmd/loadgen -snapshot recipes.snapshot.json.gz -concurrency 50 -duration 1m
+//
+// The report is written to standard output. In process, the skill's request
+// logs go to standard error and can be discarded with 2>/dev/null.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mongodb-developer/alexa-golang-example/mongodb"
+	"github.com/mongodb-developer/alexa-golang-example/recipes"
+	"github.com/mongodb-developer/alexa-golang-example/secrets"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Names used when no snapshot is given
+var (
+	defaultRecipes     = []string{"chili", "pizza", "pancakes", "lasagna", "guacamole"}
+	defaultIngredients = []string{"salt", "flour", "eggs", "tomato", "cheese", "avocado", "beef"}
+)
+
+// The latency of a request and whether it failed
+type sample struct {
+	scenario string
+	latency  time.Duration
+	failed   bool
+}
+
+func main() {
+	targetURL := flag.String("target", "", "the /alexa endpoint of a server started with -http -unsigned; the skill runs in this process when empty")
+	metricsURL := flag.String("metrics", "", "with -target, the /debug/vars endpoint of the server's -admin listener, to count its database operations")
+	snapshotPath := flag.String("snapshot", "", "a recipe snapshot to draw recipe names and ingredients from, and to serve from in process")
+	database := flag.String("database", "", "in process, query this database in ATLAS_URI instead of the snapshot")
+	collection := flag.String("collection", "recipes", "the recipes collection, with -database")
+	duration := flag.Duration("duration", 30*time.Second, "how long to generate traffic")
+	concurrency := flag.Int("concurrency", 10, "how many sessions run at once")
+	rate := flag.Float64("rate", 0, "the most requests per second across all sessions, or 0 for no limit")
+	think := flag.Duration("think", 0, "the pause between the turns of a session")
+	mixSpec := flag.String("mix", defaultMix, "scenarios and their weights")
+	users := flag.Int("users", 1000, "the number of distinct users sessions are drawn from")
+	applicationID := flag.String("app", "", "the applicationId to send")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "the random seed, for repeatable runs")
+	faultsPath := flag.String("faults", "", "in process, inject the faults scheduled in this JSON file into recipe lookups")
+	flag.Parse()
+
+	trafficMix, err := parseMix(*mixSpec)
+	if err != nil {
+		fail(err)
+	}
+	ctx := context.Background()
+
+	words := newVocabulary(defaultRecipes, defaultIngredients)
+	var snapshot *recipes.SnapshotStore
+	if *snapshotPath != "" {
+		if snapshot, err = recipes.LoadSnapshot(*snapshotPath); err != nil {
+			fail(err)
+		}
+		var names, ingredients []string
+		for _, recipe := range snapshot.Recipes() {
+			names = append(names, recipe.Name)
+			ingredients = append(ingredients, recipe.Ingredients...)
+		}
+		words = newVocabulary(names, ingredients)
+	}
+
+	inject := func(store recipes.RecipeStore) recipes.RecipeStore { return store }
+	if *faultsPath != "" {
+		schedule, err := recipes.LoadFaultSchedule(*faultsPath)
+		if err != nil {
+			fail(err)
+		}
+		inject = func(store recipes.RecipeStore) recipes.RecipeStore { return recipes.NewFaultyStore(store, schedule) }
+	}
+
+	var destination target
+	switch {
+	case *targetURL != "":
+		client := &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{MaxIdleConnsPerHost: *concurrency},
+		}
+		if destination, err = newHTTPTarget(client, *targetURL, *metricsURL); err != nil {
+			fail(err)
+		}
+	case *database != "":
+		cachedSecrets, secretName, err := secrets.NewFromEnv(ctx)
+		if err != nil {
+			fail(err)
+		}
+		uri, err := cachedSecrets.Secret(ctx, secretName)
+		if err != nil {
+			fail(err)
+		}
+		connect := func(ctx context.Context, uri string) (*mongo.Client, error) {
+			return mongo.Connect(ctx, options.Client().ApplyURI(uri).SetMonitor(commandMonitor))
+		}
+		connected, err := connect(ctx, uri)
+		if err != nil {
+			fail(err)
+		}
+		client := mongodb.NewClient(cachedSecrets, secretName, uri, connected, connect)
+		defer client.Disconnect(ctx)
+		store := resilientStore(recipes.NewMongoRecipeStore(client.Collection(*database, *collection)), inject)
+		destination = &inProcessTarget{invoke: recipes.NewSkill(store).Invoke}
+	case snapshot != nil:
+		destination = &inProcessTarget{invoke: recipes.NewSkill(inject(snapshot)).Invoke}
+	default:
+		fail(fmt.Errorf("set -target, -database or -snapshot"))
+	}
+
+	before, err := destination.counters(ctx)
+	if err != nil {
+		fail(fmt.Errorf("unable to read the database counters: %v", err))
+	}
+	var memoryBefore runtime.MemStats
+	runtime.ReadMemStats(&memoryBefore)
+
+	samples := run(ctx, destination, trafficMix, words, runOptions{
+		duration:      *duration,
+		concurrency:   *concurrency,
+		rate:          *rate,
+		think:         *think,
+		users:         *users,
+		applicationID: *applicationID,
+		seed:          *seed,
+	})
+
+	var memoryAfter runtime.MemStats
+	runtime.ReadMemStats(&memoryAfter)
+	after, err := destination.counters(ctx)
+	if err != nil {
+		fail(fmt.Errorf("unable to read the database counters: %v", err))
+	}
+
+	report(samples, *duration, difference(before, after))
+	if *targetURL == "" && len(samples) > 0 {
+		fmt.Printf("\nmemory: %.1f KiB allocated per request, %.1f MiB heap in use\n",
+			float64(memoryAfter.TotalAlloc-memoryBefore.TotalAlloc)/float64(len(samples))/1024,
+			float64(memoryAfter.HeapInuse)/(1<<20))
+	}
+}
+
+// Puts the database behind the retries and circuit breaker the skill uses,
+// injecting faults into the database's lookups so they are retried as real
+// driver errors would be
+func resilientStore(database recipes.RecipeStore, inject func(recipes.RecipeStore) recipes.RecipeStore) *recipes.ResilientStore {
+	return recipes.NewResilientStore(
+		inject(database),
+		recipes.RetryPolicy{MaxAttempts: 3, BaseDelay: 50 * time.Millisecond, MaxDelay: time.Second},
+		&recipes.CircuitBreaker{Threshold: 5, Cooldown: 30 * time.Second},
+	)
+}
+
+type runOptions struct {
+	duration      time.Duration
+	concurrency   int
+	rate          float64
+	think         time.Duration
+	users         int
+	applicationID string
+	seed          int64
+}
+
+// Runs sessions from concurrency workers until the duration is up
+func run(ctx context.Context, destination target, trafficMix *mix, words vocabulary, opts runOptions) []sample {
+	ctx, cancel := context.WithTimeout(ctx, opts.duration)
+	defer cancel()
+
+	var tick <-chan time.Time
+	if opts.rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	var mutex sync.Mutex
+	var samples []sample
+	var workers sync.WaitGroup
+	for worker := 0; worker < opts.concurrency; worker++ {
+		workers.Add(1)
+		random := rand.New(rand.NewSource(opts.seed + int64(worker)))
+		go func() {
+			defer workers.Done()
+			for ctx.Err() == nil {
+				name := trafficMix.pick(random)
+				session := newSession(random, words, opts.applicationID, opts.users)
+				for turn, request := range scenarios[name](session) {
+					if turn > 0 && opts.think > 0 {
+						select {
+						case <-time.After(opts.think):
+						case <-ctx.Done():
+						}
+					}
+					if tick != nil {
+						select {
+						case <-tick:
+						case <-ctx.Done():
+						}
+					}
+					if ctx.Err() != nil {
+						return
+					}
+					started := time.Now()
+					// Requests in flight when time is up are allowed to finish
+					err := destination.send(context.Background(), request)
+					mutex.Lock()
+					samples = append(samples, sample{scenario: name, latency: time.Since(started), failed: err != nil})
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+	workers.Wait()
+	return samples
+}
+
+// Prints latency percentiles and error rates by scenario, then the database
+// operations per request
+func report(samples []sample, duration time.Duration, operations map[string]int64) {
+	byScenario := make(map[string][]sample)
+	for _, sample := range samples {
+		byScenario[sample.scenario] = append(byScenario[sample.scenario], sample)
+	}
+	var names []string
+	for name := range byScenario {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "scenario\trequests\terrors\tp50\tp90\tp99\tmax\t")
+	for _, name := range names {
+		printRow(table, name, byScenario[name])
+	}
+	printRow(table, "all", samples)
+	table.Flush()
+
+	fmt.Printf("\n%.1f requests per second\n", float64(len(samples))/duration.Seconds())
+	if len(operations) == 0 || len(samples) == 0 {
+		return
+	}
+	fmt.Println("\ndatabase operations:")
+	var counted []string
+	for name := range operations {
+		counted = append(counted, name)
+	}
+	sort.Strings(counted)
+	table = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "counter\ttotal\tper request\t")
+	for _, name := range counted {
+		fmt.Fprintf(table, "%s\t%d\t%.2f\t\n", name, operations[name], float64(operations[name])/float64(len(samples)))
+	}
+	table.Flush()
+}
+
+func printRow(table *tabwriter.Writer, name string, samples []sample) {
+	if len(samples) == 0 {
+		fmt.Fprintf(table, "%s\t0\t-\t-\t-\t-\t-\t\n", name)
+		return
+	}
+	latencies := make([]time.Duration, len(samples))
+	failures := 0
+	for i, sample := range samples {
+		latencies[i] = sample.latency
+		if sample.failed {
+			failures++
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	fmt.Fprintf(table, "%s\t%d\t%.2f%%\t%v\t%v\t%v\t%v\t\n",
+		name, len(samples), 100*float64(failures)/float64(len(samples)),
+		percentile(latencies, 50), percentile(latencies, 90), percentile(latencies, 99), latencies[len(latencies)-1].Round(time.Microsecond))
+}
+
+// The nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1].Round(time.Microsecond)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "loadgen:", err)
+	os.Exit(1)
+}
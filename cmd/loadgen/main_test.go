@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mongodb-developer/alexa-golang-example/recipes"
+)
+
+// A RecipeStore standing in for the database, counting the lookups that reach it
+type countingStore struct {
+	recipe recipes.Recipe
+	calls  int
+}
+
+func (store *countingStore) FindByName(ctx context.Context, name string) (recipes.Recipe, error) {
+	store.calls++
+	return store.recipe, nil
+}
+
+func (store *countingStore) FindByNames(ctx context.Context, names []string) ([]recipes.Recipe, error) {
+	store.calls++
+	return []recipes.Recipe{store.recipe}, nil
+}
+
+func (store *countingStore) FindByIngredients(ctx context.Context, ingredients []string) ([]recipes.Recipe, error) {
+	store.calls++
+	return []recipes.Recipe{store.recipe}, nil
+}
+
+func (store *countingStore) Query(ctx context.Context, query recipes.RecipeQuery) ([]recipes.Recipe, error) {
+	store.calls++
+	return []recipes.Recipe{store.recipe}, nil
+}
+
+func TestDatabaseFaultsAreRetried(t *testing.T) {
+	database := &countingStore{recipe: recipes.Recipe{Name: "Pancakes"}}
+	// The first two lookups fail as a dropped connection would
+	schedule := recipes.FaultSchedule{Rules: []recipes.FaultRule{{Until: 2, Kind: recipes.FaultError, Error: "network"}}}
+	store := resilientStore(database, func(store recipes.RecipeStore) recipes.RecipeStore {
+		return recipes.NewFaultyStore(store, schedule)
+	})
+
+	recipe, err := store.FindByName(context.Background(), "Pancakes")
+	if err != nil || recipe.Name != "Pancakes" {
+		t.Fatalf("got %v, %v, want the recipe once the retries get past the faults", recipe, err)
+	}
+	if database.calls != 1 {
+		t.Fatalf("the database was queried %d times, want once, after two injected failures", database.calls)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/mongodb-developer/alexa-golang-example/alexa"
+	"github.com/mongodb-developer/alexa-golang-example/skill"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// Where requests are sent
+type target interface {
+	// Sends a request, returning an error when it failed or the skill could not answer it
+	send(ctx context.Context, request alexa.Request) error
+	// The cumulative count of database operations, by name
+	counters(ctx context.Context) (map[string]int64, error)
+}
+
+// Posts requests to the /alexa endpoint of a server started with -http
+type httpTarget struct {
+	client   *http.Client
+	endpoint string
+	// The /debug/vars endpoint of the server's -admin listener, if it has one
+	vars string
+}
+
+func newHTTPTarget(client *http.Client, endpoint string, vars string) (*httpTarget, error) {
+	for _, address := range []string{endpoint, vars} {
+		if _, err := url.Parse(address); err != nil {
+			return nil, err
+		}
+	}
+	return &httpTarget{client: client, endpoint: endpoint, vars: vars}, nil
+}
+
+func (target *httpTarget) send(ctx context.Context, request alexa.Request) error {
+	encoded, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, target.endpoint, bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	response, err := target.client.Do(httpRequest)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		io.Copy(ioutil.Discard, response.Body)
+		return fmt.Errorf("status %d", response.StatusCode)
+	}
+	var decoded alexa.Response
+	return json.NewDecoder(response.Body).Decode(&decoded)
+}
+
+// Reads the server's expvar counters, none when it has no admin listener
+func (target *httpTarget) counters(ctx context.Context) (map[string]int64, error) {
+	if target.vars == "" {
+		return map[string]int64{}, nil
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target.vars, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := target.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	var vars map[string]json.RawMessage
+	if err := json.NewDecoder(response.Body).Decode(&vars); err != nil {
+		return nil, err
+	}
+	counters := make(map[string]int64)
+	for _, name := range countedVars {
+		var values map[string]int64
+		if raw, ok := vars[name]; ok && json.Unmarshal(raw, &values) == nil {
+			for key, value := range values {
+				counters[name+"."+key] = value
+			}
+		}
+	}
+	return counters, nil
+}
+
+// The expvar maps reported as database operations
+var countedVars = []string{"mongo_commands", "recipe_store"}
+
+// Counts the commands this process sends to MongoDB, like the server does
+var commandMetrics = expvar.NewMap("mongo_commands")
+
+var commandMonitor = &event.CommandMonitor{
+	Started: func(ctx context.Context, started *event.CommandStartedEvent) {
+		commandMetrics.Add(started.CommandName, 1)
+	},
+}
+
+// Invokes a skill in this process, leaving out HTTP and Lambda entirely
+type inProcessTarget struct {
+	invoke skill.InvokeFunc
+}
+
+func (target *inProcessTarget) send(ctx context.Context, request alexa.Request) error {
+	_, err := target.invoke(ctx, request)
+	return err
+}
+
+func (target *inProcessTarget) counters(ctx context.Context) (map[string]int64, error) {
+	counters := make(map[string]int64)
+	for _, name := range countedVars {
+		values, ok := expvar.Get(name).(*expvar.Map)
+		if !ok {
+			continue
+		}
+		values.Do(func(entry expvar.KeyValue) {
+			if value, ok := entry.Value.(*expvar.Int); ok {
+				counters[name+"."+entry.Key] = value.Value()
+			}
+		})
+	}
+	return counters, nil
+}
+
+// Subtracts the counters read before the run from those read after it
+func difference(before map[string]int64, after map[string]int64) map[string]int64 {
+	changed := make(map[string]int64)
+	for name, value := range after {
+		if delta := value - before[name]; delta != 0 {
+			changed[name] = delta
+		}
+	}
+	return changed
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mongodb-developer/alexa-golang-example/alexa"
+)
+
+// The scenarios a session can follow, and how often each is picked by default
+var defaultMix = "launch=1,lookup=4,multi=2,search=3,about=1,conversation=2"
+
+// A scenario produces the requests of one session, in order
+type scenario func(session *sessionBuilder) []alexa.Request
+
+var scenarios = map[string]scenario{
+	// Opening the skill without asking anything
+	"launch": func(session *sessionBuilder) []alexa.Request {
+		return []alexa.Request{session.launch()}
+	},
+	// A one-shot "ask recipe manager what do I need for ..."
+	"lookup": func(session *sessionBuilder) []alexa.Request {
+		return []alexa.Request{session.intent("GetIngredientsForRecipeIntent", "recipe", session.recipe())}
+	},
+	// Ingredients for several recipes at once
+	"multi": func(session *sessionBuilder) []alexa.Request {
+		return []alexa.Request{session.intent(
+			"GetIngredientsForRecipesIntent",
+			"recipeone", session.recipe(),
+			"recipetwo", session.recipe(),
+			"recipethree", session.recipe(),
+		)}
+	},
+	// Recipes that use two ingredients
+	"search": func(session *sessionBuilder) []alexa.Request {
+		return []alexa.Request{session.intent(
+			"GetRecipeFromIngredientsIntent",
+			"ingredientone", session.ingredient(),
+			"ingredienttwo", session.ingredient(),
+		)}
+	},
+	"about": func(session *sessionBuilder) []alexa.Request {
+		return []alexa.Request{session.intent("AboutIntent")}
+	},
+	// Opening the skill, asking a few questions and leaving
+	"conversation": func(session *sessionBuilder) []alexa.Request {
+		requests := []alexa.Request{session.launch()}
+		for turns := 1 + session.random.Intn(3); turns > 0; turns-- {
+			if session.random.Intn(2) == 0 {
+				requests = append(requests, session.intent("GetIngredientsForRecipeIntent", "recipe", session.recipe()))
+			} else {
+				requests = append(requests, session.intent(
+					"GetRecipeFromIngredientsIntent",
+					"ingredientone", session.ingredient(),
+					"ingredienttwo", session.ingredient(),
+				))
+			}
+		}
+		return append(requests, session.ended())
+	},
+}
+
+// Picks scenarios at random in proportion to their weights
+type mix struct {
+	names   []string
+	weights []int
+	total   int
+}
+
+// Parses a mix such as "launch=1,lookup=4"
+func parseMix(spec string) (*mix, error) {
+	parsed := &mix{}
+	for _, part := range strings.Split(spec, ",") {
+		nameWeight := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if _, ok := scenarios[nameWeight[0]]; !ok {
+			return nil, fmt.Errorf("unknown scenario %q", nameWeight[0])
+		}
+		weight := 1
+		if len(nameWeight) == 2 {
+			var err error
+			if weight, err = strconv.Atoi(nameWeight[1]); err != nil || weight < 0 {
+				return nil, fmt.Errorf("invalid weight for %s", nameWeight[0])
+			}
+		}
+		parsed.names = append(parsed.names, nameWeight[0])
+		parsed.weights = append(parsed.weights, weight)
+		parsed.total += weight
+	}
+	if parsed.total == 0 {
+		return nil, fmt.Errorf("the mix has no weight")
+	}
+	return parsed, nil
+}
+
+func (mix *mix) pick(random *rand.Rand) string {
+	n := random.Intn(mix.total)
+	for i, weight := range mix.weights {
+		if n < weight {
+			return mix.names[i]
+		}
+		n -= weight
+	}
+	return mix.names[len(mix.names)-1]
+}
+
+// The recipe names and ingredients lookups are made with
+type vocabulary struct {
+	recipes     []string
+	ingredients []string
+}
+
+func newVocabulary(recipeNames []string, ingredients []string) vocabulary {
+	unique := func(values []string) []string {
+		seen := make(map[string]bool)
+		var result []string
+		for _, value := range values {
+			if !seen[value] {
+				seen[value] = true
+				result = append(result, value)
+			}
+		}
+		sort.Strings(result)
+		return result
+	}
+	return vocabulary{recipes: unique(recipeNames), ingredients: unique(ingredients)}
+}
+
+// Builds the requests of one session, as a single user on a single device
+type sessionBuilder struct {
+	random        *rand.Rand
+	words         vocabulary
+	applicationID string
+	sessionID     string
+	userID        string
+	locale        string
+	sent          int
+}
+
+func newSession(random *rand.Rand, words vocabulary, applicationID string, users int) *sessionBuilder {
+	return &sessionBuilder{
+		random:        random,
+		words:         words,
+		applicationID: applicationID,
+		sessionID:     fmt.Sprintf("amzn1.echo-api.session.loadgen-%016x", random.Uint64()),
+		// Drawing users from a fixed pool lets persistent attributes be reused
+		userID: fmt.Sprintf("amzn1.ask.account.loadgen-%d", random.Intn(users)),
+		locale: []string{"en-US", "en-US", "en-US", "en-GB", "en-CA"}[random.Intn(5)],
+	}
+}
+
+func (session *sessionBuilder) recipe() string {
+	if len(session.words.recipes) == 0 {
+		return "chili"
+	}
+	// A few unknown names exercise the not-found path
+	if session.random.Intn(20) == 0 {
+		return "unheard of casserole"
+	}
+	return session.words.recipes[session.random.Intn(len(session.words.recipes))]
+}
+
+func (session *sessionBuilder) ingredient() string {
+	if len(session.words.ingredients) == 0 {
+		return "salt"
+	}
+	return session.words.ingredients[session.random.Intn(len(session.words.ingredients))]
+}
+
+func (session *sessionBuilder) request(body alexa.RequestBody) alexa.Request {
+	session.sent++
+	body.RequestID = fmt.Sprintf("amzn1.echo-api.request.loadgen-%016x", session.random.Uint64())
+	body.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	body.Locale = session.locale
+	application := alexa.Application{ApplicationID: session.applicationID}
+	user := alexa.User{UserID: session.userID}
+	return alexa.Request{
+		Version: "1.0",
+		Session: &alexa.Session{
+			New:         session.sent == 1,
+			SessionID:   session.sessionID,
+			Application: application,
+			User:        user,
+		},
+		Context: &alexa.Context{System: alexa.System{
+			Application: application,
+			User:        user,
+			Device:      alexa.Device{DeviceID: "amzn1.ask.device.loadgen"},
+			APIEndpoint: "https://api.amazonalexa.com",
+		}},
+		Body: body,
+	}
+}
+
+func (session *sessionBuilder) launch() alexa.Request {
+	return session.request(alexa.RequestBody{Type: alexa.LaunchRequest})
+}
+
+func (session *sessionBuilder) ended() alexa.Request {
+	return session.request(alexa.RequestBody{Type: alexa.SessionEndedRequest, Reason: "USER_INITIATED"})
+}
+
+// Builds an IntentRequest from the intent name and pairs of slot names and values
+func (session *sessionBuilder) intent(name string, slotsAndValues ...string) alexa.Request {
+	intent := &alexa.Intent{Name: name, ConfirmationStatus: alexa.ConfirmationNone, Slots: make(map[string]alexa.Slot)}
+	for i := 0; i+1 < len(slotsAndValues); i += 2 {
+		intent.Slots[slotsAndValues[i]] = alexa.Slot{Name: slotsAndValues[i], Value: slotsAndValues[i+1]}
+	}
+	return session.request(alexa.RequestBody{Type: alexa.IntentRequest, Intent: intent, DialogState: alexa.DialogCompleted})
+}
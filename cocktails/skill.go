@@ -0,0 +1,101 @@
+// Package cocktails implements the cocktail manager skill, a sibling of the
+// recipe manager that stores its drinks in the same shape as recipes.
+package cocktails
+
+import (
+	"errors"
+	"log"
+	"os"
+
+	"github.com/mongodb-developer/alexa-golang-example/recipes"
+	"github.com/mongodb-developer/alexa-golang-example/skill"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Stores a handle to the cocktail store being used by the skill's handlers
+type Connection struct {
+	store recipes.RecipeStore
+}
+
+// The phrases the skill speaks, by locale
+var messages = skill.Catalog{
+	"en": {
+		"degraded":    "I'm having trouble reaching the cocktail database, so this answer may be out of date. ",
+		"ingredients": "A %s is made with %s",
+		"cocktails":   "You could mix %s",
+		"nothing":     "I don't know any cocktails with those ingredients",
+		"notFound":    "I don't know how to make a %s",
+		"about":       "Cocktail manager, a sibling of recipe manager",
+		"unknown":     "The intent was unrecognized",
+		"unavailable": "I can't reach the cocktail database right now. Please try again in a minute.",
+		"apology":     "Sorry, I had trouble with that request. Please try again.",
+		// Shorter versions for users who want brief answers
+		"degraded.brief":    "This may be out of date. ",
+		"ingredients.brief": "%s: %s",
+		"notFound.brief":    "I don't know a %s",
+		"unavailable.brief": "I can't reach the cocktails right now.",
+	},
+}
+
+// Builds the cocktail manager skill on top of a store of cocktails
+func NewSkill(store recipes.RecipeStore) *skill.Skill {
+	connection := Connection{store: store}
+	logger := log.New(os.Stderr, "cocktails ", log.LstdFlags)
+	return skill.New().AddRequestInterceptors(
+		skill.LoadAttributesInterceptor(),
+		skill.LocaleInterceptor(messages, "en-US"),
+		skill.LoggingInterceptor(logger),
+	).AddResponseInterceptors(
+		skill.SSMLLimitInterceptor(skill.MaxSpeechLength),
+		skill.AnalyticsInterceptor(skill.LogAnalyticsRecorder{Logger: logger}),
+		skill.SaveAttributesInterceptor(),
+	).AddErrorHandlers(
+		skill.NewErrorHandler(recipes.IsUnavailable, recipes.Unavailable),
+	).AddRequestHandlers(
+		skill.IntentHandler(connection.ingredientsForCocktail, "GetCocktailIngredientsIntent"),
+		skill.IntentHandler(connection.cocktailsFromIngredients, "GetCocktailsFromIngredientsIntent"),
+		skill.IntentHandler(about, "AboutIntent"),
+		skill.FallbackHandler(unknown),
+	)
+}
+
+func (connection Connection) ingredientsForCocktail(input *skill.HandlerInput) error {
+	name := input.Request.Body.SlotValue("cocktail")
+	if name == "" {
+		return errors.New("Cocktail name is not present in the request")
+	}
+	cocktail, err := connection.store.FindByName(input.Context, name)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return recipes.Answer(input, "Cocktail", input.Messages.Format("notFound", name), nil)
+	}
+	return recipes.Answer(input, "Cocktail", input.Messages.Format("ingredients", name, recipes.JoinWithAnd(cocktail.Ingredients)), err)
+}
+
+func (connection Connection) cocktailsFromIngredients(input *skill.HandlerInput) error {
+	var ingredients []string
+	for _, slot := range []string{"ingredientone", "ingredienttwo"} {
+		if ingredient := input.Request.Body.SlotValue(slot); ingredient != "" {
+			ingredients = append(ingredients, ingredient)
+		}
+	}
+	if len(ingredients) == 0 {
+		return errors.New("Ingredients are not present in the request")
+	}
+	cocktails, err := connection.store.FindByIngredients(input.Context, ingredients)
+	if len(cocktails) == 0 && (err == nil || errors.Is(err, recipes.ErrDegraded)) {
+		return recipes.Answer(input, "Cocktails", input.Messages.Format("nothing"), err)
+	}
+	var names []string
+	for _, cocktail := range cocktails {
+		names = append(names, cocktail.Name)
+	}
+	return recipes.Answer(input, "Cocktails", input.Messages.Format("cocktails", recipes.JoinWithAnd(names)), err)
+}
+
+func about(input *skill.HandlerInput) error {
+	return recipes.Answer(input, "About", input.Messages.Format("about"), nil)
+}
+
+func unknown(input *skill.HandlerInput) error {
+	return recipes.Answer(input, "Unknown Request", input.Messages.Format("unknown"), nil)
+}
@@ -0,0 +1,83 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/mongodb-developer/alexa-golang-example/linking"
+	"github.com/mongodb-developer/alexa-golang-example/recipes"
+)
+
+// Serves GraphQL queries posted as JSON
+type Handler struct {
+	schema *graphql.Schema
+	store  recipes.RecipeStore
+	auth   linking.Authenticator
+}
+
+// Builds the handler. favorites and plans may be nil while the database is
+// unreachable, in which case only the recipe queries are answered.
+func NewHandler(store recipes.RecipeStore, favorites recipes.FavoriteStore, plans recipes.MealPlanStore, auth linking.Authenticator) (*Handler, error) {
+	parsed, err := graphql.ParseSchema(schema, &resolver{store: store, favorites: favorites, plans: plans})
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{schema: parsed, store: store, auth: auth}, nil
+}
+
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+func (handler *Handler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writeJSON(writer, http.StatusMethodNotAllowed, map[string]string{"message": http.StatusText(http.StatusMethodNotAllowed)})
+		return
+	}
+	var query graphQLRequest
+	if err := json.NewDecoder(request.Body).Decode(&query); err != nil {
+		writeJSON(writer, http.StatusBadRequest, map[string]string{"message": "the body must be a JSON GraphQL request"})
+		return
+	}
+
+	var userID string
+	if header := request.Header.Get("Authorization"); header != "" {
+		token := strings.TrimPrefix(header, "Bearer ")
+		var err error
+		if token == header || handler.auth == nil {
+			err = linking.ErrUnauthenticated
+		} else {
+			userID, err = handler.auth.Authenticate(request.Context(), token)
+		}
+		if err == linking.ErrUnauthenticated {
+			writer.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(writer, http.StatusUnauthorized, map[string]string{"message": err.Error()})
+			return
+		} else if err != nil {
+			log.Printf("unable to authenticate: %v", err)
+			writeJSON(writer, http.StatusBadGateway, map[string]string{"message": "unable to check the token"})
+			return
+		}
+	}
+
+	state := newRequestState(handler.store, userID)
+	ctx := contextWithState(request.Context(), state)
+	response := handler.schema.Exec(ctx, query.Query, query.OperationName, query.Variables)
+	if state.isDegraded() {
+		response.Extensions = map[string]interface{}{"degraded": true}
+	}
+	writeJSON(writer, http.StatusOK, response)
+}
+
+func writeJSON(writer http.ResponseWriter, status int, value interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	if err := json.NewEncoder(writer).Encode(value); err != nil {
+		log.Printf("unable to encode response: %v", err)
+	}
+}
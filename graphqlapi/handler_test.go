@@ -0,0 +1,276 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mongodb-developer/alexa-golang-example/linking"
+	"github.com/mongodb-developer/alexa-golang-example/recipes"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// A RecipeStore keeping recipes in memory, which records the names each
+// FindByNames call asks for
+type memoryRecipes struct {
+	recipes []recipes.Recipe
+
+	mutex   sync.Mutex
+	batches [][]string
+}
+
+func (store *memoryRecipes) FindByName(ctx context.Context, name string) (recipes.Recipe, error) {
+	for _, recipe := range store.recipes {
+		if recipe.Name == name {
+			return recipe, nil
+		}
+	}
+	return recipes.Recipe{}, mongo.ErrNoDocuments
+}
+
+func (store *memoryRecipes) FindByNames(ctx context.Context, names []string) ([]recipes.Recipe, error) {
+	store.mutex.Lock()
+	store.batches = append(store.batches, names)
+	store.mutex.Unlock()
+	var found []recipes.Recipe
+	for _, name := range names {
+		if recipe, err := store.FindByName(ctx, name); err == nil {
+			found = append(found, recipe)
+		}
+	}
+	return found, nil
+}
+
+func (store *memoryRecipes) FindByIngredients(ctx context.Context, ingredients []string) ([]recipes.Recipe, error) {
+	return nil, nil
+}
+
+func (store *memoryRecipes) Query(ctx context.Context, query recipes.RecipeQuery) ([]recipes.Recipe, error) {
+	var found []recipes.Recipe
+	for _, recipe := range store.recipes {
+		if strings.Contains(recipe.Name, query.Text) {
+			found = append(found, recipe)
+		}
+	}
+	return found, nil
+}
+
+type memoryFavorites map[string][]string
+
+func (favorites memoryFavorites) Favorites(ctx context.Context, userID string) ([]string, error) {
+	return favorites[userID], nil
+}
+
+func (favorites memoryFavorites) AddFavorite(ctx context.Context, userID string, recipeName string) error {
+	favorites[userID] = append(favorites[userID], recipeName)
+	return nil
+}
+
+func (favorites memoryFavorites) RemoveFavorite(ctx context.Context, userID string, recipeName string) error {
+	return nil
+}
+
+type memoryPlans []recipes.MealPlan
+
+func (plans memoryPlans) MealPlans(ctx context.Context, userID string) ([]recipes.MealPlan, error) {
+	return plans, nil
+}
+
+func (plans memoryPlans) SaveMealPlan(ctx context.Context, plan recipes.MealPlan) (recipes.MealPlan, error) {
+	return plan, nil
+}
+
+func (plans memoryPlans) SaveMealPlanByName(ctx context.Context, plan recipes.MealPlan) (recipes.MealPlan, error) {
+	return plan, nil
+}
+
+func (plans memoryPlans) DeleteMealPlan(ctx context.Context, userID string, id primitive.ObjectID) (bool, error) {
+	return false, nil
+}
+
+// Accepts the token "valid" for user-1, rejects "expired" and can't reach
+// the profile endpoint for "unreachable"
+type stubAuthenticator struct{}
+
+func (stubAuthenticator) Authenticate(ctx context.Context, token string) (string, error) {
+	switch token {
+	case "valid":
+		return "user-1", nil
+	case "unreachable":
+		return "", errors.New("profile endpoint unreachable")
+	}
+	return "", linking.ErrUnauthenticated
+}
+
+func newTestHandler(t *testing.T, count int) (*Handler, *memoryRecipes) {
+	store := &memoryRecipes{}
+	var names []string
+	for i := 1; i <= count; i++ {
+		name := "Recipe " + strconv.Itoa(i)
+		store.recipes = append(store.recipes, recipes.Recipe{Name: name, Ingredients: []string{"salt"}})
+		names = append(names, name)
+	}
+	plans := memoryPlans{
+		{Name: "Week one", Days: []recipes.MealPlanDay{{Day: "Monday", Recipes: names[:2]}, {Day: "Tuesday", Recipes: names[1:3]}}},
+		{Name: "Week two", Days: []recipes.MealPlanDay{{Day: "Monday", Recipes: names[3:4]}}},
+	}
+	handler, err := NewHandler(store, memoryFavorites{"user-1": names}, plans, stubAuthenticator{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return handler, store
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage
+	Errors []struct{ Message string }
+}
+
+// Posts a query with the token, if any, returning the status and response
+func post(t *testing.T, handler http.Handler, token string, query string) (int, graphQLResponse) {
+	body, _ := json.Marshal(graphQLRequest{Query: query})
+	request := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	if token != "" {
+		request.Header.Set("Authorization", token)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	var response graphQLResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("%s is not JSON: %v", recorder.Body.String(), err)
+	}
+	return recorder.Code, response
+}
+
+func TestHandlerAuthenticates(t *testing.T) {
+	handler, _ := newTestHandler(t, 4)
+	tests := []struct {
+		name          string
+		authorization string
+		query         string
+		status        int
+		data          string
+		errors        int
+	}{
+		{"public query", "", `{ recipe(name: "Recipe 1") { name } }`, http.StatusOK, `{"recipe":{"name":"Recipe 1"}}`, 0},
+		{"signed in", "Bearer valid", `{ me { id } }`, http.StatusOK, `{"me":{"id":"user-1"}}`, 0},
+		{"signed out", "", `{ me { id } }`, http.StatusOK, `null`, 1},
+		{"expired token", "Bearer expired", `{ me { id } }`, http.StatusUnauthorized, ``, 0},
+		{"not a bearer token", "Basic dXNlcjpwYXNz", `{ me { id } }`, http.StatusUnauthorized, ``, 0},
+		{"profile endpoint down", "Bearer unreachable", `{ me { id } }`, http.StatusBadGateway, ``, 0},
+	}
+	for _, test := range tests {
+		status, response := post(t, handler, test.authorization, test.query)
+		if status != test.status || string(response.Data) != test.data || len(response.Errors) != test.errors {
+			t.Errorf("%s: got %d with %s and errors %v, want %d with %s and %d errors", test.name, status, response.Data, response.Errors, test.status, test.data, test.errors)
+		}
+	}
+}
+
+type recipePage struct {
+	Recipes struct {
+		Edges []struct {
+			Cursor string
+			Node   struct{ Name string }
+		}
+		PageInfo struct {
+			HasNextPage bool
+			EndCursor   *string
+		}
+		TotalCount int
+	}
+}
+
+func TestHandlerPaginates(t *testing.T) {
+	handler, _ := newTestHandler(t, 5)
+	query := func(args string) (recipePage, []string) {
+		_, response := post(t, handler, "", `{ recipes(query: "Recipe"`+args+`) { edges { cursor node { name } } pageInfo { hasNextPage endCursor } totalCount } }`)
+		var page recipePage
+		var messages []string
+		for _, err := range response.Errors {
+			messages = append(messages, err.Message)
+		}
+		if len(messages) == 0 {
+			if err := json.Unmarshal(response.Data, &page); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return page, messages
+	}
+
+	var names []string
+	after := ""
+	for pages := 0; pages < 3; pages++ {
+		page, errs := query(`, first: 2` + after)
+		if errs != nil {
+			t.Fatalf("got errors %v", errs)
+		}
+		for _, edge := range page.Recipes.Edges {
+			names = append(names, edge.Node.Name)
+		}
+		if page.Recipes.TotalCount != 5 || page.Recipes.PageInfo.HasNextPage != (pages < 2) {
+			t.Errorf("page %d: got a total of %d and next page %v", pages, page.Recipes.TotalCount, page.Recipes.PageInfo.HasNextPage)
+		}
+		if page.Recipes.PageInfo.EndCursor == nil {
+			t.Fatalf("page %d has no end cursor", pages)
+		}
+		after = `, after: "` + *page.Recipes.PageInfo.EndCursor + `"`
+	}
+	if want := []string{"Recipe 1", "Recipe 2", "Recipe 3", "Recipe 4", "Recipe 5"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+
+	for _, args := range []string{
+		`, after: "` + encodeCursor(5) + `"`,
+		`, after: "` + encodeCursor(int(^uint(0)>>1)) + `"`,
+		`, after: "not a cursor"`,
+		`, first: -1`,
+	} {
+		if _, errs := query(args); len(errs) != 1 {
+			t.Errorf("%s: got errors %v, want one", args, errs)
+		}
+	}
+}
+
+func TestHandlerBatchesRecipeLookups(t *testing.T) {
+	handler, store := newTestHandler(t, 6)
+	status, response := post(t, handler, "Bearer valid", `{ me { mealPlans { edges { node { days { recipes { name } } } } } } }`)
+	if status != http.StatusOK || len(response.Errors) > 0 {
+		t.Fatalf("got %d with errors %v", status, response.Errors)
+	}
+	want := `{"me":{"mealPlans":{"edges":[` +
+		`{"node":{"days":[{"recipes":[{"name":"Recipe 1"},{"name":"Recipe 2"}]},{"recipes":[{"name":"Recipe 2"},{"name":"Recipe 3"}]}]}},` +
+		`{"node":{"days":[{"recipes":[{"name":"Recipe 4"}]}]}}]}}}`
+	if string(response.Data) != want {
+		t.Errorf("got %s, want %s", response.Data, want)
+	}
+	if len(store.batches) != 1 {
+		t.Fatalf("got lookups %v, want one for every day of every plan", store.batches)
+	}
+	batch := append([]string(nil), store.batches[0]...)
+	sort.Strings(batch)
+	if want := []string{"Recipe 1", "Recipe 2", "Recipe 3", "Recipe 4"}; !reflect.DeepEqual(batch, want) {
+		t.Errorf("got a lookup of %v, want %v", batch, want)
+	}
+
+	// Only the favorites on the page are looked up
+	store.batches = nil
+	if _, response = post(t, handler, "Bearer valid", `{ me { favorites(first: 2, after: "`+encodeCursor(1)+`") { edges { node { name } } } } }`); len(response.Errors) > 0 {
+		t.Fatal(response.Errors)
+	}
+	if len(store.batches) == 1 {
+		sort.Strings(store.batches[0])
+	}
+	if want := [][]string{{"Recipe 3", "Recipe 4"}}; !reflect.DeepEqual(store.batches, want) {
+		t.Errorf("got lookups %v, want %v", store.batches, want)
+	}
+}
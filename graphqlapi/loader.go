@@ -0,0 +1,95 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/graph-gophers/dataloader"
+	"github.com/mongodb-developer/alexa-golang-example/recipes"
+)
+
+// How long the loader waits for more names before looking up a batch
+const batchWait = 2 * time.Millisecond
+
+type contextKey int
+
+const requestStateKey contextKey = 0
+
+// Per-request state: the signed in user and a loader that batches the recipe
+// lookups made while resolving favorites and meal plans into one FindByNames
+type requestState struct {
+	userID string
+	loader *dataloader.Loader
+
+	mutex    sync.Mutex
+	degraded bool
+}
+
+func newRequestState(store recipes.RecipeStore, userID string) *requestState {
+	state := &requestState{userID: userID}
+	state.loader = dataloader.NewBatchedLoader(func(ctx context.Context, keys dataloader.Keys) []*dataloader.Result {
+		found, err := store.FindByNames(ctx, keys.Keys())
+		if errors.Is(err, recipes.ErrDegraded) {
+			state.markDegraded()
+			err = nil
+		}
+		byName := make(map[string]recipes.Recipe, len(found))
+		for _, recipe := range found {
+			byName[recipe.Name] = recipe
+		}
+		results := make([]*dataloader.Result, len(keys))
+		for i, key := range keys {
+			if err != nil {
+				results[i] = &dataloader.Result{Error: err}
+			} else if recipe, ok := byName[key.String()]; ok {
+				results[i] = &dataloader.Result{Data: recipe}
+			} else {
+				// A recipe removed since it was favorited or planned
+				results[i] = &dataloader.Result{}
+			}
+		}
+		return results
+	}, dataloader.WithWait(batchWait))
+	return state
+}
+
+func contextWithState(ctx context.Context, state *requestState) context.Context {
+	return context.WithValue(ctx, requestStateKey, state)
+}
+
+func stateFrom(ctx context.Context) *requestState {
+	state, _ := ctx.Value(requestStateKey).(*requestState)
+	return state
+}
+
+// Notes that some answer came from a cache or snapshot, which is reported in
+// the response extensions
+func (state *requestState) markDegraded() {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	state.degraded = true
+}
+
+func (state *requestState) isDegraded() bool {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	return state.degraded
+}
+
+// Loads recipes by name through the batching loader, in order, leaving nil
+// in place of any that no longer exist
+func (state *requestState) loadRecipes(ctx context.Context, names []string) ([]*recipes.Recipe, error) {
+	values, errs := state.loader.LoadMany(ctx, dataloader.NewKeysFromStrings(names))()
+	loaded := make([]*recipes.Recipe, len(values))
+	for i, value := range values {
+		if i < len(errs) && errs[i] != nil {
+			return nil, errs[i]
+		}
+		if recipe, ok := value.(recipes.Recipe); ok {
+			loaded[i] = &recipe
+		}
+	}
+	return loaded, nil
+}
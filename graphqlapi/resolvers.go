@@ -0,0 +1,431 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/mongodb-developer/alexa-golang-example/linking"
+	"github.com/mongodb-developer/alexa-golang-example/recipes"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var errUserDataUnavailable = errors.New("favorites and meal plans are unavailable while the database is unreachable")
+
+// Resolves the Query and Mutation fields
+type resolver struct {
+	store     recipes.RecipeStore
+	favorites recipes.FavoriteStore
+	plans     recipes.MealPlanStore
+}
+
+// Returns the signed in user, or linking.ErrUnauthenticated
+func (r *resolver) user(ctx context.Context) (*userResolver, error) {
+	state := stateFrom(ctx)
+	if state == nil || state.userID == "" {
+		return nil, linking.ErrUnauthenticated
+	}
+	if r.favorites == nil || r.plans == nil {
+		return nil, errUserDataUnavailable
+	}
+	return &userResolver{root: r, id: state.userID}, nil
+}
+
+// Passes store errors through, except that answers from a cache or snapshot
+// are kept and reported in the response extensions
+func checkDegraded(ctx context.Context, err error) error {
+	if errors.Is(err, recipes.ErrDegraded) {
+		if state := stateFrom(ctx); state != nil {
+			state.markDegraded()
+		}
+		return nil
+	}
+	return err
+}
+
+func (r *resolver) Recipe(ctx context.Context, args struct{ Name string }) (*recipeResolver, error) {
+	recipe, err := r.store.FindByName(ctx, args.Name)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err = checkDegraded(ctx, err); err != nil {
+		return nil, err
+	}
+	return &recipeResolver{recipe: recipe}, nil
+}
+
+type pageArgs struct {
+	First *int32
+	After *string
+}
+
+func (r *resolver) Recipes(ctx context.Context, args struct {
+	Query string
+	First *int32
+	After *string
+}) (*recipeConnection, error) {
+	found, err := r.store.Query(ctx, recipes.RecipeQuery{Text: args.Query})
+	if err = checkDegraded(ctx, err); err != nil {
+		return nil, err
+	}
+	return newRecipeConnection(found, pageArgs{First: args.First, After: args.After})
+}
+
+func (r *resolver) RecipesWithIngredients(ctx context.Context, args struct {
+	Ingredients []string
+	First       *int32
+	After       *string
+}) (*recipeConnection, error) {
+	found, err := r.store.FindByIngredients(ctx, args.Ingredients)
+	if err = checkDegraded(ctx, err); err != nil {
+		return nil, err
+	}
+	return newRecipeConnection(found, pageArgs{First: args.First, After: args.After})
+}
+
+func (r *resolver) Me(ctx context.Context) (*userResolver, error) {
+	return r.user(ctx)
+}
+
+func (r *resolver) AddFavorite(ctx context.Context, args struct{ Recipe string }) (*userResolver, error) {
+	user, err := r.user(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.store.FindByName(ctx, args.Recipe); err == mongo.ErrNoDocuments {
+		return nil, errors.New("no recipe is named " + strconv.Quote(args.Recipe))
+	} else if err = checkDegraded(ctx, err); err != nil {
+		return nil, err
+	}
+	if err := r.favorites.AddFavorite(ctx, user.id, args.Recipe); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *resolver) RemoveFavorite(ctx context.Context, args struct{ Recipe string }) (*userResolver, error) {
+	user, err := r.user(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.favorites.RemoveFavorite(ctx, user.id, args.Recipe); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+type mealPlanInput struct {
+	ID   *graphql.ID
+	Name string
+	Days []struct {
+		Day     string
+		Recipes []string
+	}
+}
+
+func (r *resolver) SaveMealPlan(ctx context.Context, args struct{ Plan mealPlanInput }) (*mealPlanResolver, error) {
+	user, err := r.user(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plan := recipes.MealPlan{UserID: user.id, Name: args.Plan.Name}
+	if args.Plan.ID != nil {
+		if plan.ID, err = primitive.ObjectIDFromHex(string(*args.Plan.ID)); err != nil {
+			return nil, errors.New("id is not a meal plan id")
+		}
+	}
+	for _, day := range args.Plan.Days {
+		plan.Days = append(plan.Days, recipes.MealPlanDay{Day: day.Day, Recipes: day.Recipes})
+	}
+	plan, err = r.plans.SaveMealPlan(ctx, plan)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("no meal plan has that id")
+	} else if err != nil {
+		return nil, err
+	}
+	return &mealPlanResolver{plan: plan}, nil
+}
+
+func (r *resolver) DeleteMealPlan(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	user, err := r.user(ctx)
+	if err != nil {
+		return false, err
+	}
+	id, err := primitive.ObjectIDFromHex(string(args.ID))
+	if err != nil {
+		return false, nil
+	}
+	return r.plans.DeleteMealPlan(ctx, user.id, id)
+}
+
+type recipeResolver struct {
+	recipe recipes.Recipe
+}
+
+func (r *recipeResolver) ID() graphql.ID {
+	return graphql.ID(r.recipe.ID.Hex())
+}
+
+func (r *recipeResolver) Name() string {
+	return r.recipe.Name
+}
+
+func (r *recipeResolver) Ingredients() []string {
+	if r.recipe.Ingredients == nil {
+		return []string{}
+	}
+	return r.recipe.Ingredients
+}
+
+type userResolver struct {
+	root *resolver
+	id   string
+}
+
+func (r *userResolver) ID() graphql.ID {
+	return graphql.ID(r.id)
+}
+
+// Pages through the names first, so only the recipes on the page are loaded
+func (r *userResolver) Favorites(ctx context.Context, args pageArgs) (*recipeConnection, error) {
+	names, err := r.root.favorites.Favorites(ctx, r.id)
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := page(len(names), args)
+	if err != nil {
+		return nil, err
+	}
+	found, err := stateFrom(ctx).loadRecipes(ctx, names[start:end])
+	if err != nil {
+		return nil, err
+	}
+	connection := &recipeConnection{hasNext: end < len(names), total: len(names)}
+	for i, recipe := range found {
+		if recipe != nil {
+			connection.add(start+i, *recipe)
+		}
+	}
+	return connection, nil
+}
+
+func (r *userResolver) MealPlans(ctx context.Context, args pageArgs) (*mealPlanConnection, error) {
+	plans, err := r.root.plans.MealPlans(ctx, r.id)
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := page(len(plans), args)
+	if err != nil {
+		return nil, err
+	}
+	connection := &mealPlanConnection{start: start, hasNext: end < len(plans), total: len(plans)}
+	for _, plan := range plans[start:end] {
+		connection.plans = append(connection.plans, &mealPlanResolver{plan: plan})
+	}
+	return connection, nil
+}
+
+type mealPlanResolver struct {
+	plan recipes.MealPlan
+}
+
+func (r *mealPlanResolver) ID() graphql.ID {
+	return graphql.ID(r.plan.ID.Hex())
+}
+
+func (r *mealPlanResolver) Name() string {
+	return r.plan.Name
+}
+
+func (r *mealPlanResolver) Days() []*mealPlanDayResolver {
+	days := make([]*mealPlanDayResolver, 0, len(r.plan.Days))
+	for _, day := range r.plan.Days {
+		days = append(days, &mealPlanDayResolver{day: day})
+	}
+	return days
+}
+
+type mealPlanDayResolver struct {
+	day recipes.MealPlanDay
+}
+
+func (r *mealPlanDayResolver) Day() string {
+	return r.day.Day
+}
+
+// Every day of every plan on the page is resolved concurrently, so their
+// lookups are batched together by the loader
+func (r *mealPlanDayResolver) Recipes(ctx context.Context) ([]*recipeResolver, error) {
+	found, err := stateFrom(ctx).loadRecipes(ctx, r.day.Recipes)
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*recipeResolver, 0, len(found))
+	for _, recipe := range found {
+		if recipe != nil {
+			resolvers = append(resolvers, &recipeResolver{recipe: *recipe})
+		}
+	}
+	return resolvers, nil
+}
+
+// Works out the slice of total items a page covers. Cursors are opaque
+// offsets into the list, so one past its end is rejected.
+func page(total int, args pageArgs) (int, int, error) {
+	size := defaultPageSize
+	if args.First != nil {
+		if *args.First < 0 {
+			return 0, 0, errors.New("first must not be negative")
+		}
+		size = int(*args.First)
+		if size > maxPageSize {
+			size = maxPageSize
+		}
+	}
+	start := 0
+	if args.After != nil {
+		offset, err := decodeCursor(*args.After)
+		if err != nil || offset >= total {
+			return 0, 0, errors.New("after is not a valid cursor")
+		}
+		start = offset + 1
+	}
+	end := total
+	if size < total-start {
+		end = start + size
+	}
+	return start, end, nil
+}
+
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(decoded) < len("offset:") || string(decoded[:len("offset:")]) != "offset:" {
+		return 0, errors.New("invalid cursor")
+	}
+	offset, err := strconv.Atoi(string(decoded[len("offset:"):]))
+	if err == nil && offset < 0 {
+		err = errors.New("invalid cursor")
+	}
+	return offset, err
+}
+
+type pageInfoResolver struct {
+	hasNext   bool
+	endCursor *string
+}
+
+func (r *pageInfoResolver) HasNextPage() bool {
+	return r.hasNext
+}
+
+func (r *pageInfoResolver) EndCursor() *string {
+	return r.endCursor
+}
+
+type recipeConnection struct {
+	edges   []*recipeEdge
+	hasNext bool
+	total   int
+}
+
+func newRecipeConnection(found []recipes.Recipe, args pageArgs) (*recipeConnection, error) {
+	start, end, err := page(len(found), args)
+	if err != nil {
+		return nil, err
+	}
+	connection := &recipeConnection{hasNext: end < len(found), total: len(found)}
+	for i := start; i < end; i++ {
+		connection.add(i, found[i])
+	}
+	return connection, nil
+}
+
+// Adds the recipe at offset in the full list
+func (r *recipeConnection) add(offset int, recipe recipes.Recipe) {
+	r.edges = append(r.edges, &recipeEdge{cursor: encodeCursor(offset), node: &recipeResolver{recipe: recipe}})
+}
+
+func (r *recipeConnection) Edges() []*recipeEdge {
+	if r.edges == nil {
+		return []*recipeEdge{}
+	}
+	return r.edges
+}
+
+func (r *recipeConnection) PageInfo() *pageInfoResolver {
+	info := &pageInfoResolver{hasNext: r.hasNext}
+	if len(r.edges) > 0 {
+		info.endCursor = &r.edges[len(r.edges)-1].cursor
+	}
+	return info
+}
+
+func (r *recipeConnection) TotalCount() int32 {
+	return int32(r.total)
+}
+
+type recipeEdge struct {
+	cursor string
+	node   *recipeResolver
+}
+
+func (r *recipeEdge) Cursor() string {
+	return r.cursor
+}
+
+func (r *recipeEdge) Node() *recipeResolver {
+	return r.node
+}
+
+type mealPlanConnection struct {
+	plans   []*mealPlanResolver
+	start   int
+	hasNext bool
+	total   int
+}
+
+func (r *mealPlanConnection) Edges() []*mealPlanEdge {
+	edges := make([]*mealPlanEdge, 0, len(r.plans))
+	for i, plan := range r.plans {
+		edges = append(edges, &mealPlanEdge{cursor: encodeCursor(r.start + i), node: plan})
+	}
+	return edges
+}
+
+func (r *mealPlanConnection) PageInfo() *pageInfoResolver {
+	info := &pageInfoResolver{hasNext: r.hasNext}
+	if len(r.plans) > 0 {
+		cursor := encodeCursor(r.start + len(r.plans) - 1)
+		info.endCursor = &cursor
+	}
+	return info
+}
+
+func (r *mealPlanConnection) TotalCount() int32 {
+	return int32(r.total)
+}
+
+type mealPlanEdge struct {
+	cursor string
+	node   *mealPlanResolver
+}
+
+func (r *mealPlanEdge) Cursor() string {
+	return r.cursor
+}
+
+func (r *mealPlanEdge) Node() *mealPlanResolver {
+	return r.node
+}
@@ -0,0 +1,91 @@
+// Package graphqlapi serves recipes, ingredient search, favorites and meal
+// plans over GraphQL for the frontend. Recipes are public; favorites and meal
+// plans belong to the user whose linked account token is sent with the request.
+package graphqlapi
+
+// The GraphQL schema. Lists are paginated with Relay-style connections.
+const schema = `
+schema {
+	query: Query
+	mutation: Mutation
+}
+
+type Query {
+	recipe(name: String!): Recipe
+	# Recipes whose name or ingredients contain the query
+	recipes(query: String!, first: Int, after: String): RecipeConnection!
+	# Recipes that use every one of the ingredients
+	recipesWithIngredients(ingredients: [String!]!, first: Int, after: String): RecipeConnection!
+	# The signed in user. Requires a linked account token.
+	me: User!
+}
+
+type Mutation {
+	addFavorite(recipe: String!): User!
+	removeFavorite(recipe: String!): User!
+	# Creates a meal plan, or replaces one when an id is given
+	saveMealPlan(plan: MealPlanInput!): MealPlan!
+	deleteMealPlan(id: ID!): Boolean!
+}
+
+type Recipe {
+	id: ID!
+	name: String!
+	ingredients: [String!]!
+}
+
+type User {
+	id: ID!
+	favorites(first: Int, after: String): RecipeConnection!
+	mealPlans(first: Int, after: String): MealPlanConnection!
+}
+
+type MealPlan {
+	id: ID!
+	name: String!
+	days: [MealPlanDay!]!
+}
+
+type MealPlanDay {
+	day: String!
+	recipes: [Recipe!]!
+}
+
+input MealPlanInput {
+	id: ID
+	name: String!
+	days: [MealPlanDayInput!]!
+}
+
+input MealPlanDayInput {
+	day: String!
+	recipes: [String!]!
+}
+
+type PageInfo {
+	hasNextPage: Boolean!
+	endCursor: String
+}
+
+type RecipeConnection {
+	edges: [RecipeEdge!]!
+	pageInfo: PageInfo!
+	totalCount: Int!
+}
+
+type RecipeEdge {
+	cursor: String!
+	node: Recipe!
+}
+
+type MealPlanConnection {
+	edges: [MealPlanEdge!]!
+	pageInfo: PageInfo!
+	totalCount: Int!
+}
+
+type MealPlanEdge {
+	cursor: String!
+	node: MealPlan!
+}
+`
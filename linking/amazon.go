@@ -0,0 +1,107 @@
+// Package linking authenticates the Login with Amazon access tokens that
+// Alexa account linking issues, for the APIs the web and mobile apps call.
+package linking
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Returned when a token is missing, expired or was not issued for a linked account
+var ErrUnauthenticated = errors.New("a valid linked account token is required")
+
+// Resolves the access token of a linked account to the ID of its user
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (string, error)
+}
+
+// The Login with Amazon profile endpoint
+const AmazonProfileURL = "https://api.amazon.com/user/profile"
+
+// Authenticates the Login with Amazon access tokens that Alexa account linking
+// issues. The user is the profile's user_id (amzn1.account...), not the Alexa
+// userId (amzn1.ask.account...), so the skill resolves the token it is sent
+// too, with skill.AccountLinkingInterceptor, to share data with the apps.
+// Profiles are cached for TTL to spare a round trip on every request.
+type LoginWithAmazon struct {
+	Client     *http.Client
+	ProfileURL string
+	TTL        time.Duration
+
+	mutex sync.Mutex
+	users map[string]cachedUser
+}
+
+type cachedUser struct {
+	id        string
+	expiresAt time.Time
+}
+
+func (auth *LoginWithAmazon) Authenticate(ctx context.Context, token string) (string, error) {
+	auth.mutex.Lock()
+	if cached, ok := auth.users[token]; ok && time.Now().Before(cached.expiresAt) {
+		auth.mutex.Unlock()
+		return cached.id, nil
+	}
+	auth.mutex.Unlock()
+
+	userID, err := auth.fetchProfile(ctx, token)
+	if err != nil {
+		return "", err
+	}
+
+	auth.mutex.Lock()
+	defer auth.mutex.Unlock()
+	if auth.users == nil {
+		auth.users = make(map[string]cachedUser)
+	}
+	for cachedToken, cached := range auth.users {
+		if time.Now().After(cached.expiresAt) {
+			delete(auth.users, cachedToken)
+		}
+	}
+	auth.users[token] = cachedUser{id: userID, expiresAt: time.Now().Add(auth.TTL)}
+	return userID, nil
+}
+
+func (auth *LoginWithAmazon) fetchProfile(ctx context.Context, token string) (string, error) {
+	url := auth.ProfileURL
+	if url == "" {
+		url = AmazonProfileURL
+	}
+	client := auth.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	response, err := client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	switch {
+	case response.StatusCode == http.StatusBadRequest || response.StatusCode == http.StatusUnauthorized:
+		return "", ErrUnauthenticated
+	case response.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("profile request failed: %s", response.Status)
+	}
+	var profile struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&profile); err != nil {
+		return "", err
+	}
+	if profile.UserID == "" {
+		return "", ErrUnauthenticated
+	}
+	return profile.UserID, nil
+}
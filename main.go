@@ -3,83 +3,377 @@ package main
 import (
 	"context"
 	"errors"
+	"expvar"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
 	"os"
-	"strings"
+	"time"
 
-	"go.mongodb.org/mongo-driver/bson/primitive"
-
-	"github.com/arienmalec/alexa-go"
 	"github.com/aws/aws-lambda-go/lambda"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/mongodb-developer/alexa-golang-example/graphqlapi"
+	"github.com/mongodb-developer/alexa-golang-example/linking"
+	"github.com/mongodb-developer/alexa-golang-example/mongodb"
+	"github.com/mongodb-developer/alexa-golang-example/recipeapi"
+	"github.com/mongodb-developer/alexa-golang-example/recipes"
+	"github.com/mongodb-developer/alexa-golang-example/secrets"
+	"github.com/mongodb-developer/alexa-golang-example/skill"
+	"github.com/mongodb-developer/alexa-golang-example/webhooks"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
-// Stores a handle to the collection being used by the Lambda function
-type Connection struct {
-	collection *mongo.Collection
+// Counts the commands sent to MongoDB by name, published through expvar under "mongo_commands"
+var commandMetrics = expvar.NewMap("mongo_commands")
+
+var commandMonitor = &event.CommandMonitor{
+	Started: func(ctx context.Context, started *event.CommandStartedEvent) {
+		commandMetrics.Add(started.CommandName, 1)
+	},
 }
 
-// A data structure representation of the collection schema
-type Recipe struct {
-	ID          primitive.ObjectID `bson:"_id"`
-	Name        string             `bson:"name"`
-	Ingredients []string           `bson:"ingredients"`
+// Connects to the cluster and makes sure it can be reached with the given read preference
+func connect(ctx context.Context, uri string, readPreference *readpref.ReadPref) (*mongo.Client, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetMonitor(commandMonitor))
+	if err != nil {
+		return nil, err
+	}
+	if err = ping(ctx, client, readPreference); err != nil {
+		client.Disconnect(ctx)
+		return nil, err
+	}
+	return client, nil
 }
 
-func (connection Connection) IntentDispatcher(ctx context.Context, request alexa.Request) (alexa.Response, error) {
-	var response alexa.Response
-	switch request.Body.Intent.Name {
-	case "GetIngredientsForRecipeIntent":
-		var recipe Recipe
-		recipeName := request.Body.Intent.Slots["recipe"].Value
-		if recipeName == "" {
-			return alexa.Response{}, errors.New("Recipe name is not present in the request")
-		}
-		if err := connection.collection.FindOne(ctx, bson.M{"name": recipeName}).Decode(&recipe); err != nil {
-			return alexa.Response{}, err
-		}
-		response = alexa.NewSimpleResponse("Ingredients", strings.Join(recipe.Ingredients, ", "))
-	case "GetRecipeFromIngredientsIntent":
-		var recipes []Recipe
-		ingredient1 := request.Body.Intent.Slots["ingredientone"].Value
-		ingredient2 := request.Body.Intent.Slots["ingredienttwo"].Value
-		cursor, err := connection.collection.Find(ctx, bson.M{
-			"ingredients": bson.D{
-				{"$all", bson.A{ingredient1, ingredient2}},
-			},
-		})
+func ping(ctx context.Context, client *mongo.Client, readPreference *readpref.ReadPref) error {
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return client.Ping(pingCtx, readPreference)
+}
+
+// Imports ingredient prices from a CSV file into the database of the recipes skill
+func importPrices(ctx context.Context, client *mongodb.Client, configs []SkillConfig, path string) error {
+	for _, config := range configs {
+		if config.Name != "recipes" {
+			continue
+		}
+		file, err := os.Open(path)
 		if err != nil {
-			return alexa.Response{}, err
+			return err
 		}
-		if err = cursor.All(ctx, &recipes); err != nil {
-			return alexa.Response{}, err
+		defer file.Close()
+		imported, err := recipes.NewMongoPriceStore(client.Collection(config.Database, "prices")).Import(ctx, file)
+		if err != nil && imported > 0 {
+			return fmt.Errorf("%s: imported %d prices, then failed: %v", path, imported, err)
+		} else if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
 		}
-		var recipeList []string
-		for _, recipe := range recipes {
-			recipeList = append(recipeList, recipe.Name)
+		log.Printf("imported %d prices into %s.prices", imported, config.Database)
+		return nil
+	}
+	return errors.New("prices are only used by the recipes skill, which isn't configured")
+}
+
+// Stores the nutrition per serving of the recipes skill's recipes
+func updateNutrition(ctx context.Context, client *mongodb.Client, configs []SkillConfig) error {
+	for _, config := range configs {
+		if config.Name != "recipes" {
+			continue
+		}
+		catalog := webhooks.NewCatalog(client.Collection(config.Database, config.Collection), webhooks.NewStore(client, config.Database))
+		updated, err := catalog.UpdateNutrition(ctx, client.Collection(config.Database, "ingredientNutrition"))
+		if err != nil {
+			return err
+		}
+		log.Printf("updated the nutrition of %d recipes in %s.%s", updated, config.Database, config.Collection)
+		return nil
+	}
+	return errors.New("nutrition is only used by the recipes skill, which isn't configured")
+}
+
+// Reads the pronunciation lexicon at LEXICON_PATH, or the default one if it
+// exists, returning nil when there is none
+func loadLexicon() (*skill.Lexicon, error) {
+	path := os.Getenv("LEXICON_PATH")
+	if path == "" {
+		path = skill.DefaultLexiconPath
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil, nil
 		}
-		response = alexa.NewSimpleResponse("Recipes", strings.Join(recipeList, ", "))
-	case "AboutIntent":
-		response = alexa.NewSimpleResponse("About", "Created by Nic Raboy in Tracy, CA")
-	default:
-		response = alexa.NewSimpleResponse("Unknown Request", "The intent was unrecognized")
 	}
-	return response, nil
+	return skill.LoadLexicon(path)
 }
 
 func main() {
+	snapshot := flag.Bool("snapshot", false, "write a snapshot of each skill's collection to its snapshot path and exit")
+	httpAddress := flag.String("http", "", "serve the skill on /alexa and the GraphQL API on /graphql on this address instead of running as a Lambda function")
+	grpcAddress := flag.String("grpc", "", "serve the recipe API over gRPC on this address instead of running as a Lambda function")
+	grpcCert := flag.String("grpc-cert", "", "the TLS certificate file the recipe API is served with")
+	grpcKey := flag.String("grpc-key", "", "the TLS key file the recipe API is served with")
+	grpcPlaintext := flag.Bool("grpc-plaintext", false, "serve the recipe API without TLS, for local use or behind a proxy that terminates TLS")
+	pricesPath := flag.String("import-prices", "", "import ingredient prices from this CSV file into the recipes skill's database and exit")
+	nutrition := flag.Bool("update-nutrition", false, "compute the nutrition per serving of the recipes skill's recipes from the ingredientNutrition collection and exit")
+	unsigned := flag.Bool("unsigned", false, "with -http, accept requests that Alexa didn't sign, for load tests against a private address")
+	adminAddress := flag.String("admin", "", "serve the expvar metrics on /debug/vars on this address, which should not be reachable from the internet, such as localhost:9090")
+	dispatch := flag.Bool("dispatch", false, "deliver the pending webhook events and exit, for running on a schedule next to a Lambda deployment")
+	faultsPath := flag.String("faults", "", "inject the faults scheduled in this JSON file into recipe lookups, for trying out error handling")
+	flag.Parse()
+
+	configs, err := LoadSkillConfigs()
+	if err != nil {
+		panic(err)
+	}
+	catalogReads, err := LoadReadConfig(CatalogQueries)
+	if err != nil {
+		panic(err)
+	}
+	userDataReads, err := LoadReadConfig(UserDataQueries)
+	if err != nil {
+		panic(err)
+	}
+	lexicon, err := loadLexicon()
+	if err != nil {
+		panic(err)
+	}
+	var faults *recipes.FaultSchedule
+	if *faultsPath != "" {
+		schedule, err := recipes.LoadFaultSchedule(*faultsPath)
+		if err != nil {
+			panic(err)
+		}
+		log.Printf("injecting faults from %s into recipe lookups", *faultsPath)
+		faults = &schedule
+	}
+
 	ctx := context.Background()
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(os.Getenv("ATLAS_URI")))
+	cachedSecrets, secretName, err := secrets.NewFromEnv(ctx)
 	if err != nil {
 		panic(err)
 	}
+	uri, err := cachedSecrets.Secret(ctx, secretName)
+	var connected *mongo.Client
+	if err == nil {
+		// The driver keeps trying to reach the cluster, so the client is kept
+		// even when the ping fails
+		connected, err = mongo.Connect(ctx, options.Client().ApplyURI(uri).SetMonitor(commandMonitor))
+	}
+	unreachable := err
+	var client *mongodb.Client
+	if err == nil {
+		unreachable = ping(ctx, connected, catalogReads.ReadPreference)
+		// Every store reads through client, so they all reconnect together when
+		// the database rejects rotated credentials
+		client = mongodb.NewClient(cachedSecrets, secretName, uri, connected, func(ctx context.Context, uri string) (*mongo.Client, error) {
+			return connect(ctx, uri, catalogReads.ReadPreference)
+		})
+	}
+
+	if *snapshot {
+		if unreachable != nil {
+			panic(unreachable)
+		}
+		defer client.Disconnect(ctx)
+		for _, config := range configs {
+			if err := recipes.WriteSnapshot(ctx, client.Current().Database(config.Database).Collection(config.Collection), config.Snapshot); err != nil {
+				panic(err)
+			}
+		}
+		return
+	}
+	if *pricesPath != "" {
+		if unreachable != nil {
+			panic(unreachable)
+		}
+		defer client.Disconnect(ctx)
+		if err := importPrices(ctx, client, configs, *pricesPath); err != nil {
+			panic(err)
+		}
+		return
+	}
+	if *nutrition {
+		if unreachable != nil {
+			panic(unreachable)
+		}
+		defer client.Disconnect(ctx)
+		if err := updateNutrition(ctx, client, configs); err != nil {
+			panic(err)
+		}
+		return
+	}
+	if err == nil {
+		defer client.Disconnect(ctx)
+	}
+	if unreachable != nil {
+		log.Printf("database unreachable, answering from the snapshots until it can be reached: %v", unreachable)
+	}
 
-	defer client.Disconnect(ctx)
+	// Resolves the access tokens of linked accounts, sent by the skill and the
+	// apps alike, to the users they belong to
+	auth := &linking.LoginWithAmazon{TTL: 5 * time.Minute}
+	router := skill.NewRouter()
+	stores := make(map[string]recipes.RecipeStore)
+	var favorites recipes.FavoriteStore
+	var plans recipes.MealPlanStore
+	var webhookStore *webhooks.Store
+	var catalog *webhooks.Catalog
+	for _, config := range configs {
+		config := config
+		var store recipes.RecipeStore
+		var persistence skill.PersistenceAdapter
+		build := skillBuilders[config.Name]
+		offline, snapshotErr := recipes.LoadSnapshot(config.Snapshot)
+		if snapshotErr != nil {
+			if unreachable != nil {
+				panic(fmt.Errorf("database unreachable: %v; snapshot %s: %v", unreachable, config.Snapshot, snapshotErr))
+			}
+			log.Printf("no snapshot to fall back to for %s: %v", config.Name, snapshotErr)
+		}
+		if err != nil {
+			// Without a client there is nothing to reconnect, so the snapshot is all there is
+			log.Printf("no database client, serving %s from %s: %v", config.Name, config.Snapshot, err)
+			store = offline
+			if faults != nil {
+				store = recipes.NewFaultyStore(store, *faults)
+			}
+			if config.Name == "recipes" {
+				build = func(store recipes.RecipeStore) *skill.Skill {
+					return recipes.NewSkillFrom(recipes.Sources{Recipes: store, Lexicon: lexicon})
+				}
+			}
+		} else {
+			var database recipes.RecipeStore = recipes.NewMongoRecipeStore(catalogReads.Collection(client, config.Database, config.Collection))
+			if faults != nil {
+				// Faults go beneath the retries and the circuit breaker so they are exercised too
+				database = recipes.NewFaultyStore(database, *faults)
+			}
+			resilient := recipes.NewResilientStore(
+				database,
+				recipes.RetryPolicy{MaxAttempts: 3, BaseDelay: 50 * time.Millisecond, MaxDelay: time.Second},
+				&recipes.CircuitBreaker{Threshold: 5, Cooldown: 30 * time.Second},
+			)
+			if snapshotErr == nil {
+				resilient.WithFallback(offline)
+			}
+			store = resilient
+			persistence = skill.NewMongoPersistenceAdapter(userDataReads.Collection(client, config.Database, config.Users))
+			if config.Name == "recipes" {
+				favorites = recipes.NewMongoFavoriteStore(userDataReads.Collection(client, config.Database, "favorites"))
+				plans = recipes.NewMongoMealPlanStore(userDataReads.Collection(client, config.Database, "mealPlans"))
+				webhookStore = webhooks.NewStore(client, config.Database)
+				catalog = webhooks.NewCatalog(userDataReads.Collection(client, config.Database, config.Collection), webhookStore)
+				glossary := recipes.NewMongoGlossaryStore(catalogReads.Collection(client, config.Database, "glossary"), 10*time.Minute)
+				prices := recipes.NewMongoPriceStore(catalogReads.Collection(client, config.Database, "prices"))
+				taxonomy := recipes.NewMongoTaxonomyStore(catalogReads.Collection(client, config.Database, "ingredientTaxonomy"), 10*time.Minute)
+				goals := recipes.NewMongoNutritionGoalStore(userDataReads.Collection(client, config.Database, "nutritionGoals"))
+				pantry := recipes.NewMongoPantryStore(userDataReads.Collection(client, config.Database, "pantries"))
+				build = func(store recipes.RecipeStore) *skill.Skill {
+					return recipes.NewSkillFrom(recipes.Sources{
+						Recipes:  store,
+						Glossary: glossary,
+						Prices:   prices,
+						Taxonomy: taxonomy,
+						Plans:    plans,
+						Goals:    goals,
+						Pantry:   pantry,
+						Lexicon:  lexicon,
+						Accounts: auth,
+					})
+				}
+			}
+		}
+		stores[config.Name] = store
 
-	connection := Connection{
-		collection: client.Database("alexa").Collection("recipes"),
+		hosted := build(store)
+		if persistence != nil {
+			hosted.WithPersistenceAdapter(persistence)
+		}
+		router.Handle(config.ApplicationID, hosted)
 	}
 
-	lambda.Start(connection.IntentDispatcher)
+	if *adminAddress != "" {
+		admin := http.NewServeMux()
+		admin.Handle("/debug/vars", expvar.Handler())
+		go func() {
+			log.Printf("serving metrics on %s", *adminAddress)
+			if err := http.ListenAndServe(*adminAddress, admin); err != nil {
+				panic(err)
+			}
+		}()
+	}
+	if *dispatch {
+		if webhookStore == nil {
+			panic("webhooks need the recipes skill's database")
+		}
+		if err := webhooks.NewDispatcher(webhookStore).Drain(ctx); err != nil {
+			panic(err)
+		}
+		return
+	}
+	// Servers deliver webhook events as they run; Lambda functions are frozen
+	// between requests, so they rely on -dispatch being run on a schedule
+	if webhookStore != nil && (*grpcAddress != "" || *httpAddress != "") {
+		go webhooks.NewDispatcher(webhookStore).Run(ctx)
+	}
+	if *grpcAddress != "" {
+		if stores["recipes"] == nil {
+			panic("the recipe API needs the recipes skill to be configured")
+		}
+		var serverOptions []grpc.ServerOption
+		if *grpcCert != "" || *grpcKey != "" {
+			tls, err := credentials.NewServerTLSFromFile(*grpcCert, *grpcKey)
+			if err != nil {
+				panic(err)
+			}
+			serverOptions = append(serverOptions, grpc.Creds(tls))
+		} else if !*grpcPlaintext {
+			panic("the recipe API carries access tokens, so it needs -grpc-cert and -grpc-key, or -grpc-plaintext behind a proxy that terminates TLS")
+		}
+		listener, err := net.Listen("tcp", *grpcAddress)
+		if err != nil {
+			panic(err)
+		}
+		server := grpc.NewServer(serverOptions...)
+		recipeapi.RegisterRecipeServiceServer(server, recipeapi.NewServer(stores["recipes"], favorites, auth))
+		log.Printf("serving the recipe API on %s", listener.Addr())
+		if err := server.Serve(listener); err != nil {
+			panic(err)
+		}
+		return
+	}
+	if *httpAddress != "" {
+		mux := http.NewServeMux()
+		verifier := &skill.Verifier{}
+		if *unsigned {
+			log.Printf("accepting requests that Alexa didn't sign")
+			verifier = nil
+		}
+		mux.Handle("/alexa", skill.NewHTTPHandler(router.Invoke, verifier))
+		if stores["recipes"] != nil {
+			graphqlHandler, err := graphqlapi.NewHandler(stores["recipes"], favorites, plans, auth)
+			if err != nil {
+				panic(err)
+			}
+			mux.Handle("/graphql", graphqlHandler)
+		}
+		if webhookStore != nil {
+			if token := os.Getenv("ADMIN_TOKEN"); token != "" {
+				admin := webhooks.NewAdminHandler(webhookStore, catalog, token)
+				mux.Handle("/webhooks", admin)
+				mux.Handle("/webhooks/", admin)
+				mux.Handle("/recipes", admin)
+			}
+		}
+		log.Printf("serving HTTP on %s", *httpAddress)
+		if err := http.ListenAndServe(*httpAddress, mux); err != nil {
+			panic(err)
+		}
+		return
+	}
+	lambda.Start(skill.EventHandler(router.Invoke))
 }
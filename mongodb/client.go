@@ -0,0 +1,124 @@
+// Package mongodb keeps the connection to MongoDB, reconnecting with a freshly
+// fetched URI when the database rejects the current credentials, so a rotated
+// password is picked up by warm functions without a redeploy.
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/mongodb-developer/alexa-golang-example/secrets"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Connects to the database at a URI
+type Connector func(ctx context.Context, uri string) (*mongo.Client, error)
+
+// Holds the client connected with the current credentials. Every store reads
+// its collections through it, so all of them move to a new client together.
+type Client struct {
+	secrets *secrets.Cached
+	name    string
+	connect Connector
+
+	// Held through a rotation, so concurrent rejections connect only once
+	rotating sync.Mutex
+	// Held only to read or swap the current client
+	mutex   sync.RWMutex
+	uri     string
+	current *mongo.Client
+}
+
+// Wraps client, which is connected to uri, the value of the secret name.
+// Connects with connect when the secret is rotated.
+func NewClient(cached *secrets.Cached, name string, uri string, client *mongo.Client, connect Connector) *Client {
+	return &Client{secrets: cached, name: name, connect: connect, uri: uri, current: client}
+}
+
+// Reports whether the database refused the credentials in the URI
+func IsAuthError(err error) bool {
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) && commandErr.Code == 18 {
+		return true
+	}
+	return err != nil && (strings.Contains(err.Error(), "auth error") ||
+		strings.Contains(err.Error(), "AuthenticationFailed"))
+}
+
+// The client connected with the latest credentials
+func (client *Client) Current() *mongo.Client {
+	client.mutex.RLock()
+	defer client.mutex.RUnlock()
+	return client.current
+}
+
+func (client *Client) Disconnect(ctx context.Context) error {
+	return client.Current().Disconnect(ctx)
+}
+
+// Runs operation with the current client and, if the database rejects its
+// credentials, once more with a client connected with the rotated ones.
+// Operations that fail authentication never reach the database, so repeating
+// a write is safe.
+func (client *Client) Do(ctx context.Context, operation func(*mongo.Client) error) error {
+	current := client.Current()
+	err := operation(current)
+	if !IsAuthError(err) {
+		return err
+	}
+	rotated, rotateErr := client.rotate(ctx, current)
+	if rotateErr != nil {
+		log.Printf("unable to rotate database credentials: %v", rotateErr)
+		return err
+	}
+	if rotated == nil {
+		return err
+	}
+	return operation(rotated)
+}
+
+// Fetches the URI again and connects with it when it has changed. Returns nil
+// when the secret hasn't been rotated yet. The secret is fetched and the new
+// client connected without holding up Current, which only waits for the swap.
+func (client *Client) rotate(ctx context.Context, failed *mongo.Client) (*mongo.Client, error) {
+	client.rotating.Lock()
+	defer client.rotating.Unlock()
+	client.mutex.RLock()
+	current, currentURI := client.current, client.uri
+	client.mutex.RUnlock()
+	if current != failed {
+		// Another request already rotated while this one was waiting
+		return current, nil
+	}
+	client.secrets.Invalidate(client.name)
+	uri, err := client.secrets.Secret(ctx, client.name)
+	if err != nil {
+		return nil, err
+	}
+	if uri == currentURI {
+		return nil, nil
+	}
+	rotated, err := client.connect(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	client.mutex.Lock()
+	swapped := client.current == failed
+	if swapped {
+		client.uri, client.current = uri, rotated
+	}
+	current = client.current
+	client.mutex.Unlock()
+	if !swapped {
+		go rotated.Disconnect(context.Background())
+		return current, nil
+	}
+	log.Printf("reconnected to the database with rotated credentials")
+	// Operations already running on the old client may still finish
+	go failed.Disconnect(context.Background())
+	return rotated, nil
+}
@@ -0,0 +1,109 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// A collection of whichever client currently holds the credentials, with the
+// methods of mongo.Collection the stores use
+type Collection struct {
+	client   *Client
+	database string
+	name     string
+	options  []*options.CollectionOptions
+}
+
+func (client *Client) Collection(database string, name string, opts ...*options.CollectionOptions) *Collection {
+	return &Collection{client: client, database: database, name: name, options: opts}
+}
+
+// The collection of a particular client, such as the one passed to Client.Do
+// for a transaction, whose operations must all use the client of the session
+func (collection *Collection) On(client *mongo.Client) *mongo.Collection {
+	return client.Database(collection.database).Collection(collection.name, collection.options...)
+}
+
+// The collection of the current client, for operations Collection doesn't wrap
+func (collection *Collection) Current() *mongo.Collection {
+	return collection.On(collection.client.Current())
+}
+
+// The client the collection belongs to
+func (collection *Collection) Client() *Client {
+	return collection.client
+}
+
+func (collection *Collection) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	var cursor *mongo.Cursor
+	err := collection.client.Do(ctx, func(client *mongo.Client) (err error) {
+		cursor, err = collection.On(client).Find(ctx, filter, opts...)
+		return err
+	})
+	return cursor, err
+}
+
+func (collection *Collection) FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult {
+	var result *mongo.SingleResult
+	collection.client.Do(ctx, func(client *mongo.Client) error {
+		result = collection.On(client).FindOne(ctx, filter, opts...)
+		return result.Err()
+	})
+	return result
+}
+
+func (collection *Collection) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	var result *mongo.SingleResult
+	collection.client.Do(ctx, func(client *mongo.Client) error {
+		result = collection.On(client).FindOneAndUpdate(ctx, filter, update, opts...)
+		return result.Err()
+	})
+	return result
+}
+
+func (collection *Collection) InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error) {
+	var result *mongo.InsertOneResult
+	err := collection.client.Do(ctx, func(client *mongo.Client) (err error) {
+		result, err = collection.On(client).InsertOne(ctx, document, opts...)
+		return err
+	})
+	return result, err
+}
+
+func (collection *Collection) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	var result *mongo.UpdateResult
+	err := collection.client.Do(ctx, func(client *mongo.Client) (err error) {
+		result, err = collection.On(client).UpdateOne(ctx, filter, update, opts...)
+		return err
+	})
+	return result, err
+}
+
+func (collection *Collection) ReplaceOne(ctx context.Context, filter interface{}, replacement interface{}, opts ...*options.ReplaceOptions) (*mongo.UpdateResult, error) {
+	var result *mongo.UpdateResult
+	err := collection.client.Do(ctx, func(client *mongo.Client) (err error) {
+		result, err = collection.On(client).ReplaceOne(ctx, filter, replacement, opts...)
+		return err
+	})
+	return result, err
+}
+
+func (collection *Collection) DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	var result *mongo.DeleteResult
+	err := collection.client.Do(ctx, func(client *mongo.Client) (err error) {
+		result, err = collection.On(client).DeleteOne(ctx, filter, opts...)
+		return err
+	})
+	return result, err
+}
+
+func (collection *Collection) BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	var result *mongo.BulkWriteResult
+	err := collection.client.Do(ctx, func(client *mongo.Client) (err error) {
+		result, err = collection.On(client).BulkWrite(ctx, models, opts...)
+		return err
+	})
+	return result, err
+}
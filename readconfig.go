@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mongodb-developer/alexa-golang-example/mongodb"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// Groups queries that share consistency requirements. The value is used as
+// the prefix of the environment variables that configure the class.
+type QueryClass string
+
+const (
+	// Recipe catalog lookups, which tolerate slightly stale data
+	CatalogQueries QueryClass = "CATALOG"
+	// Per-user data, which must read its own writes
+	UserDataQueries QueryClass = "USERDATA"
+)
+
+// Defaults used when a query class has no environment configuration
+var readDefaults = map[QueryClass]map[string]string{
+	CatalogQueries: {
+		"READ_PREFERENCE": "nearest",
+		"READ_CONCERN":    "local",
+	},
+	UserDataQueries: {
+		"READ_PREFERENCE": "primary",
+		"READ_CONCERN":    "majority",
+	},
+}
+
+// The read preference, read concern and write concern used for a query class
+type ReadConfig struct {
+	ReadPreference *readpref.ReadPref
+	ReadConcern    *readconcern.ReadConcern
+	WriteConcern   *writeconcern.WriteConcern
+}
+
+// Builds the configuration for a query class from <CLASS>_READ_PREFERENCE,
+// <CLASS>_READ_CONCERN and <CLASS>_MAX_STALENESS, for example
+// CATALOG_READ_PREFERENCE=secondaryPreferred and CATALOG_MAX_STALENESS=120s.
+// Writes always use a majority write concern.
+func LoadReadConfig(class QueryClass) (ReadConfig, error) {
+	setting := func(name string) string {
+		if value := os.Getenv(string(class) + "_" + name); value != "" {
+			return value
+		}
+		return readDefaults[class][name]
+	}
+
+	mode, err := readpref.ModeFromString(setting("READ_PREFERENCE"))
+	if err != nil {
+		return ReadConfig{}, fmt.Errorf("%s_READ_PREFERENCE: %v", class, err)
+	}
+	if class == UserDataQueries && mode != readpref.PrimaryMode {
+		// Versioned pantry saves and catalog transactions read what they wrote
+		return ReadConfig{}, fmt.Errorf("%s_READ_PREFERENCE: user data must be read from the primary, not %s", class, mode)
+	}
+	var readPrefOptions []readpref.Option
+	if staleness := setting("MAX_STALENESS"); staleness != "" {
+		if mode == readpref.PrimaryMode {
+			return ReadConfig{}, fmt.Errorf("%s_MAX_STALENESS: can't be used with the primary read preference", class)
+		}
+		maxStaleness, err := time.ParseDuration(staleness)
+		if err != nil {
+			return ReadConfig{}, fmt.Errorf("%s_MAX_STALENESS: %v", class, err)
+		}
+		readPrefOptions = append(readPrefOptions, readpref.WithMaxStaleness(maxStaleness))
+	}
+	readPreference, err := readpref.New(mode, readPrefOptions...)
+	if err != nil {
+		return ReadConfig{}, fmt.Errorf("%s read preference: %v", class, err)
+	}
+
+	level := setting("READ_CONCERN")
+	switch level {
+	case "local", "available", "majority":
+	case "linearizable":
+		if mode != readpref.PrimaryMode {
+			return ReadConfig{}, fmt.Errorf("%s_READ_CONCERN: linearizable reads must use the primary read preference, not %s", class, mode)
+		}
+	case "snapshot":
+		// MongoDB only allows snapshot reads in transactions and snapshot
+		// sessions, so every query would fail
+		return ReadConfig{}, fmt.Errorf("%s_READ_CONCERN: snapshot is only allowed in transactions", class)
+	default:
+		return ReadConfig{}, fmt.Errorf("%s_READ_CONCERN: unknown level %q", class, level)
+	}
+
+	return ReadConfig{
+		ReadPreference: readPreference,
+		ReadConcern:    readconcern.New(readconcern.Level(level)),
+		WriteConcern:   writeconcern.New(writeconcern.WMajority()),
+	}, nil
+}
+
+// Returns a handle to the named collection that uses this configuration
+func (config ReadConfig) Collection(client *mongodb.Client, database string, name string) *mongodb.Collection {
+	return client.Collection(database, name, options.Collection().
+		SetReadPreference(config.ReadPreference).
+		SetReadConcern(config.ReadConcern).
+		SetWriteConcern(config.WriteConcern))
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLoadReadConfigRejectsSnapshotReadConcern(t *testing.T) {
+	t.Setenv("CATALOG_READ_CONCERN", "snapshot")
+	if _, err := LoadReadConfig(CatalogQueries); err == nil {
+		t.Fatal("got no error for a snapshot read concern")
+	}
+}
+
+func TestLoadReadConfigDefaults(t *testing.T) {
+	config, err := LoadReadConfig(UserDataQueries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.ReadConcern.GetLevel() != "majority" || config.ReadPreference.Mode().String() != "primary" {
+		t.Errorf("got %s and %s, want majority reads from the primary", config.ReadConcern.GetLevel(), config.ReadPreference.Mode())
+	}
+}
+
+func TestLoadReadConfigRejectsInconsistentSettings(t *testing.T) {
+	tests := []struct {
+		class    QueryClass
+		settings map[string]string
+	}{
+		{UserDataQueries, map[string]string{"USERDATA_READ_PREFERENCE": "secondary"}},
+		{UserDataQueries, map[string]string{"USERDATA_READ_PREFERENCE": "primaryPreferred"}},
+		{UserDataQueries, map[string]string{"USERDATA_MAX_STALENESS": "120s"}},
+		{CatalogQueries, map[string]string{"CATALOG_READ_PREFERENCE": "nearest", "CATALOG_READ_CONCERN": "linearizable"}},
+		{CatalogQueries, map[string]string{"CATALOG_READ_PREFERENCE": "primary", "CATALOG_MAX_STALENESS": "120s"}},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.settings), func(t *testing.T) {
+			for name, value := range test.settings {
+				t.Setenv(name, value)
+			}
+			if _, err := LoadReadConfig(test.class); err == nil {
+				t.Errorf("got no error for %v", test.settings)
+			}
+		})
+	}
+
+	t.Setenv("CATALOG_READ_PREFERENCE", "primary")
+	t.Setenv("CATALOG_READ_CONCERN", "linearizable")
+	if _, err := LoadReadConfig(CatalogQueries); err != nil {
+		t.Errorf("got %v for linearizable reads from the primary", err)
+	}
+}
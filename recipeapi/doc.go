@@ -0,0 +1,6 @@
+// Package recipeapi serves the recipe lookups the skill makes, and users'
+// favorites, over gRPC for the web and mobile apps. The contract is in
+// recipes.proto, which the messages and service stubs are generated from.
+package recipeapi
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative recipes.proto
@@ -0,0 +1,40 @@
+package recipeapi
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// The in-memory buffer size of an in-process connection
+const inProcessBufferSize = 1 << 20
+
+// Serves service over an in-memory listener and returns a client connected to
+// it, so other Go code and tests can exercise the API without a network port.
+// The returned function closes the client and stops the server.
+func NewInProcessClient(service RecipeServiceServer, opts ...grpc.ServerOption) (RecipeServiceClient, func(), error) {
+	listener := bufconn.Listen(inProcessBufferSize)
+	server := grpc.NewServer(opts...)
+	RegisterRecipeServiceServer(server, service)
+	go server.Serve(listener)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///recipeapi",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		server.Stop()
+		return nil, nil, err
+	}
+	stop := func() {
+		conn.Close()
+		server.Stop()
+	}
+	return NewRecipeServiceClient(conn), stop, nil
+}
@@ -0,0 +1,238 @@
+package recipeapi
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/mongodb-developer/alexa-golang-example/linking"
+	"github.com/mongodb-developer/alexa-golang-example/recipes"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Implements RecipeService on top of the same stores the skill uses
+type Server struct {
+	UnimplementedRecipeServiceServer
+	store     recipes.RecipeStore
+	favorites recipes.FavoriteStore
+	auth      linking.Authenticator
+}
+
+// Serves lookups from store and favorites from favorites, which may be nil
+// while the database is unreachable. Favorites belong to the user auth
+// resolves the call's access token to.
+func NewServer(store recipes.RecipeStore, favorites recipes.FavoriteStore, auth linking.Authenticator) *Server {
+	return &Server{store: store, favorites: favorites, auth: auth}
+}
+
+func (server *Server) SearchRecipes(ctx context.Context, request *SearchRecipesRequest) (*SearchRecipesResponse, error) {
+	if request.Query == "" {
+		return nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+	pageSize := int(request.PageSize)
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	} else if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	offset, err := decodePageToken(request.PageToken)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "page_token is invalid")
+	}
+	found, err := server.store.Query(ctx, recipes.RecipeQuery{Text: request.Query})
+	degraded, err := classify(err)
+	if err != nil {
+		return nil, err
+	}
+	response := &SearchRecipesResponse{Degraded: degraded}
+	if offset < len(found) {
+		end := offset + pageSize
+		if end < len(found) {
+			response.NextPageToken = encodePageToken(end)
+		} else {
+			end = len(found)
+		}
+		response.Recipes = toMessages(found[offset:end])
+	}
+	return response, nil
+}
+
+func (server *Server) GetRecipe(ctx context.Context, request *GetRecipeRequest) (*GetRecipeResponse, error) {
+	if request.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	recipe, err := server.store.FindByName(ctx, request.Name)
+	if err == mongo.ErrNoDocuments {
+		return nil, status.Errorf(codes.NotFound, "recipe %q not found", request.Name)
+	}
+	degraded, err := classify(err)
+	if err != nil {
+		return nil, err
+	}
+	return &GetRecipeResponse{Recipe: toMessage(recipe), Degraded: degraded}, nil
+}
+
+func (server *Server) MatchIngredients(ctx context.Context, request *MatchIngredientsRequest) (*MatchIngredientsResponse, error) {
+	if len(request.Ingredients) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "at least one ingredient is required")
+	}
+	found, err := server.store.FindByIngredients(ctx, request.Ingredients)
+	degraded, err := classify(err)
+	if err != nil {
+		return nil, err
+	}
+	return &MatchIngredientsResponse{Recipes: toMessages(found), Degraded: degraded}, nil
+}
+
+func (server *Server) ListFavorites(ctx context.Context, request *ListFavoritesRequest) (*ListFavoritesResponse, error) {
+	userID, err := server.user(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return server.listFavorites(ctx, userID)
+}
+
+func (server *Server) AddFavorite(ctx context.Context, request *FavoriteRequest) (*ListFavoritesResponse, error) {
+	userID, err := server.checkFavorite(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := server.store.FindByName(ctx, request.RecipeName); err == mongo.ErrNoDocuments {
+		return nil, status.Errorf(codes.NotFound, "recipe %q not found", request.RecipeName)
+	} else if _, err := classify(err); err != nil {
+		return nil, err
+	}
+	if err := server.favorites.AddFavorite(ctx, userID, request.RecipeName); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return server.listFavorites(ctx, userID)
+}
+
+func (server *Server) RemoveFavorite(ctx context.Context, request *FavoriteRequest) (*ListFavoritesResponse, error) {
+	userID, err := server.checkFavorite(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if err := server.favorites.RemoveFavorite(ctx, userID, request.RecipeName); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return server.listFavorites(ctx, userID)
+}
+
+// Returns the user the favorites request is for
+func (server *Server) checkFavorite(ctx context.Context, request *FavoriteRequest) (string, error) {
+	if request.RecipeName == "" {
+		return "", status.Error(codes.InvalidArgument, "recipe_name is required")
+	}
+	userID, err := server.user(ctx)
+	if err != nil {
+		return "", err
+	}
+	if server.favorites == nil {
+		return "", status.Error(codes.Unavailable, "favorites are unavailable while the database is unreachable")
+	}
+	return userID, nil
+}
+
+// Authenticates the access token sent as "authorization: Bearer <token>" in
+// the call's metadata, the way the GraphQL API checks its Authorization header
+func (server *Server) user(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 || server.auth == nil {
+		return "", status.Error(codes.Unauthenticated, linking.ErrUnauthenticated.Error())
+	}
+	token := strings.TrimPrefix(values[0], "Bearer ")
+	if token == values[0] {
+		return "", status.Error(codes.Unauthenticated, linking.ErrUnauthenticated.Error())
+	}
+	userID, err := server.auth.Authenticate(ctx, token)
+	if err == linking.ErrUnauthenticated {
+		return "", status.Error(codes.Unauthenticated, err.Error())
+	} else if err != nil {
+		log.Printf("unable to authenticate: %v", err)
+		return "", status.Error(codes.Unavailable, "unable to check the token")
+	}
+	return userID, nil
+}
+
+func (server *Server) listFavorites(ctx context.Context, userID string) (*ListFavoritesResponse, error) {
+	if server.favorites == nil {
+		return nil, status.Error(codes.Unavailable, "favorites are unavailable while the database is unreachable")
+	}
+	names, err := server.favorites.Favorites(ctx, userID)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	response := &ListFavoritesResponse{Recipes: []*Recipe{}}
+	if len(names) == 0 {
+		return response, nil
+	}
+	found, err := server.store.FindByNames(ctx, names)
+	response.Degraded, err = classify(err)
+	if err != nil {
+		return nil, err
+	}
+	response.Recipes = toMessages(found)
+	return response, nil
+}
+
+// Reports whether a store answered from a cache or snapshot, and converts any
+// other store error into a status
+func classify(err error) (bool, error) {
+	switch {
+	case err == nil:
+		return false, nil
+	case errors.Is(err, recipes.ErrDegraded):
+		return true, nil
+	case errors.Is(err, context.Canceled):
+		return false, status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return false, status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, recipes.ErrCircuitOpen) || recipes.IsRetryable(err):
+		return false, status.Error(codes.Unavailable, err.Error())
+	}
+	return false, status.Error(codes.Internal, err.Error())
+}
+
+func encodePageToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodePageToken(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, err
+	}
+	offset, err := strconv.Atoi(string(decoded))
+	if err == nil && offset < 0 {
+		err = errors.New("negative offset")
+	}
+	return offset, err
+}
+
+func toMessage(recipe recipes.Recipe) *Recipe {
+	return &Recipe{Id: recipe.ID.Hex(), Name: recipe.Name, Ingredients: recipe.Ingredients}
+}
+
+func toMessages(found []recipes.Recipe) []*Recipe {
+	messages := make([]*Recipe, 0, len(found))
+	for _, recipe := range found {
+		messages = append(messages, toMessage(recipe))
+	}
+	return messages
+}
@@ -0,0 +1,242 @@
+package recipeapi
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mongodb-developer/alexa-golang-example/linking"
+	"github.com/mongodb-developer/alexa-golang-example/recipes"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// A RecipeStore holding a few recipes in memory, answering with err as well
+type memoryStore struct {
+	recipes []recipes.Recipe
+	err     error
+}
+
+func (store *memoryStore) FindByName(ctx context.Context, name string) (recipes.Recipe, error) {
+	for _, recipe := range store.recipes {
+		if recipe.Name == name {
+			return recipe, store.err
+		}
+	}
+	return recipes.Recipe{}, mongo.ErrNoDocuments
+}
+
+func (store *memoryStore) FindByNames(ctx context.Context, names []string) ([]recipes.Recipe, error) {
+	var found []recipes.Recipe
+	for _, name := range names {
+		if recipe, err := store.FindByName(ctx, name); err != mongo.ErrNoDocuments {
+			found = append(found, recipe)
+		}
+	}
+	return found, store.err
+}
+
+func (store *memoryStore) FindByIngredients(ctx context.Context, ingredients []string) ([]recipes.Recipe, error) {
+	var groups [][]string
+	for _, ingredient := range ingredients {
+		groups = append(groups, []string{ingredient})
+	}
+	return store.Query(ctx, recipes.RecipeQuery{IngredientGroups: groups})
+}
+
+func (store *memoryStore) Query(ctx context.Context, query recipes.RecipeQuery) ([]recipes.Recipe, error) {
+	var found []recipes.Recipe
+	for _, recipe := range store.recipes {
+		if query.Matches(recipe) {
+			found = append(found, recipe)
+		}
+	}
+	return found, store.err
+}
+
+// A FavoriteStore keeping each user's favorites in memory
+type memoryFavorites map[string][]string
+
+func (favorites memoryFavorites) Favorites(ctx context.Context, userID string) ([]string, error) {
+	return favorites[userID], nil
+}
+
+func (favorites memoryFavorites) AddFavorite(ctx context.Context, userID string, recipeName string) error {
+	favorites[userID] = append(favorites[userID], recipeName)
+	return nil
+}
+
+func (favorites memoryFavorites) RemoveFavorite(ctx context.Context, userID string, recipeName string) error {
+	var kept []string
+	for _, name := range favorites[userID] {
+		if name != recipeName {
+			kept = append(kept, name)
+		}
+	}
+	favorites[userID] = kept
+	return nil
+}
+
+// An Authenticator knowing the user of each access token
+type tokenAuth map[string]string
+
+func (auth tokenAuth) Authenticate(ctx context.Context, token string) (string, error) {
+	if userID, ok := auth[token]; ok {
+		return userID, nil
+	}
+	return "", linking.ErrUnauthenticated
+}
+
+var testRecipes = []recipes.Recipe{
+	{Name: "Chicken Soup", Ingredients: []string{"chicken", "carrots", "onion"}},
+	{Name: "Chicken Curry", Ingredients: []string{"chicken", "rice", "onion"}},
+	{Name: "Fried Rice", Ingredients: []string{"rice", "egg", "onion"}},
+}
+
+// Sends token with the calls made with the returned context
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+// Connects to a Server over the in-process connection
+func newTestClient(t *testing.T, store recipes.RecipeStore, favorites recipes.FavoriteStore) RecipeServiceClient {
+	client, stop, err := NewInProcessClient(NewServer(store, favorites, tokenAuth{"alice-token": "alice", "bob-token": "bob"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(stop)
+	return client
+}
+
+func names(messages []*Recipe) string {
+	var found []string
+	for _, message := range messages {
+		found = append(found, message.GetName())
+	}
+	return strings.Join(found, ", ")
+}
+
+func TestSearchRecipesPages(t *testing.T) {
+	client := newTestClient(t, &memoryStore{recipes: testRecipes}, nil)
+	ctx := context.Background()
+
+	first, err := client.SearchRecipes(ctx, &SearchRecipesRequest{Query: "onion", PageSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(first.GetRecipes()); got != "Chicken Soup, Chicken Curry" || first.GetNextPageToken() == "" {
+		t.Fatalf("got %q with token %q, want the first two recipes and a next page", got, first.GetNextPageToken())
+	}
+	second, err := client.SearchRecipes(ctx, &SearchRecipesRequest{Query: "onion", PageSize: 2, PageToken: first.GetNextPageToken()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(second.GetRecipes()); got != "Fried Rice" || second.GetNextPageToken() != "" {
+		t.Fatalf("got %q with token %q, want the last recipe and no next page", got, second.GetNextPageToken())
+	}
+
+	_, err = client.SearchRecipes(ctx, &SearchRecipesRequest{Query: "onion", PageToken: "not a token"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("got %v for a bad page token, want InvalidArgument", err)
+	}
+}
+
+func TestGetRecipe(t *testing.T) {
+	client := newTestClient(t, &memoryStore{recipes: testRecipes}, nil)
+	ctx := context.Background()
+
+	response, err := client.GetRecipe(ctx, &GetRecipeRequest{Name: "Fried Rice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recipe := response.GetRecipe(); recipe.GetName() != "Fried Rice" || len(recipe.GetIngredients()) != 3 || response.GetDegraded() {
+		t.Fatalf("got %v, want Fried Rice with its three ingredients", response)
+	}
+	if _, err := client.GetRecipe(ctx, &GetRecipeRequest{Name: "Pancakes"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("got %v for an unknown recipe, want NotFound", err)
+	}
+}
+
+func TestMatchIngredientsReportsDegradedAnswers(t *testing.T) {
+	client := newTestClient(t, &memoryStore{recipes: testRecipes, err: recipes.ErrDegraded}, nil)
+	response, err := client.MatchIngredients(context.Background(), &MatchIngredientsRequest{Ingredients: []string{"chicken", "rice"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(response.GetRecipes()); got != "Chicken Curry" || !response.GetDegraded() {
+		t.Fatalf("got %q, degraded %v, want Chicken Curry marked as degraded", got, response.GetDegraded())
+	}
+}
+
+func TestUnreachableDatabaseIsUnavailable(t *testing.T) {
+	client := newTestClient(t, &memoryStore{recipes: testRecipes, err: recipes.ErrCircuitOpen}, nil)
+	_, err := client.SearchRecipes(context.Background(), &SearchRecipesRequest{Query: "rice"})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("got %v, want Unavailable", err)
+	}
+}
+
+func TestFavorites(t *testing.T) {
+	client := newTestClient(t, &memoryStore{recipes: testRecipes}, memoryFavorites{})
+	ctx := withToken("alice-token")
+
+	if _, err := client.AddFavorite(ctx, &FavoriteRequest{RecipeName: "Fried Rice"}); err != nil {
+		t.Fatal(err)
+	}
+	response, err := client.AddFavorite(ctx, &FavoriteRequest{RecipeName: "Chicken Soup"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(response.GetRecipes()); got != "Fried Rice, Chicken Soup" {
+		t.Fatalf("got %q, want both favorites", got)
+	}
+	if _, err := client.AddFavorite(ctx, &FavoriteRequest{RecipeName: "Pancakes"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("got %v for an unknown recipe, want NotFound", err)
+	}
+
+	if _, err := client.RemoveFavorite(ctx, &FavoriteRequest{RecipeName: "Fried Rice"}); err != nil {
+		t.Fatal(err)
+	}
+	response, err = client.ListFavorites(ctx, &ListFavoritesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(response.GetRecipes()); got != "Chicken Soup" {
+		t.Fatalf("got %q, want the remaining favorite", got)
+	}
+
+	// Another user sees only their own favorites
+	response, err = client.ListFavorites(withToken("bob-token"), &ListFavoritesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(response.GetRecipes()); got != "" {
+		t.Fatalf("got %q for another user, want no favorites", got)
+	}
+}
+
+func TestFavoritesNeedAnAccessToken(t *testing.T) {
+	client := newTestClient(t, &memoryStore{recipes: testRecipes}, memoryFavorites{})
+	calls := map[string]func() error{
+		"no token": func() error {
+			_, err := client.ListFavorites(context.Background(), &ListFavoritesRequest{})
+			return err
+		},
+		"unknown token": func() error {
+			_, err := client.AddFavorite(withToken("stolen-token"), &FavoriteRequest{RecipeName: "Fried Rice"})
+			return err
+		},
+		"not a bearer token": func() error {
+			ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "alice-token")
+			_, err := client.RemoveFavorite(ctx, &FavoriteRequest{RecipeName: "Fried Rice"})
+			return err
+		},
+	}
+	for name, call := range calls {
+		if err := call(); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s: got %v, want Unauthenticated", name, err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package recipes
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// How many lookups ResilientStore remembers, and for how long, unless set with WithCache
+const (
+	defaultCacheSize = 1000
+	defaultCacheTTL  = time.Hour
+)
+
+// Lookup results kept for at most ttl, evicting the least recently used once
+// there are more than size of them
+type resultCache struct {
+	size int
+	ttl  time.Duration
+
+	mutex sync.Mutex
+	// Most recently used first
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type cachedResult struct {
+	key      string
+	recipes  []Recipe
+	storedAt time.Time
+}
+
+func newResultCache(size int, ttl time.Duration) *resultCache {
+	return &resultCache{size: size, ttl: ttl, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (cache *resultCache) put(key string, recipes []Recipe) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if element, ok := cache.entries[key]; ok {
+		element.Value = cachedResult{key: key, recipes: recipes, storedAt: time.Now()}
+		cache.order.MoveToFront(element)
+		return
+	}
+	cache.entries[key] = cache.order.PushFront(cachedResult{key: key, recipes: recipes, storedAt: time.Now()})
+	for cache.order.Len() > cache.size {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(cachedResult).key)
+	}
+}
+
+func (cache *resultCache) get(key string) ([]Recipe, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	element, ok := cache.entries[key]
+	if !ok {
+		return nil, false
+	}
+	result := element.Value.(cachedResult)
+	if time.Since(result.storedAt) > cache.ttl {
+		cache.order.Remove(element)
+		delete(cache.entries, key)
+		return nil, false
+	}
+	cache.order.MoveToFront(element)
+	return result.recipes, true
+}
@@ -0,0 +1,224 @@
+package recipes
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/mongodb-developer/alexa-golang-example/skill"
+)
+
+// Session attributes holding the recipe being cooked, the step being read
+// and the glossary terms used in that step
+const (
+	cookingRecipeAttribute = "cookingRecipe"
+	cookingStepAttribute   = "cookingStep"
+	stepTermsAttribute     = "stepTerms"
+)
+
+// Reports whether the user is cooking a recipe, so that "next" and "what's
+// that?" refer to its steps
+func isCooking(input *skill.HandlerInput) bool {
+	_, ok := input.Attributes.SessionAttributes()[cookingRecipeAttribute].(string)
+	return ok
+}
+
+// Handles the intents of cooking mode, but only while a recipe is being cooked
+func cookingHandler(handle skill.HandlerFunc, intentNames ...string) skill.Handler {
+	intents := skill.IntentHandler(handle, intentNames...)
+	return skill.NewHandler(func(input *skill.HandlerInput) bool {
+		return isCooking(input) && intents.CanHandle(input)
+	}, handle)
+}
+
+// Starts reading the steps of a recipe, one at a time
+func (connection Connection) startCooking(input *skill.HandlerInput) error {
+	recipeName := input.Request.Body.SlotValue("recipe")
+	if recipeName == "" {
+		return errors.New("Recipe name is not present in the request")
+	}
+	recipe, err := connection.store.FindByName(input.Context, recipeName)
+	if err != nil && !errors.Is(err, ErrDegraded) {
+		return err
+	}
+	if len(recipe.Steps) == 0 {
+		return Answer(input, "Cooking", input.Messages.Format("noSteps", recipe.Name), nil)
+	}
+	attributes := input.Attributes.SessionAttributes()
+	attributes[cookingRecipeAttribute] = recipe.Name
+	prefix := ""
+	if err != nil {
+		prefix = input.Messages.Format("degraded")
+	}
+	return connection.readStep(input, recipe, 0, prefix)
+}
+
+func (connection Connection) nextStep(input *skill.HandlerInput) error {
+	return connection.moveStep(input, 1)
+}
+
+func (connection Connection) previousStep(input *skill.HandlerInput) error {
+	return connection.moveStep(input, -1)
+}
+
+func (connection Connection) repeatStep(input *skill.HandlerInput) error {
+	return connection.moveStep(input, 0)
+}
+
+// Reads the step by steps from the current one, finishing cooking mode after
+// the last and taking the recipe's ingredients out of the pantry
+func (connection Connection) moveStep(input *skill.HandlerInput, by int) error {
+	attributes := input.Attributes.SessionAttributes()
+	recipe, err := connection.store.FindByName(input.Context, attributes[cookingRecipeAttribute].(string))
+	if err != nil && !errors.Is(err, ErrDegraded) {
+		return err
+	}
+	step := intAttribute(attributes[cookingStepAttribute]) + by
+	if step < 0 {
+		step = 0
+	}
+	if step >= len(recipe.Steps) {
+		delete(attributes, cookingRecipeAttribute)
+		delete(attributes, cookingStepAttribute)
+		delete(attributes, stepTermsAttribute)
+		return Answer(input, "Cooking", input.Messages.Format("finished", recipe.Name)+connection.finishedCooking(input, recipe), nil)
+	}
+	return connection.readStep(input, recipe, step, "")
+}
+
+// Speaks a step, noting the glossary terms it uses so the user can ask about
+// them and listing their explanations on the card, or speaking them too for
+// users who want detailed answers
+func (connection Connection) readStep(input *skill.HandlerInput, recipe Recipe, step int, prefix string) error {
+	attributes := input.Attributes.SessionAttributes()
+	attributes[cookingStepAttribute] = step
+	text := input.Messages.Format("step", step+1, len(recipe.Steps), recipe.Steps[step])
+	for _, doneness := range DonenessIn(recipe.Steps[step]) {
+		text += input.Messages.Format("doneness", doneness.Food, temperature(input, doneness.Temperature))
+	}
+	card := text
+	reprompt := input.Messages.Format("cookingPrompt")
+
+	var names []string
+	for _, term := range connection.termsIn(input, recipe.Steps[step]) {
+		names = append(names, term.Name)
+		card += "\n\n" + term.Name + ": " + term.Card
+		if input.Preferences.Verbosity == skill.Detailed {
+			text += " " + term.Speech
+		}
+	}
+	if len(names) > 0 {
+		attributes[stepTermsAttribute] = names
+		reprompt = input.Messages.Format("termPrompt", JoinWithAnd(names))
+	} else {
+		delete(attributes, stepTermsAttribute)
+	}
+	input.Response.Speak(prefix+text).SimpleCard(recipe.Name, card).Reprompt(reprompt)
+	return nil
+}
+
+// The glossary terms used in a step, or none when the glossary can't be read
+func (connection Connection) termsIn(input *skill.HandlerInput, step string) []Term {
+	if connection.glossary == nil {
+		return nil
+	}
+	glossary, err := connection.glossary.Glossary(input.Context)
+	if err != nil {
+		log.Printf("unable to read the glossary: %v", err)
+		return nil
+	}
+	return glossary.TermsIn(step)
+}
+
+// Explains a term asked about by name, as in "what does fold mean"
+func (connection Connection) explainTerm(input *skill.HandlerInput) error {
+	slot := input.Request.Body.Intent.Slots["term"]
+	word := slot.ResolvedValue()
+	if word == "" {
+		return errors.New("Term is not present in the request")
+	}
+	if connection.glossary == nil {
+		return Answer(input, "Glossary", input.Messages.Format("glossaryUnavailable"), nil)
+	}
+	glossary, err := connection.glossary.Glossary(input.Context)
+	if err != nil {
+		return err
+	}
+	term, ok := glossary.Find(word)
+	if !ok {
+		term, ok = glossary.Find(slot.Value)
+	}
+	if !ok {
+		return Answer(input, "Glossary", input.Messages.Format("unknownTerm", word), nil)
+	}
+	connection.speakTerms(input, []Term{term})
+	return nil
+}
+
+// Explains the terms used in the step being read, as in "what's that?"
+func (connection Connection) explainStepTerms(input *skill.HandlerInput) error {
+	names := stringsAttribute(input.Attributes.SessionAttributes()[stepTermsAttribute])
+	if !isCooking(input) || len(names) == 0 || connection.glossary == nil {
+		return Answer(input, "Glossary", input.Messages.Format("noStepTerms"), nil)
+	}
+	glossary, err := connection.glossary.Glossary(input.Context)
+	if err != nil {
+		return err
+	}
+	var terms []Term
+	for _, name := range names {
+		if term, ok := glossary.Find(name); ok {
+			terms = append(terms, term)
+		}
+	}
+	connection.speakTerms(input, terms)
+	return nil
+}
+
+// Speaks the short explanations of terms and shows the long ones, keeping
+// the session open while cooking
+func (connection Connection) speakTerms(input *skill.HandlerInput, terms []Term) {
+	var speech, card []string
+	for _, term := range terms {
+		speech = append(speech, term.Speech)
+		card = append(card, term.Name+": "+term.Card)
+	}
+	title := "Glossary"
+	if len(terms) == 1 {
+		title = capitalize(terms[0].Name)
+	}
+	input.Response.Speak(strings.Join(speech, " ")).SimpleCard(title, strings.Join(card, "\n\n"))
+	if isCooking(input) {
+		input.Response.Reprompt(input.Messages.Format("cookingPrompt"))
+	}
+}
+
+// Reads a number from a session attribute, which is a float64 once it has
+// made a round trip through Alexa
+func intAttribute(value interface{}) int {
+	switch number := value.(type) {
+	case int:
+		return number
+	case float64:
+		return int(number)
+	}
+	return 0
+}
+
+// Reads a list of strings from a session attribute, which is a []interface{}
+// once it has made a round trip through Alexa
+func stringsAttribute(value interface{}) []string {
+	switch list := value.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		var values []string
+		for _, item := range list {
+			if text, ok := item.(string); ok {
+				values = append(values, text)
+			}
+		}
+		return values
+	}
+	return nil
+}
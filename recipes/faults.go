@@ -0,0 +1,261 @@
+package recipes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+// The kinds of fault a FaultyStore can inject
+const (
+	// Delays the lookup by Latency, then makes it
+	FaultLatency = "latency"
+	// Waits for Latency, or until the context is done, and fails with context.DeadlineExceeded
+	FaultTimeout = "timeout"
+	// Fails with the driver error named by Error
+	FaultError = "error"
+	// Makes the lookup, then keeps only the Keep fraction of the recipes found,
+	// or of the ingredients of a single recipe
+	FaultPartial = "partial"
+)
+
+// Errors shaped like those the driver returns, by the names used in schedules
+var faultErrors = map[string]func() error{
+	"network": func() error {
+		return mongo.CommandError{Message: "connection(injected) incomplete read of message header", Labels: []string{"NetworkError"}}
+	},
+	"server-selection": func() error {
+		return fmt.Errorf("server selection error: %w (injected)", topology.ErrServerSelectionTimeout)
+	},
+	"not-primary": func() error {
+		return mongo.CommandError{Code: 10107, Name: "NotWritablePrimary", Message: "not primary (injected)"}
+	},
+	"shutdown": func() error {
+		return mongo.CommandError{Code: 11600, Name: "InterruptedAtShutdown", Message: "interrupted at shutdown (injected)"}
+	},
+	"auth": func() error {
+		return mongo.CommandError{Code: 18, Name: "AuthenticationFailed", Message: "authentication failed (injected)"}
+	},
+	"unauthorized": func() error {
+		return mongo.CommandError{Code: 13, Name: "Unauthorized", Message: "not authorized (injected)"}
+	},
+	"no-documents": func() error {
+		return mongo.ErrNoDocuments
+	},
+}
+
+// A fault and the lookups it applies to. Calls are numbered from zero in the
+// order the store receives them.
+type FaultRule struct {
+	// The lookups affected, such as "FindByName", or all of them when empty
+	Operations []string `json:"operations,omitempty"`
+	// The first call affected
+	From int `json:"from,omitempty"`
+	// The call after the last one affected, or no limit when zero
+	Until int `json:"until,omitempty"`
+	// How likely an affected call is to fail, always when zero
+	Probability float64 `json:"probability,omitempty"`
+
+	Kind    string        `json:"kind"`
+	Latency time.Duration `json:"-"`
+	Error   string        `json:"error,omitempty"`
+	Keep    float64       `json:"keep,omitempty"`
+}
+
+// Reads latency as a duration string such as "250ms"
+func (rule *FaultRule) UnmarshalJSON(data []byte) error {
+	type plain FaultRule
+	var decoded struct {
+		*plain
+		Latency string `json:"latency,omitempty"`
+	}
+	decoded.plain = (*plain)(rule)
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	if decoded.Latency != "" {
+		latency, err := time.ParseDuration(decoded.Latency)
+		if err != nil {
+			return fmt.Errorf("latency: %v", err)
+		}
+		rule.Latency = latency
+	}
+	return nil
+}
+
+// The faults to inject. The first rule that matches a call applies.
+type FaultSchedule struct {
+	// Seeds the probabilities, so a run can be repeated
+	Seed  int64       `json:"seed,omitempty"`
+	Rules []FaultRule `json:"rules"`
+}
+
+// Reads a schedule from a JSON file, for example
+//
+//	{"rules": [
+//		{"operations": ["FindByName"], "from": 3, "until": 6, "kind": "error", "error": "network"},
+//		{"probability": 0.1, "kind": "latency", "latency": "2s"}
+//	]}
+func LoadFaultSchedule(path string) (FaultSchedule, error) {
+	var schedule FaultSchedule
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return schedule, err
+	}
+	if err := json.Unmarshal(data, &schedule); err != nil {
+		return schedule, fmt.Errorf("%s: %v", path, err)
+	}
+	for i, rule := range schedule.Rules {
+		switch rule.Kind {
+		case FaultLatency, FaultTimeout, FaultPartial:
+		case FaultError:
+			if _, ok := faultErrors[rule.Error]; !ok {
+				return schedule, fmt.Errorf("%s: rule %d: unknown error %q", path, i, rule.Error)
+			}
+		default:
+			return schedule, fmt.Errorf("%s: rule %d: unknown kind %q", path, i, rule.Kind)
+		}
+	}
+	return schedule, nil
+}
+
+// A RecipeStore decorator that injects latency, timeouts, driver errors and
+// partial results on a schedule, to exercise error handling without breaking
+// a real database
+type FaultyStore struct {
+	store    RecipeStore
+	schedule FaultSchedule
+
+	mutex  sync.Mutex
+	calls  int
+	random *rand.Rand
+}
+
+func NewFaultyStore(store RecipeStore, schedule FaultSchedule) *FaultyStore {
+	return &FaultyStore{store: store, schedule: schedule, random: rand.New(rand.NewSource(schedule.Seed))}
+}
+
+func (store *FaultyStore) FindByName(ctx context.Context, name string) (Recipe, error) {
+	rule, err := store.before(ctx, "FindByName")
+	if err != nil {
+		return Recipe{}, err
+	}
+	recipe, err := store.store.FindByName(ctx, name)
+	if rule != nil && rule.Kind == FaultPartial {
+		recipe.Ingredients = recipe.Ingredients[:kept(len(recipe.Ingredients), rule.Keep)]
+	}
+	return recipe, err
+}
+
+func (store *FaultyStore) FindByNames(ctx context.Context, names []string) ([]Recipe, error) {
+	return store.find(ctx, "FindByNames", func() ([]Recipe, error) {
+		return store.store.FindByNames(ctx, names)
+	})
+}
+
+func (store *FaultyStore) FindByIngredients(ctx context.Context, ingredients []string) ([]Recipe, error) {
+	return store.find(ctx, "FindByIngredients", func() ([]Recipe, error) {
+		return store.store.FindByIngredients(ctx, ingredients)
+	})
+}
+
+func (store *FaultyStore) Query(ctx context.Context, query RecipeQuery) ([]Recipe, error) {
+	return store.find(ctx, "Query", func() ([]Recipe, error) {
+		return store.store.Query(ctx, query)
+	})
+}
+
+func (store *FaultyStore) find(ctx context.Context, operation string, lookup func() ([]Recipe, error)) ([]Recipe, error) {
+	rule, err := store.before(ctx, operation)
+	if err != nil {
+		return nil, err
+	}
+	recipes, err := lookup()
+	if rule != nil && rule.Kind == FaultPartial {
+		recipes = recipes[:kept(len(recipes), rule.Keep)]
+	}
+	return recipes, err
+}
+
+// Finds the rule for the next call and injects any fault that happens before
+// the lookup. Returns the rule so partial results can be applied after it.
+func (store *FaultyStore) before(ctx context.Context, operation string) (*FaultRule, error) {
+	rule := store.next(operation)
+	if rule == nil {
+		return nil, nil
+	}
+	log.Printf("injecting %s fault into %s", rule.Kind, operation)
+	switch rule.Kind {
+	case FaultLatency:
+		if err := sleep(ctx, rule.Latency); err != nil {
+			return nil, err
+		}
+	case FaultTimeout:
+		if err := sleep(ctx, rule.Latency); err != nil {
+			return nil, err
+		}
+		return nil, context.DeadlineExceeded
+	case FaultError:
+		return nil, faultErrors[rule.Error]()
+	}
+	return rule, nil
+}
+
+func (store *FaultyStore) next(operation string) *FaultRule {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	call := store.calls
+	store.calls++
+	for i := range store.schedule.Rules {
+		rule := &store.schedule.Rules[i]
+		if call < rule.From || (rule.Until > 0 && call >= rule.Until) || !includes(rule.Operations, operation) {
+			continue
+		}
+		if rule.Probability > 0 && store.random.Float64() >= rule.Probability {
+			continue
+		}
+		return rule
+	}
+	return nil
+}
+
+// Reports whether operation is one of operations, or operations is empty
+func includes(operations []string, operation string) bool {
+	for _, included := range operations {
+		if included == operation {
+			return true
+		}
+	}
+	return len(operations) == 0
+}
+
+// Waits for duration, or until ctx is done
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// The number of n values to keep when keeping fraction of them
+func kept(n int, fraction float64) int {
+	if fraction >= 1 {
+		return n
+	}
+	if fraction <= 0 {
+		return 0
+	}
+	return int(float64(n) * fraction)
+}
@@ -0,0 +1,238 @@
+package recipes
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Makes count FindByNames calls and reports which of them failed
+func failedCalls(store *FaultyStore, count int) []bool {
+	failed := make([]bool, count)
+	for i := range failed {
+		_, err := store.FindByNames(context.Background(), []string{"Pancakes"})
+		failed[i] = err != nil
+	}
+	return failed
+}
+
+func TestFaultyStoreMatchesCallsFromUntil(t *testing.T) {
+	schedule := FaultSchedule{Rules: []FaultRule{{From: 2, Until: 4, Kind: FaultError, Error: "network"}}}
+	store := NewFaultyStore(&stubStore{recipes: []Recipe{{Name: "Pancakes"}}}, schedule)
+	want := []bool{false, false, true, true, false, false}
+	for i, failed := range failedCalls(store, len(want)) {
+		if failed != want[i] {
+			t.Errorf("call %d failed = %v, want %v", i, failed, want[i])
+		}
+	}
+}
+
+func TestFaultyStoreMatchesOperations(t *testing.T) {
+	schedule := FaultSchedule{Rules: []FaultRule{{Operations: []string{"FindByName"}, Kind: FaultError, Error: "auth"}}}
+	store := NewFaultyStore(&stubStore{recipes: []Recipe{{Name: "Pancakes"}}}, schedule)
+	if _, err := store.Query(context.Background(), RecipeQuery{}); err != nil {
+		t.Errorf("Query failed with a fault meant for FindByName: %v", err)
+	}
+	_, err := store.FindByName(context.Background(), "Pancakes")
+	if commandErr, ok := err.(mongo.CommandError); !ok || commandErr.Code != 18 {
+		t.Errorf("FindByName err = %v, want the injected authentication error", err)
+	}
+}
+
+func TestFaultyStoreAppliesTheFirstMatchingRule(t *testing.T) {
+	schedule := FaultSchedule{Rules: []FaultRule{
+		{Until: 1, Kind: FaultError, Error: "no-documents"},
+		{Kind: FaultError, Error: "network"},
+	}}
+	store := NewFaultyStore(&stubStore{}, schedule)
+	if _, err := store.FindByNames(context.Background(), nil); err != mongo.ErrNoDocuments {
+		t.Errorf("first call err = %v, want %v", err, mongo.ErrNoDocuments)
+	}
+	if _, err := store.FindByNames(context.Background(), nil); !IsRetryable(err) {
+		t.Errorf("second call err = %v, want a retryable network error", err)
+	}
+}
+
+func TestFaultyStoreProbabilityIsSeeded(t *testing.T) {
+	schedule := FaultSchedule{Seed: 42, Rules: []FaultRule{{Probability: 0.5, Kind: FaultError, Error: "shutdown"}}}
+	first := failedCalls(NewFaultyStore(&stubStore{}, schedule), 200)
+	second := failedCalls(NewFaultyStore(&stubStore{}, schedule), 200)
+	failures := 0
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("call %d failed = %v then %v with the same seed", i, first[i], second[i])
+		}
+		if first[i] {
+			failures++
+		}
+	}
+	if failures < 70 || failures > 130 {
+		t.Errorf("%d of 200 calls failed with probability 0.5", failures)
+	}
+}
+
+func TestFaultyStoreErrorsAreShapedLikeTheDriver(t *testing.T) {
+	retryable := map[string]bool{
+		"network":          true,
+		"server-selection": true,
+		"not-primary":      true,
+		"shutdown":         true,
+		"auth":             false,
+		"unauthorized":     false,
+		"no-documents":     false,
+	}
+	for name := range faultErrors {
+		want, ok := retryable[name]
+		if !ok {
+			t.Errorf("no expectation for the %q fault", name)
+			continue
+		}
+		store := NewFaultyStore(&stubStore{}, FaultSchedule{Rules: []FaultRule{{Kind: FaultError, Error: name}}})
+		_, err := store.FindByIngredients(context.Background(), []string{"egg"})
+		if err == nil {
+			t.Errorf("the %q fault didn't fail the lookup", name)
+		} else if IsRetryable(err) != want {
+			t.Errorf("IsRetryable(%q fault) = %v, want %v", name, !want, want)
+		}
+	}
+}
+
+func TestFaultyStoreTimesOut(t *testing.T) {
+	database := &stubStore{recipes: []Recipe{{Name: "Pancakes"}}}
+	store := NewFaultyStore(database, FaultSchedule{Rules: []FaultRule{{Kind: FaultTimeout, Latency: time.Hour}}})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := store.FindByName(ctx, "Pancakes"); err != context.DeadlineExceeded {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	store = NewFaultyStore(database, FaultSchedule{Rules: []FaultRule{{Kind: FaultTimeout}}})
+	if _, err := store.Query(context.Background(), RecipeQuery{}); err != context.DeadlineExceeded {
+		t.Errorf("err = %v, want %v without latency", err, context.DeadlineExceeded)
+	}
+	if database.calls != 0 {
+		t.Errorf("%d timed out lookups reached the database", database.calls)
+	}
+}
+
+func TestFaultyStoreDelays(t *testing.T) {
+	database := &stubStore{recipes: []Recipe{{Name: "Pancakes"}}}
+	store := NewFaultyStore(database, FaultSchedule{Rules: []FaultRule{{Kind: FaultLatency, Latency: 20 * time.Millisecond}}})
+	start := time.Now()
+	recipes, err := store.FindByNames(context.Background(), []string{"Pancakes"})
+	if err != nil || len(recipes) != 1 {
+		t.Fatalf("FindByNames = %v, %v", recipes, err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("the lookup took %v, want at least 20ms", elapsed)
+	}
+}
+
+func TestFaultyStoreReturnsPartialResults(t *testing.T) {
+	database := &stubStore{recipes: []Recipe{
+		{Name: "Pancakes", Ingredients: []string{"flour", "milk", "egg", "butter", "sugar"}},
+		{Name: "Omelette"},
+		{Name: "Porridge"},
+		{Name: "Crumpets"},
+	}}
+	store := NewFaultyStore(database, FaultSchedule{Rules: []FaultRule{{Kind: FaultPartial, Keep: 0.5}}})
+
+	recipes, err := store.FindByIngredients(context.Background(), []string{"egg"})
+	if err != nil || len(recipes) != 2 {
+		t.Errorf("FindByIngredients kept %d recipes, want 2 (err %v)", len(recipes), err)
+	}
+	recipe, err := store.FindByName(context.Background(), "Pancakes")
+	if err != nil || strings.Join(recipe.Ingredients, ",") != "flour,milk" {
+		t.Errorf("FindByName kept ingredients %v, want [flour milk] (err %v)", recipe.Ingredients, err)
+	}
+	if len(database.recipes[0].Ingredients) != 5 {
+		t.Errorf("partial results changed the stored recipe to %v", database.recipes[0].Ingredients)
+	}
+}
+
+func TestKept(t *testing.T) {
+	tests := []struct {
+		n        int
+		fraction float64
+		want     int
+	}{
+		{10, 0.25, 2},
+		{10, 0.5, 5},
+		{3, 0.5, 1},
+		{3, 1, 3},
+		{3, 1.5, 3},
+		{3, 0, 0},
+		{3, -1, 0},
+		{0, 0.5, 0},
+	}
+	for _, test := range tests {
+		if got := kept(test.n, test.fraction); got != test.want {
+			t.Errorf("kept(%d, %v) = %d, want %d", test.n, test.fraction, got, test.want)
+		}
+	}
+}
+
+func TestFaultRuleReadsLatency(t *testing.T) {
+	var rule FaultRule
+	data := `{"operations": ["Query"], "from": 1, "until": 3, "probability": 0.25, "kind": "latency", "latency": "250ms"}`
+	if err := json.Unmarshal([]byte(data), &rule); err != nil {
+		t.Fatal(err)
+	}
+	if rule.Latency != 250*time.Millisecond || rule.Kind != FaultLatency || rule.From != 1 || rule.Until != 3 ||
+		rule.Probability != 0.25 || len(rule.Operations) != 1 || rule.Operations[0] != "Query" {
+		t.Errorf("rule = %+v", rule)
+	}
+
+	if err := json.Unmarshal([]byte(`{"kind": "latency", "latency": "soon"}`), &rule); err == nil || !strings.Contains(err.Error(), "latency") {
+		t.Errorf("err = %v, want a latency error", err)
+	}
+}
+
+func TestLoadFaultSchedule(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faults")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	schedule, err := LoadFaultSchedule(write("valid.json", `{"seed": 7, "rules": [
+		{"operations": ["FindByName"], "from": 3, "until": 6, "kind": "error", "error": "network"},
+		{"probability": 0.1, "kind": "latency", "latency": "2s"},
+		{"kind": "partial", "keep": 0.5}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schedule.Seed != 7 || len(schedule.Rules) != 3 || schedule.Rules[1].Latency != 2*time.Second || schedule.Rules[2].Keep != 0.5 {
+		t.Errorf("schedule = %+v", schedule)
+	}
+
+	invalid := map[string]string{
+		"kind.json":    `{"rules": [{"kind": "explode"}]}`,
+		"error.json":   `{"rules": [{"kind": "error", "error": "gremlins"}]}`,
+		"latency.json": `{"rules": [{"kind": "latency", "latency": "soon"}]}`,
+		"syntax.json":  `{"rules": [`,
+	}
+	for name, contents := range invalid {
+		if _, err := LoadFaultSchedule(write(name, contents)); err == nil {
+			t.Errorf("%s loaded without an error", name)
+		}
+	}
+	if _, err := LoadFaultSchedule(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("a missing schedule loaded without an error")
+	}
+}
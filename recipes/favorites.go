@@ -0,0 +1,52 @@
+package recipes
+
+import (
+	"context"
+
+	"github.com/mongodb-developer/alexa-golang-example/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Describes the recipes a user has marked as favorites, by recipe name
+type FavoriteStore interface {
+	Favorites(ctx context.Context, userID string) ([]string, error)
+	AddFavorite(ctx context.Context, userID string, recipeName string) error
+	RemoveFavorite(ctx context.Context, userID string, recipeName string) error
+}
+
+// A FavoriteStore keeping one document per user, {_id: userId, recipes: [...]}
+type MongoFavoriteStore struct {
+	collection *mongodb.Collection
+}
+
+func NewMongoFavoriteStore(collection *mongodb.Collection) *MongoFavoriteStore {
+	return &MongoFavoriteStore{collection: collection}
+}
+
+func (store *MongoFavoriteStore) Favorites(ctx context.Context, userID string) ([]string, error) {
+	var document struct {
+		Recipes []string `bson:"recipes"`
+	}
+	err := store.collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&document)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return document.Recipes, err
+}
+
+func (store *MongoFavoriteStore) AddFavorite(ctx context.Context, userID string, recipeName string) error {
+	_, err := store.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": userID},
+		bson.M{"$addToSet": bson.M{"recipes": recipeName}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (store *MongoFavoriteStore) RemoveFavorite(ctx context.Context, userID string, recipeName string) error {
+	_, err := store.collection.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$pull": bson.M{"recipes": recipeName}})
+	return err
+}
@@ -0,0 +1,250 @@
+package recipes
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mongodb-developer/alexa-golang-example/skill"
+)
+
+// The internal temperature a food is safely cooked at
+type SafeTemperature struct {
+	Food string
+	// Other ways the food is named in questions and recipe steps
+	Aliases    []string
+	Fahrenheit int
+	Celsius    int
+	// Minutes to rest after cooking, which is part of what makes it safe
+	RestMinutes int
+	// Whether recipe steps cooking the food mention its temperature
+	Meat bool
+}
+
+// A span of time such as 3 to 4 days. A zero Max means the food shouldn't be
+// stored that way.
+type StorageSpan struct {
+	Min  int
+	Max  int
+	Unit string
+}
+
+// How long a food keeps in the fridge and the freezer
+type StorageTime struct {
+	Food    string
+	Aliases []string
+	Fridge  StorageSpan
+	Freezer StorageSpan
+}
+
+// Safe minimum internal temperatures, from the USDA chart at
+// https://www.foodsafety.gov/food-safety-charts/safe-minimum-internal-temperatures.
+// More specific foods come first where their names overlap.
+var SafeTemperatures = []SafeTemperature{
+	{Food: "ground poultry", Aliases: []string{"ground chicken", "ground turkey", "minced chicken", "minced turkey"}, Fahrenheit: 165, Celsius: 74, Meat: true},
+	{Food: "ground meat", Aliases: []string{"ground beef", "ground pork", "ground lamb", "ground veal", "minced beef", "minced pork", "mince", "hamburger", "hamburgers", "burger", "burgers", "meatballs", "meatloaf", "sausage", "sausages"}, Fahrenheit: 160, Celsius: 71, Meat: true},
+	{Food: "poultry", Aliases: []string{"chicken", "chicken breast", "chicken breasts", "chicken thighs", "chicken wings", "turkey", "duck", "goose"}, Fahrenheit: 165, Celsius: 74, Meat: true},
+	{Food: "beef", Aliases: []string{"steak", "steaks", "roast beef", "veal", "lamb", "lamb chops", "brisket"}, Fahrenheit: 145, Celsius: 63, RestMinutes: 3, Meat: true},
+	{Food: "pork", Aliases: []string{"pork chops", "pork loin", "pork tenderloin", "pork shoulder", "fresh ham"}, Fahrenheit: 145, Celsius: 63, RestMinutes: 3, Meat: true},
+	{Food: "fish", Aliases: []string{"salmon", "tuna", "cod", "halibut", "trout", "tilapia", "seafood"}, Fahrenheit: 145, Celsius: 63, Meat: true},
+	{Food: "egg dishes", Aliases: []string{"eggs", "quiche", "frittata", "custard"}, Fahrenheit: 160, Celsius: 71},
+	{Food: "leftovers", Aliases: []string{"casserole", "casseroles", "reheated food"}, Fahrenheit: 165, Celsius: 74},
+}
+
+// Cold storage times, from the USDA chart at
+// https://www.foodsafety.gov/food-safety-charts/cold-food-storage-charts
+var StorageTimes = []StorageTime{
+	{Food: "cooked rice", Aliases: []string{"rice", "leftover rice"}, Fridge: StorageSpan{3, 4, "days"}, Freezer: StorageSpan{1, 2, "months"}},
+	{Food: "cooked pasta", Aliases: []string{"pasta", "noodles", "leftover pasta"}, Fridge: StorageSpan{3, 5, "days"}, Freezer: StorageSpan{1, 2, "months"}},
+	{Food: "raw chicken", Aliases: []string{"raw poultry", "raw turkey", "chicken", "turkey"}, Fridge: StorageSpan{1, 2, "days"}, Freezer: StorageSpan{9, 12, "months"}},
+	{Food: "cooked chicken", Aliases: []string{"cooked poultry", "cooked turkey", "leftover chicken", "rotisserie chicken"}, Fridge: StorageSpan{3, 4, "days"}, Freezer: StorageSpan{2, 6, "months"}},
+	{Food: "raw ground meat", Aliases: []string{"ground beef", "ground pork", "ground turkey", "mince", "hamburger"}, Fridge: StorageSpan{1, 2, "days"}, Freezer: StorageSpan{3, 4, "months"}},
+	{Food: "raw steaks and chops", Aliases: []string{"steak", "steaks", "pork chops", "lamb chops", "roast", "raw beef", "raw pork"}, Fridge: StorageSpan{3, 5, "days"}, Freezer: StorageSpan{4, 12, "months"}},
+	{Food: "cooked meat", Aliases: []string{"leftover meat", "cooked beef", "cooked pork"}, Fridge: StorageSpan{3, 4, "days"}, Freezer: StorageSpan{2, 3, "months"}},
+	{Food: "raw fish", Aliases: []string{"fish", "fresh fish", "salmon", "shellfish"}, Fridge: StorageSpan{1, 2, "days"}, Freezer: StorageSpan{3, 8, "months"}},
+	{Food: "cooked fish", Aliases: []string{"leftover fish", "cooked salmon"}, Fridge: StorageSpan{3, 4, "days"}, Freezer: StorageSpan{4, 6, "months"}},
+	{Food: "raw eggs", Aliases: []string{"eggs", "eggs in the shell"}, Fridge: StorageSpan{3, 5, "weeks"}},
+	{Food: "hard-boiled eggs", Aliases: []string{"hard boiled eggs", "boiled eggs"}, Fridge: StorageSpan{1, 1, "week"}},
+	{Food: "soups and stews", Aliases: []string{"soup", "stew", "chili", "curry"}, Fridge: StorageSpan{3, 4, "days"}, Freezer: StorageSpan{2, 3, "months"}},
+	{Food: "pizza", Aliases: []string{"leftover pizza"}, Fridge: StorageSpan{3, 4, "days"}, Freezer: StorageSpan{1, 2, "months"}},
+	{Food: "leftovers", Aliases: []string{"cooked leftovers", "casserole"}, Fridge: StorageSpan{3, 4, "days"}, Freezer: StorageSpan{2, 3, "months"}},
+}
+
+// Words in a recipe step that mean something is being cooked, rather than
+// thawed, marinated or sliced
+var cookingVerbs = []string{
+	"cook", "cooking", "roast", "roasting", "grill", "grilling", "bake", "baking",
+	"fry", "frying", "sear", "searing", "brown", "browning", "broil", "broiling",
+	"saute", "sauté", "sauteing", "simmer", "simmering", "barbecue", "smoke", "poach", "braise",
+}
+
+// Words that, following a meat, name something made from it that isn't
+// cooked to a safe temperature in the step, as in "simmer the chicken stock"
+var meatProducts = []string{"stock", "stocks", "broth", "broths", "bouillon"}
+
+// Finds the safe temperature for a food asked about by name
+func FindSafeTemperature(food string) (SafeTemperature, bool) {
+	for _, temperature := range SafeTemperatures {
+		if matchesFood(food, temperature.Food, temperature.Aliases) {
+			return temperature, true
+		}
+	}
+	return SafeTemperature{}, false
+}
+
+// Finds the storage time for a food asked about by name
+func FindStorageTime(food string) (StorageTime, bool) {
+	for _, storage := range StorageTimes {
+		if matchesFood(food, storage.Food, storage.Aliases) {
+			return storage, true
+		}
+	}
+	return StorageTime{}, false
+}
+
+// A meat a recipe step cooks, as the step names it, and its safe temperature
+type Doneness struct {
+	Food        string
+	Temperature SafeTemperature
+}
+
+// The meats a step cooks, in the order they are mentioned, or none when the
+// step doesn't cook anything
+func DonenessIn(step string) []Doneness {
+	text := " " + normalizeTerm(step) + " "
+	cooks := false
+	for _, verb := range cookingVerbs {
+		cooks = cooks || strings.Contains(text, " "+verb+" ")
+	}
+	if !cooks {
+		return nil
+	}
+	type name struct {
+		form  string
+		entry int
+	}
+	var names []name
+	for i, temperature := range SafeTemperatures {
+		if !temperature.Meat {
+			continue
+		}
+		for _, form := range append([]string{temperature.Food}, temperature.Aliases...) {
+			names = append(names, name{form: normalizeTerm(form), entry: i})
+		}
+	}
+	// Longest first, so "chicken" isn't found again inside "ground chicken"
+	sort.SliceStable(names, func(i, j int) bool { return len(names[i].form) > len(names[j].form) })
+	position := make(map[int]int)
+	forms := make(map[int]string)
+	for _, name := range names {
+		for {
+			index := strings.Index(text, " "+name.form+" ")
+			if index < 0 {
+				break
+			}
+			end := index + 1 + len(name.form)
+			text = text[:index+1] + strings.Repeat("_", len(name.form)) + text[end:]
+			if isMeatProduct(text[end:]) {
+				continue
+			}
+			if previous, seen := position[name.entry]; !seen || index < previous {
+				position[name.entry], forms[name.entry] = index, name.form
+			}
+		}
+	}
+	var entries []int
+	for entry := range position {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return position[entries[i]] < position[entries[j]] })
+	var found []Doneness
+	for _, entry := range entries {
+		found = append(found, Doneness{Food: forms[entry], Temperature: SafeTemperatures[entry]})
+	}
+	return found
+}
+
+// Reports whether rest, the text after a meat, starts with a word such as
+// "stock" that makes the meat a flavour rather than the food being cooked
+func isMeatProduct(rest string) bool {
+	for _, product := range meatProducts {
+		if strings.HasPrefix(rest, " "+product+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether food names name or one of its aliases, ignoring case and punctuation
+func matchesFood(food string, name string, aliases []string) bool {
+	food = normalizeTerm(food)
+	for _, candidate := range append([]string{name}, aliases...) {
+		if normalizeTerm(candidate) == food {
+			return true
+		}
+	}
+	return false
+}
+
+// Speaks a span such as "3 to 4 days" or "1 week"
+func (span StorageSpan) String() string {
+	if span.Min == span.Max {
+		return fmt.Sprintf("%d %s", span.Min, span.Unit)
+	}
+	return fmt.Sprintf("%d to %d %s", span.Min, span.Max, span.Unit)
+}
+
+// Answers "what temperature is chicken done"
+func safeTemperature(input *skill.HandlerInput) error {
+	food := input.Request.Body.Intent.Slots["food"].ResolvedValue()
+	if food == "" {
+		return errors.New("Food is not present in the request")
+	}
+	safe, ok := FindSafeTemperature(food)
+	if !ok {
+		return Answer(input, "Food Safety", input.Messages.Format("unknownFood", food), nil)
+	}
+	text := input.Messages.Format("safeTemperature", food, temperature(input, safe))
+	if safe.RestMinutes > 0 {
+		text = input.Messages.Format("safeTemperatureRest", food, temperature(input, safe), safe.RestMinutes)
+	}
+	return Answer(input, "Food Safety", text, nil)
+}
+
+// Answers "how long does cooked rice last in the fridge", giving both the
+// fridge and freezer times unless the freezer was asked about
+func storageTime(input *skill.HandlerInput) error {
+	food := input.Request.Body.Intent.Slots["food"].ResolvedValue()
+	if food == "" {
+		return errors.New("Food is not present in the request")
+	}
+	storage, ok := FindStorageTime(food)
+	if !ok {
+		return Answer(input, "Food Safety", input.Messages.Format("unknownFood", food), nil)
+	}
+	fridge := input.Messages.Format("fridge")
+	var text string
+	switch {
+	case strings.EqualFold(input.Request.Body.SlotValue("storage"), "freezer") && storage.Freezer.Max > 0:
+		text = input.Messages.Format("freezer", capitalize(food), storage.Freezer)
+	case storage.Freezer.Max > 0:
+		text = input.Messages.Format("storage", capitalize(food), storage.Fridge, fridge, storage.Freezer)
+	default:
+		text = input.Messages.Format("noFreezing", capitalize(food), storage.Fridge, fridge)
+	}
+	return Answer(input, "Food Safety", text, nil)
+}
+
+// Speaks a safe temperature in the units of the request's locale
+func temperature(input *skill.HandlerInput, safe SafeTemperature) string {
+	return input.Messages.Format("temperature", safe.Fahrenheit, safe.Celsius)
+}
+
+// Upper-cases the first letter of text, to start a sentence with it
+func capitalize(text string) string {
+	first, size := utf8.DecodeRuneInString(text)
+	return string(unicode.ToUpper(first)) + text[size:]
+}
@@ -0,0 +1,30 @@
+package recipes
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDonenessIn(t *testing.T) {
+	tests := map[string]string{
+		"Roast the chicken for 40 minutes":                           "chicken",
+		"Simmer the chicken stock for an hour":                       "",
+		"Add the beef broth and a bouillon cube, then simmer":        "",
+		"Stir in chicken bouillon and bring to a simmer":             "",
+		"Pour in the chicken stock, then brown the ground beef":      "ground beef",
+		"Simmer the chicken stock, then poach the chicken in it":     "chicken",
+		"Fry the ground chicken with the chicken":                    "ground chicken, chicken",
+		"Sear the steaks and ground chicken, then ground chicken":    "steaks, ground chicken",
+		"Slice the chicken thinly":                                   "",
+		"Bake the salmon over a pan of fish stock and grill the cod": "salmon",
+	}
+	for step, want := range tests {
+		var foods []string
+		for _, doneness := range DonenessIn(step) {
+			foods = append(foods, doneness.Food)
+		}
+		if got := strings.Join(foods, ", "); got != want {
+			t.Errorf("DonenessIn(%q) found %q, want %q", step, got, want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package recipes
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mongodb-developer/alexa-golang-example/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// A cooking term or technique, such as "fold" or "julienne"
+type Term struct {
+	ID   primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name string             `bson:"name" json:"name"`
+	// Other forms of the term found in recipe steps, such as "folding" or "folded"
+	Aliases []string `bson:"aliases,omitempty" json:"aliases,omitempty"`
+	// A sentence or two for Alexa to speak
+	Speech string `bson:"speech" json:"speech"`
+	// A longer explanation for the card
+	Card string `bson:"card" json:"card"`
+}
+
+// Describes where the glossary of cooking terms comes from
+type GlossaryStore interface {
+	Glossary(ctx context.Context) (*Glossary, error)
+}
+
+// The cooking terms, indexed by every form they can take
+type Glossary struct {
+	terms map[string]Term
+	// The forms of all terms, longest first so "cut in" wins over "cut"
+	forms []string
+}
+
+func NewGlossary(terms []Term) *Glossary {
+	glossary := &Glossary{terms: make(map[string]Term)}
+	for _, term := range terms {
+		for _, form := range append([]string{term.Name}, term.Aliases...) {
+			form = normalizeTerm(form)
+			if _, ok := glossary.terms[form]; form == "" || ok {
+				continue
+			}
+			glossary.terms[form] = term
+			glossary.forms = append(glossary.forms, form)
+		}
+	}
+	sort.Slice(glossary.forms, func(i, j int) bool { return len(glossary.forms[i]) > len(glossary.forms[j]) })
+	return glossary
+}
+
+// Finds the term a user asked about by any of its forms
+func (glossary *Glossary) Find(word string) (Term, bool) {
+	term, ok := glossary.terms[normalizeTerm(word)]
+	return term, ok
+}
+
+// The terms used in a recipe step, in the order they first appear
+func (glossary *Glossary) TermsIn(step string) []Term {
+	text := " " + normalizeTerm(step) + " "
+	position := make(map[string]int)
+	var found []Term
+	for _, form := range glossary.forms {
+		index := strings.Index(text, " "+form+" ")
+		if index < 0 {
+			continue
+		}
+		term := glossary.terms[form]
+		if _, seen := position[term.Name]; !seen {
+			found = append(found, term)
+		}
+		if previous, seen := position[term.Name]; !seen || index < previous {
+			position[term.Name] = index
+		}
+		// Blanks the match so a shorter form inside it isn't found again
+		text = text[:index+1] + strings.Repeat("_", len(form)) + text[index+1+len(form):]
+	}
+	sort.SliceStable(found, func(i, j int) bool { return position[found[i].Name] < position[found[j].Name] })
+	return found
+}
+
+// Lower-cases text and reduces punctuation and runs of spaces to single spaces
+func normalizeTerm(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	}), " ")
+}
+
+// A GlossaryStore reading the glossary collection, which is small and rarely
+// changes, so it is read whole and kept for a while
+type MongoGlossaryStore struct {
+	cache *refresher
+}
+
+func NewMongoGlossaryStore(collection *mongodb.Collection, ttl time.Duration) *MongoGlossaryStore {
+	return &MongoGlossaryStore{cache: newRefresher("glossary", ttl, func(ctx context.Context) (interface{}, error) {
+		var terms []Term
+		cursor, err := collection.Find(ctx, bson.M{})
+		if err == nil {
+			err = cursor.All(ctx, &terms)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return NewGlossary(terms), nil
+	})}
+}
+
+// The glossary, read again once it is older than the TTL. The previous copy
+// is kept when it can't be.
+func (store *MongoGlossaryStore) Glossary(ctx context.Context) (*Glossary, error) {
+	glossary, err := store.cache.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return glossary.(*Glossary), nil
+}
@@ -0,0 +1,129 @@
+package recipes
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTermsIn(t *testing.T) {
+	glossary := NewGlossary([]Term{
+		{Name: "fold", Aliases: []string{"folding", "folded"}},
+		{Name: "cut in", Aliases: []string{"cutting in"}},
+		{Name: "cut"},
+		{Name: "sauté", Aliases: []string{"saute"}},
+		{Name: "al dente"},
+	})
+	tests := map[string]string{
+		"Fold in the egg whites.":                         "fold",
+		"Cut in the butter, then cut the dough in two.":   "cut in, cut",
+		"Keep folding until smooth, then fold once more.": "fold",
+		"Cook until al dente, then sauté the garlic.":     "al dente, sauté",
+		"Saute the onions; cut the leeks":                 "sauté, cut",
+		"Scaffold the unfolded layers":                    "",
+		"Cutting in the butter":                           "cut in",
+	}
+	for step, want := range tests {
+		var names []string
+		for _, term := range glossary.TermsIn(step) {
+			names = append(names, term.Name)
+		}
+		if got := strings.Join(names, ", "); got != want {
+			t.Errorf("TermsIn(%q) = %q, want %q", step, got, want)
+		}
+	}
+}
+
+// Counts reads, each returning the next of results, and holds them while
+// block isn't nil
+type countedLoad struct {
+	mutex   sync.Mutex
+	reads   int
+	results []error
+	block   chan struct{}
+}
+
+func (load *countedLoad) read(ctx context.Context) (interface{}, error) {
+	load.mutex.Lock()
+	read, block := load.reads, load.block
+	load.reads++
+	load.mutex.Unlock()
+	if block != nil {
+		<-block
+	}
+	if read < len(load.results) && load.results[read] != nil {
+		return nil, load.results[read]
+	}
+	return read, nil
+}
+
+func (load *countedLoad) count() int {
+	load.mutex.Lock()
+	defer load.mutex.Unlock()
+	return load.reads
+}
+
+func TestRefresherServesStaleCopyWhileRefreshing(t *testing.T) {
+	load := &countedLoad{}
+	cache := newRefresher("test", 10*time.Millisecond, load.read)
+	ctx := context.Background()
+	if value, err := cache.get(ctx); err != nil || value != 0 {
+		t.Fatalf("got %v, %v, want the first read", value, err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	release := make(chan struct{})
+	load.mutex.Lock()
+	load.block = release
+	load.mutex.Unlock()
+	refreshed := make(chan interface{})
+	go func() {
+		value, _ := cache.get(ctx)
+		refreshed <- value
+	}()
+	for load.count() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	// Another caller isn't held up by the refresh in progress
+	if value, err := cache.get(ctx); err != nil || value != 0 {
+		t.Errorf("got %v, %v during the refresh, want the previous copy", value, err)
+	}
+	close(release)
+	if value := <-refreshed; value != 1 {
+		t.Errorf("got %v from the refresh, want the second read", value)
+	}
+}
+
+func TestRefresherDoesNotRetryFailedReadsOnEveryCall(t *testing.T) {
+	unreachable := errors.New("server selection timeout")
+	load := &countedLoad{results: []error{nil, unreachable, unreachable}}
+	cache := newRefresher("test", 50*time.Millisecond, load.read)
+	ctx := context.Background()
+	if _, err := cache.get(ctx); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if value, err := cache.get(ctx); err != nil || value != 0 {
+			t.Fatalf("got %v, %v, want the previous copy while the database is down", value, err)
+		}
+	}
+	// The TTL is shorter than refreshRetry, so the failed read waits out the TTL
+	if reads := load.count(); reads != 2 {
+		t.Errorf("read %d times, want the failed refresh tried once", reads)
+	}
+
+	never := &countedLoad{results: []error{unreachable}}
+	cache = newRefresher("test", time.Hour, never.read)
+	for i := 0; i < 3; i++ {
+		if _, err := cache.get(ctx); err != unreachable {
+			t.Fatalf("got %v, want the error of the failed read", err)
+		}
+	}
+	if reads := never.count(); reads != 1 {
+		t.Errorf("read %d times, want a failed first read kept for refreshRetry", reads)
+	}
+}
@@ -0,0 +1,227 @@
+package recipes
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mongodb-developer/alexa-golang-example/mongodb"
+	"github.com/mongodb-developer/alexa-golang-example/skill"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Describes where users' daily nutrition goals are kept, by nutrient
+type NutritionGoalStore interface {
+	Goals(ctx context.Context, userID string) (map[string]float64, error)
+	SetGoal(ctx context.Context, userID string, nutrient string, amount float64) error
+}
+
+// A NutritionGoalStore keeping one document per user, {_id: userId, goals: {calories: 2000}}
+type MongoNutritionGoalStore struct {
+	collection *mongodb.Collection
+}
+
+func NewMongoNutritionGoalStore(collection *mongodb.Collection) *MongoNutritionGoalStore {
+	return &MongoNutritionGoalStore{collection: collection}
+}
+
+func (store *MongoNutritionGoalStore) Goals(ctx context.Context, userID string) (map[string]float64, error) {
+	var document struct {
+		Goals map[string]float64 `bson:"goals"`
+	}
+	err := store.collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&document)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return document.Goals, err
+}
+
+func (store *MongoNutritionGoalStore) SetGoal(ctx context.Context, userID string, nutrient string, amount float64) error {
+	_, err := store.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"goals." + nutrient: amount}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// Other names people use for the nutrients
+var nutrientSynonyms = map[string]string{
+	"calorie":      "calories",
+	"kcal":         "calories",
+	"energy":       "calories",
+	"carbs":        "carbohydrates",
+	"carb":         "carbohydrates",
+	"carbohydrate": "carbohydrates",
+	"fibre":        "fiber",
+	"sugars":       "sugar",
+	"salt":         "sodium",
+}
+
+// The nutrient a slot names, or an empty string when it isn't one we track
+func nutrientOf(slot string) string {
+	nutrient := strings.ToLower(strings.TrimSpace(slot))
+	if synonym, ok := nutrientSynonyms[nutrient]; ok {
+		nutrient = synonym
+	}
+	for _, known := range Nutrients {
+		if nutrient == known {
+			return nutrient
+		}
+	}
+	return ""
+}
+
+// Speaks an amount of a nutrient with its unit, such as "95 grams of protein"
+func nutrientAmount(input *skill.HandlerInput, nutrient string, amount float64) string {
+	return input.Messages.Format("amount."+nutrient, amount)
+}
+
+// Answers "high protein dinners under 600 calories" and "low sodium soups"
+func (connection Connection) recipesByNutrition(input *skill.HandlerInput) error {
+	var limits []NutrientLimit
+	for _, slot := range []string{"qualityone", "qualitytwo"} {
+		if input.Request.Body.SlotValue(slot) == "" {
+			continue
+		}
+		quality := strings.ToLower(input.Request.Body.Intent.Slots[slot].ResolvedValue())
+		if limit, ok := NutritionQualities[quality]; ok {
+			limits = append(limits, limit)
+		}
+	}
+	if calories := input.Request.Body.SlotValue("calories"); calories != "" {
+		if amount, err := strconv.ParseFloat(calories, 64); err == nil && amount > 0 {
+			limits = append(limits, NutrientLimit{Nutrient: "calories", Max: Float(amount)})
+		}
+	}
+	if len(limits) == 0 {
+		return Answer(input, "Nutrition", input.Messages.Format("nutritionHelp"), nil)
+	}
+	tag := ""
+	if input.Request.Body.SlotValue("tag") != "" {
+		tag = tagOf(input.Request.Body.Intent.Slots["tag"])
+	}
+	found, err := connection.store.Query(input.Context, RecipeQuery{Tag: tag, Nutrition: limits})
+	if err != nil && !errors.Is(err, ErrDegraded) {
+		return err
+	}
+	if len(found) == 0 {
+		return Answer(input, "Nutrition", input.Messages.Format("noNutritionResults"), err)
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Nutrition.Calories < found[j].Nutrition.Calories })
+	if len(found) > maxNutritionResults {
+		found = found[:maxNutritionResults]
+	}
+	var listed []string
+	for _, recipe := range found {
+		listed = append(listed, input.Messages.Format("nutritionResult", recipe.Name, nutrientAmount(input, "calories", recipe.Nutrition.Calories)))
+	}
+	return Answer(input, "Nutrition", input.Messages.Format("nutritionResults", JoinWithAnd(listed)), err)
+}
+
+// The most recipes read out by recipesByNutrition
+const maxNutritionResults = 5
+
+// Answers "set my daily calorie goal to 2000"
+func (connection Connection) setNutritionGoal(input *skill.HandlerInput) error {
+	nutrient := nutrientOf(input.Request.Body.Intent.Slots["nutrient"].ResolvedValue())
+	amount, err := strconv.ParseFloat(input.Request.Body.SlotValue("amount"), 64)
+	if nutrient == "" || err != nil || amount <= 0 {
+		return Answer(input, "Nutrition Goals", input.Messages.Format("goalHelp"), nil)
+	}
+	if connection.goals == nil {
+		return Answer(input, "Nutrition Goals", input.Messages.Format("goalsUnavailable"), nil)
+	}
+	if err := connection.goals.SetGoal(input.Context, input.UserID(), nutrient, amount); err != nil {
+		return err
+	}
+	return Answer(input, "Nutrition Goals", input.Messages.Format("goalSet", nutrientAmount(input, nutrient, amount)), nil)
+}
+
+// Answers "how many calories have I planned for Tuesday", from the newest of
+// the user's meal plans with that day, at one serving of each recipe, and
+// compares the day with the user's goals
+func (connection Connection) plannedNutrition(input *skill.HandlerInput) error {
+	day := input.Request.Body.SlotValue("day")
+	if day == "" {
+		return errors.New("Day is not present in the request")
+	}
+	if connection.plans == nil {
+		return Answer(input, "Nutrition Goals", input.Messages.Format("goalsUnavailable"), nil)
+	}
+	userID := input.UserID()
+	plans, err := connection.plans.MealPlans(input.Context, userID)
+	if err != nil {
+		return err
+	}
+	var planned *MealPlanDay
+	var newest MealPlan
+	for _, plan := range plans {
+		for i := range plan.Days {
+			if strings.EqualFold(plan.Days[i].Day, day) && (planned == nil || plan.ID.Timestamp().After(newest.ID.Timestamp())) {
+				planned, newest = &plan.Days[i], plan
+			}
+		}
+	}
+	if planned == nil || len(planned.Recipes) == 0 {
+		return Answer(input, "Nutrition Goals", input.Messages.Format("nothingPlanned", day), nil)
+	}
+
+	found, err := connection.store.FindByNames(input.Context, planned.Recipes)
+	if err != nil && !errors.Is(err, ErrDegraded) {
+		return err
+	}
+	byName := make(map[string]Recipe, len(found))
+	for _, recipe := range found {
+		byName[recipe.Name] = recipe
+	}
+	var total Nutrition
+	var uncounted []string
+	for _, name := range planned.Recipes {
+		recipe, ok := byName[name]
+		if !ok || recipe.Nutrition == nil {
+			uncounted = append(uncounted, name)
+			continue
+		}
+		total = total.add(*recipe.Nutrition, 1)
+	}
+
+	var goals map[string]float64
+	if connection.goals != nil {
+		var goalsErr error
+		if goals, goalsErr = connection.goals.Goals(input.Context, userID); goalsErr != nil {
+			return goalsErr
+		}
+	}
+	var amounts, comparisons []string
+	for _, nutrient := range Nutrients {
+		goal, hasGoal := goals[nutrient]
+		if nutrient != "calories" && !hasGoal {
+			continue
+		}
+		value := total.Value(nutrient)
+		amounts = append(amounts, nutrientAmount(input, nutrient, value))
+		switch {
+		case !hasGoal:
+		case value < goal:
+			comparisons = append(comparisons, input.Messages.Format("goalUnder", nutrientAmount(input, nutrient, goal-value)))
+		case value > goal:
+			comparisons = append(comparisons, input.Messages.Format("goalOver", nutrientAmount(input, nutrient, value-goal)))
+		default:
+			comparisons = append(comparisons, input.Messages.Format("goalMet", nutrient))
+		}
+	}
+	text := input.Messages.Format("planned", JoinWithAnd(amounts), day)
+	if len(comparisons) > 0 {
+		text += input.Messages.Format("goalComparison", JoinWithAnd(comparisons))
+	}
+	if len(uncounted) > 0 {
+		text += input.Messages.Format("uncounted", JoinWithAnd(uncounted))
+	}
+	return Answer(input, "Nutrition Goals", text, err)
+}
@@ -0,0 +1,91 @@
+package recipes
+
+import (
+	"context"
+
+	"github.com/mongodb-developer/alexa-golang-example/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// A user's plan of which recipes to cook on which days
+type MealPlan struct {
+	ID     primitive.ObjectID `bson:"_id" json:"id"`
+	UserID string             `bson:"userId" json:"userId"`
+	Name   string             `bson:"name" json:"name"`
+	Days   []MealPlanDay      `bson:"days" json:"days"`
+}
+
+// The recipes planned for one day, by recipe name
+type MealPlanDay struct {
+	Day     string   `bson:"day" json:"day"`
+	Recipes []string `bson:"recipes" json:"recipes"`
+}
+
+// Describes how meal plans are kept
+type MealPlanStore interface {
+	MealPlans(ctx context.Context, userID string) ([]MealPlan, error)
+	// Inserts a plan without an ID, or replaces the user's plan with the same
+	// ID, returning mongo.ErrNoDocuments when the user has no such plan
+	SaveMealPlan(ctx context.Context, plan MealPlan) (MealPlan, error)
+	// Replaces the user's plan with the same name, or inserts it when there
+	// is none, returning it with its ID
+	SaveMealPlanByName(ctx context.Context, plan MealPlan) (MealPlan, error)
+	// Reports whether the user had a plan with the ID to delete
+	DeleteMealPlan(ctx context.Context, userID string, id primitive.ObjectID) (bool, error)
+}
+
+// A MealPlanStore keeping one document per plan
+type MongoMealPlanStore struct {
+	collection *mongodb.Collection
+}
+
+func NewMongoMealPlanStore(collection *mongodb.Collection) *MongoMealPlanStore {
+	return &MongoMealPlanStore{collection: collection}
+}
+
+func (store *MongoMealPlanStore) MealPlans(ctx context.Context, userID string) ([]MealPlan, error) {
+	var plans []MealPlan
+	cursor, err := store.collection.Find(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &plans); err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
+func (store *MongoMealPlanStore) SaveMealPlan(ctx context.Context, plan MealPlan) (MealPlan, error) {
+	if plan.ID.IsZero() {
+		plan.ID = primitive.NewObjectID()
+		_, err := store.collection.InsertOne(ctx, plan)
+		return plan, err
+	}
+	result, err := store.collection.ReplaceOne(ctx, bson.M{"_id": plan.ID, "userId": plan.UserID}, plan)
+	if err != nil {
+		return MealPlan{}, err
+	}
+	if result.MatchedCount == 0 {
+		return MealPlan{}, mongo.ErrNoDocuments
+	}
+	return plan, nil
+}
+
+func (store *MongoMealPlanStore) SaveMealPlanByName(ctx context.Context, plan MealPlan) (MealPlan, error) {
+	// An upsert copies the user and name from the filter into a new plan
+	var saved MealPlan
+	err := store.collection.FindOneAndUpdate(ctx, bson.M{"userId": plan.UserID, "name": plan.Name}, bson.M{"$set": bson.M{"days": plan.Days}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&saved)
+	return saved, err
+}
+
+func (store *MongoMealPlanStore) DeleteMealPlan(ctx context.Context, userID string, id primitive.ObjectID) (bool, error) {
+	result, err := store.collection.DeleteOne(ctx, bson.M{"_id": id, "userId": userID})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
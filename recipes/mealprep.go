@@ -0,0 +1,257 @@
+package recipes
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mongodb-developer/alexa-golang-example/skill"
+)
+
+// How much of an ingredient several recipes need between them
+type PrepIngredient struct {
+	Ingredient string
+	// Zero when a recipe using it doesn't say how much
+	Amount float64
+	Unit   string
+	// The recipes using it
+	Recipes []string
+}
+
+// A preparation of an ingredient, done once for every recipe that needs it,
+// such as chopping all the onions
+type PrepTask struct {
+	Prep       string
+	Ingredient string
+	Amount     float64
+	Unit       string
+	Recipes    []string
+}
+
+// The combined preparation of several recipes for meal prep
+type MealPrep struct {
+	Portions int
+	// Each ingredient once for every unit it can be totalled in, in the order first used
+	Ingredients []PrepIngredient
+	Tasks       []PrepTask
+	// Recipes without quantities, whose ingredients are listed without amounts
+	Unmeasured []string
+	// Recipes that don't say how many they serve, which are made as written
+	Unscaled []string
+}
+
+// The number of portions made by meal prep when the user doesn't say, a working week of lunches
+const defaultPortions = 5
+
+// Combines recipes made together into totals of their ingredients scaled to
+// the portions wanted, and one task for each way an ingredient is prepared
+func PlanMealPrep(recipes []Recipe, portions int) MealPrep {
+	prep := MealPrep{Portions: portions}
+	for _, recipe := range recipes {
+		if len(recipe.Quantities) == 0 {
+			prep.Unmeasured = append(prep.Unmeasured, recipe.Name)
+			for _, ingredient := range recipe.Ingredients {
+				prep.addIngredient(Quantity{Ingredient: ingredient}, recipe.Name)
+			}
+			continue
+		}
+		factor := 1.0
+		if recipe.Servings > 0 {
+			factor = float64(portions) / float64(recipe.Servings)
+		} else {
+			prep.Unscaled = append(prep.Unscaled, recipe.Name)
+		}
+		for _, quantity := range recipe.Quantities {
+			quantity.Amount *= factor
+			prep.addIngredient(quantity, recipe.Name)
+			if quantity.Prep != "" {
+				prep.addTask(quantity, recipe.Name)
+			}
+		}
+	}
+	return prep
+}
+
+// Adds an amount to the total of an ingredient in a compatible unit, or
+// starts a new total
+func (prep *MealPrep) addIngredient(quantity Quantity, recipeName string) {
+	key := normalizeIngredient(quantity.Ingredient)
+	for i := range prep.Ingredients {
+		total := &prep.Ingredients[i]
+		if normalizeIngredient(total.Ingredient) != key {
+			continue
+		}
+		if quantity.Amount == 0 {
+			total.Recipes = appendOnce(total.Recipes, recipeName)
+			return
+		}
+		if total.Amount == 0 {
+			total.Amount, total.Unit = quantity.Amount, quantity.Unit
+			total.Recipes = appendOnce(total.Recipes, recipeName)
+			return
+		}
+		if amount, ok := ConvertAmount(quantity.Amount, quantity.Unit, total.Unit); ok {
+			total.Amount += amount
+			total.Recipes = appendOnce(total.Recipes, recipeName)
+			return
+		}
+	}
+	prep.Ingredients = append(prep.Ingredients, PrepIngredient{
+		Ingredient: quantity.Ingredient, Amount: quantity.Amount, Unit: quantity.Unit, Recipes: []string{recipeName},
+	})
+}
+
+// Adds an amount to the task preparing an ingredient the same way, or starts a new task
+func (prep *MealPrep) addTask(quantity Quantity, recipeName string) {
+	for i := range prep.Tasks {
+		task := &prep.Tasks[i]
+		if normalizeIngredient(task.Ingredient) != normalizeIngredient(quantity.Ingredient) || !strings.EqualFold(task.Prep, quantity.Prep) {
+			continue
+		}
+		if amount, ok := ConvertAmount(quantity.Amount, quantity.Unit, task.Unit); ok {
+			task.Amount += amount
+			task.Recipes = appendOnce(task.Recipes, recipeName)
+			return
+		}
+	}
+	prep.Tasks = append(prep.Tasks, PrepTask{
+		Prep: strings.ToLower(quantity.Prep), Ingredient: quantity.Ingredient, Amount: quantity.Amount, Unit: quantity.Unit, Recipes: []string{recipeName},
+	})
+}
+
+func appendOnce(list []string, value string) []string {
+	for _, item := range list {
+		if item == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
+
+// Speaks an amount of an ingredient, such as "1.5 pounds of ground beef" or
+// "3 onions", rounding counts up to whole items. Units are spoken by their
+// "unit." and "units." messages, or as written when there are none.
+func speakQuantity(input *skill.HandlerInput, ingredient string, amount float64, unit string) string {
+	if amount == 0 {
+		return ingredient
+	}
+	name, known := unitName(unit)
+	if known && name == "" {
+		// Part of an onion still means buying a whole one
+		count := math.Ceil(amount)
+		if count != 1 {
+			ingredient = pluralize(ingredient)
+		}
+		return input.Messages.Format("countOf", strconv.FormatFloat(count, 'f', -1, 64), ingredient)
+	}
+	amount = math.Round(amount*100) / 100
+	key := "unit." + name
+	if amount != 1 {
+		key = "units." + name
+	}
+	if spoken, ok := input.Messages[key]; ok && known {
+		unit = spoken
+	}
+	return input.Messages.Format("amountOf", strconv.FormatFloat(amount, 'f', -1, 64), unit, ingredient)
+}
+
+// The plural of a counted ingredient, such as "onions" or "bay leaves".
+// Ingredients already written in the plural are left as they are.
+func pluralize(ingredient string) string {
+	lower := strings.ToLower(ingredient)
+	switch {
+	case strings.HasSuffix(lower, "s"):
+		return ingredient
+	case strings.HasSuffix(lower, "leaf"):
+		return ingredient[:len(ingredient)-1] + "ves"
+	case strings.HasSuffix(lower, "x") || strings.HasSuffix(lower, "ch") || strings.HasSuffix(lower, "sh") || strings.HasSuffix(lower, "o"):
+		return ingredient + "es"
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsAny(lower[len(lower)-2:len(lower)-1], "aeiou"):
+		return ingredient[:len(ingredient)-1] + "ies"
+	}
+	return ingredient + "s"
+}
+
+// Answers "I'm making chili, rice and roasted vegetables for meal prep",
+// optionally with how many portions of each
+func (connection Connection) mealPrep(input *skill.HandlerInput) error {
+	var recipeNames []string
+	for _, slot := range []string{"recipeone", "recipetwo", "recipethree"} {
+		if recipeName := input.Request.Body.SlotValue(slot); recipeName != "" {
+			recipeNames = append(recipeNames, recipeName)
+		}
+	}
+	if len(recipeNames) == 0 {
+		return errors.New("Recipe names are not present in the request")
+	}
+	portions := defaultPortions
+	if requested, err := strconv.Atoi(input.Request.Body.SlotValue("portions")); err == nil && requested > 0 {
+		portions = requested
+	}
+	found, err := connection.store.FindByNames(input.Context, recipeNames)
+	if err != nil && !errors.Is(err, ErrDegraded) {
+		return err
+	}
+	byName := make(map[string]Recipe, len(found))
+	for _, recipe := range found {
+		byName[recipe.Name] = recipe
+	}
+	var recipes []Recipe
+	var names, missing []string
+	for _, recipeName := range recipeNames {
+		if recipe, ok := byName[recipeName]; ok {
+			recipes = append(recipes, recipe)
+			names = append(names, recipe.Name)
+		} else {
+			missing = append(missing, recipeName)
+		}
+	}
+	if len(recipes) == 0 {
+		return Answer(input, "Meal Prep", input.Messages.Format("prepNotFound", JoinWithAnd(missing)), err)
+	}
+	prep := PlanMealPrep(recipes, portions)
+
+	var ingredients, tasks, card []string
+	for _, ingredient := range prep.Ingredients {
+		ingredients = append(ingredients, speakQuantity(input, ingredient.Ingredient, ingredient.Amount, ingredient.Unit))
+	}
+	card = append(card, input.Messages.Format("prepShoppingCard", strings.Join(ingredients, "\n")))
+	for _, task := range prep.Tasks {
+		tasks = append(tasks, input.Messages.Format("prepTask", task.Prep, speakQuantity(input, task.Ingredient, task.Amount, task.Unit), JoinWithAnd(task.Recipes)))
+	}
+	var taskLines []string
+	for _, task := range tasks {
+		taskLines = append(taskLines, capitalize(task))
+	}
+	text := input.Messages.Format("prepIngredients", prep.Portions, JoinWithAnd(names), JoinWithAnd(ingredients))
+	if len(tasks) > 0 {
+		// Brief answers leave the tasks to the card
+		if input.Preferences.Verbosity != skill.Brief {
+			text += input.Messages.Format("prepTasks", JoinWithAnd(tasks))
+		}
+		card = append(card, input.Messages.Format("prepTasksCard", strings.Join(taskLines, "\n")))
+	}
+	containers := input.Messages.Format("prepContainers", prep.Portions)
+	text += containers
+	card = append(card, strings.TrimSpace(containers))
+	var notes []string
+	if len(prep.Unmeasured) > 0 {
+		notes = append(notes, input.Messages.Format("prepUnmeasured", JoinWithAnd(prep.Unmeasured)))
+	}
+	if len(prep.Unscaled) > 0 {
+		notes = append(notes, input.Messages.Format("prepUnscaled", JoinWithAnd(prep.Unscaled)))
+	}
+	if len(missing) > 0 {
+		notes = append(notes, input.Messages.Format("prepMissing", JoinWithAnd(missing)))
+	}
+	for _, note := range notes {
+		text += note
+		card = append(card, strings.TrimSpace(note))
+	}
+	if errors.Is(err, ErrDegraded) {
+		text = input.Messages.Format("degraded") + text
+	}
+	input.Response.Speak(text).SimpleCard("Meal Prep", strings.Join(card, "\n\n"))
+	return nil
+}
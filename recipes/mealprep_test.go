@@ -0,0 +1,94 @@
+package recipes
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mongodb-developer/alexa-golang-example/skill"
+)
+
+func TestPlanMealPrep(t *testing.T) {
+	recipes := []Recipe{
+		{Name: "Chili", Servings: 4, Quantities: []Quantity{
+			{Ingredient: "ground beef", Amount: 1, Unit: "lb"},
+			{Ingredient: "onion", Amount: 2, Prep: "chopped"},
+			{Ingredient: "garlic", Amount: 2, Unit: "cloves", Prep: "minced"},
+			{Ingredient: "kidney beans", Amount: 1, Unit: "can"},
+		}},
+		{Name: "Rice", Servings: 2, Quantities: []Quantity{
+			{Ingredient: "rice", Amount: 200, Unit: "g"},
+			{Ingredient: "Onion", Amount: 1, Prep: "Chopped"},
+			{Ingredient: "garlic", Amount: 1, Unit: "clove", Prep: "minced"},
+		}},
+		{Name: "Salad", Quantities: []Quantity{
+			{Ingredient: "cucumber", Amount: 1},
+			{Ingredient: "onion", Amount: 100, Unit: "g", Prep: "sliced"},
+		}},
+		{Name: "Bread", Ingredients: []string{"flour", "water"}},
+	}
+	got := PlanMealPrep(recipes, 4)
+	want := MealPrep{
+		Portions: 4,
+		Ingredients: []PrepIngredient{
+			{Ingredient: "ground beef", Amount: 1, Unit: "lb", Recipes: []string{"Chili"}},
+			{Ingredient: "onion", Amount: 4, Recipes: []string{"Chili", "Rice"}},
+			{Ingredient: "garlic", Amount: 4, Unit: "cloves", Recipes: []string{"Chili", "Rice"}},
+			{Ingredient: "kidney beans", Amount: 1, Unit: "can", Recipes: []string{"Chili"}},
+			{Ingredient: "rice", Amount: 400, Unit: "g", Recipes: []string{"Rice"}},
+			{Ingredient: "cucumber", Amount: 1, Recipes: []string{"Salad"}},
+			// Grams of onion can't be added to a count of them
+			{Ingredient: "onion", Amount: 100, Unit: "g", Recipes: []string{"Salad"}},
+			{Ingredient: "flour", Recipes: []string{"Bread"}},
+			{Ingredient: "water", Recipes: []string{"Bread"}},
+		},
+		Tasks: []PrepTask{
+			{Prep: "chopped", Ingredient: "onion", Amount: 4, Recipes: []string{"Chili", "Rice"}},
+			{Prep: "minced", Ingredient: "garlic", Amount: 4, Unit: "cloves", Recipes: []string{"Chili", "Rice"}},
+			{Prep: "sliced", Ingredient: "onion", Amount: 100, Unit: "g", Recipes: []string{"Salad"}},
+		},
+		Unmeasured: []string{"Bread"},
+		Unscaled:   []string{"Salad"},
+	}
+	if !reflect.DeepEqual(got.Ingredients, want.Ingredients) {
+		t.Errorf("got ingredients %+v, want %+v", got.Ingredients, want.Ingredients)
+	}
+	if !reflect.DeepEqual(got.Tasks, want.Tasks) {
+		t.Errorf("got tasks %+v, want %+v", got.Tasks, want.Tasks)
+	}
+	if got.Portions != want.Portions || !reflect.DeepEqual(got.Unmeasured, want.Unmeasured) || !reflect.DeepEqual(got.Unscaled, want.Unscaled) {
+		t.Errorf("got %d portions, %v unmeasured and %v unscaled, want %d, %v and %v", got.Portions, got.Unmeasured, got.Unscaled, want.Portions, want.Unmeasured, want.Unscaled)
+	}
+}
+
+func TestSpeakQuantity(t *testing.T) {
+	tests := []struct {
+		locale     string
+		ingredient string
+		amount     float64
+		unit       string
+		want       string
+	}{
+		{"en-US", "onion", 3, "", "3 onions"},
+		{"en-US", "onion", 0.5, "", "1 onion"},
+		{"en-US", "eggs", 2, "each", "2 eggs"},
+		{"en-US", "bay leaf", 2, "", "2 bay leaves"},
+		{"en-US", "tomato", 2, "", "2 tomatoes"},
+		{"en-US", "cherry", 10, "piece", "10 cherries"},
+		{"en-US", "salt", 0, "pinch", "salt"},
+		{"en-US", "ground beef", 1.5, "lb", "1.5 pounds of ground beef"},
+		{"en-US", "ground beef", 1, "Lb.", "1 pound of ground beef"},
+		{"en-US", "garlic", 4, "cloves", "4 cloves of garlic"},
+		{"en-US", "salt", 1, "pinch", "1 pinch of salt"},
+		{"en-US", "flour", 2.333, "cups", "2.33 cups of flour"},
+		{"en-US", "thyme", 2, "sprigs", "2 sprigs of thyme"},
+		{"en-US", "milk", 250, "ml", "250 milliliters of milk"},
+		{"en-GB", "milk", 250, "ml", "250 millilitres of milk"},
+		{"en-GB", "milk", 1, "l", "1 litre of milk"},
+	}
+	for _, test := range tests {
+		input := &skill.HandlerInput{Messages: messages.For(test.locale, "en-US")}
+		if got := speakQuantity(input, test.ingredient, test.amount, test.unit); got != test.want {
+			t.Errorf("%s %v %q of %s: got %q, want %q", test.locale, test.amount, test.unit, test.ingredient, got, test.want)
+		}
+	}
+}
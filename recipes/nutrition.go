@@ -0,0 +1,163 @@
+package recipes
+
+import (
+	"context"
+	"log"
+	"strconv"
+
+	"github.com/mongodb-developer/alexa-golang-example/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// The nutrients tracked, as named in queries, goals and the nutrition
+// documents, with energy in kilocalories, sodium in milligrams and the rest in grams
+var Nutrients = []string{"calories", "protein", "fat", "carbohydrates", "fiber", "sugar", "sodium"}
+
+// Nutrition facts, per serving of a recipe or per measure of an ingredient
+type Nutrition struct {
+	Calories      float64 `bson:"calories" json:"calories"`
+	Protein       float64 `bson:"protein" json:"protein"`
+	Fat           float64 `bson:"fat" json:"fat"`
+	Carbohydrates float64 `bson:"carbohydrates" json:"carbohydrates"`
+	Fiber         float64 `bson:"fiber" json:"fiber"`
+	Sugar         float64 `bson:"sugar" json:"sugar"`
+	Sodium        float64 `bson:"sodium" json:"sodium"`
+}
+
+// The amount of a nutrient by its name in Nutrients
+func (nutrition Nutrition) Value(nutrient string) float64 {
+	switch nutrient {
+	case "calories":
+		return nutrition.Calories
+	case "protein":
+		return nutrition.Protein
+	case "fat":
+		return nutrition.Fat
+	case "carbohydrates":
+		return nutrition.Carbohydrates
+	case "fiber":
+		return nutrition.Fiber
+	case "sugar":
+		return nutrition.Sugar
+	case "sodium":
+		return nutrition.Sodium
+	}
+	return 0
+}
+
+func (nutrition Nutrition) add(other Nutrition, times float64) Nutrition {
+	return Nutrition{
+		Calories:      nutrition.Calories + other.Calories*times,
+		Protein:       nutrition.Protein + other.Protein*times,
+		Fat:           nutrition.Fat + other.Fat*times,
+		Carbohydrates: nutrition.Carbohydrates + other.Carbohydrates*times,
+		Fiber:         nutrition.Fiber + other.Fiber*times,
+		Sugar:         nutrition.Sugar + other.Sugar*times,
+		Sodium:        nutrition.Sodium + other.Sodium*times,
+	}
+}
+
+// A bound on a nutrient per serving. A nil Min or Max leaves that side open,
+// so a Max of zero asks for none of the nutrient.
+type NutrientLimit struct {
+	Nutrient string
+	Min      *float64
+	Max      *float64
+}
+
+// Returns a pointer to amount, for the bounds of a NutrientLimit
+func Float(amount float64) *float64 {
+	return &amount
+}
+
+// Identifies the limit, for caching the recipes within it
+func (limit NutrientLimit) key() string {
+	return limit.Nutrient + ":" + formatBound(limit.Min) + ":" + formatBound(limit.Max)
+}
+
+func formatBound(bound *float64) string {
+	if bound == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*bound, 'g', -1, 64)
+}
+
+func (limit NutrientLimit) allows(nutrition Nutrition) bool {
+	value := nutrition.Value(limit.Nutrient)
+	return (limit.Min == nil || value >= *limit.Min) && (limit.Max == nil || value <= *limit.Max)
+}
+
+// What "high protein", "low sodium" and the like mean per serving. The lows
+// follow the FDA's limits for meals rather than for single foods.
+var NutritionQualities = map[string]NutrientLimit{
+	"high protein": {Nutrient: "protein", Min: Float(20)},
+	"high fiber":   {Nutrient: "fiber", Min: Float(5)},
+	"low calorie":  {Nutrient: "calories", Max: Float(400)},
+	"low fat":      {Nutrient: "fat", Max: Float(10)},
+	"low carb":     {Nutrient: "carbohydrates", Max: Float(20)},
+	"low sugar":    {Nutrient: "sugar", Max: Float(5)},
+	"low sodium":   {Nutrient: "sodium", Max: Float(600)},
+	"sugar free":   {Nutrient: "sugar", Max: Float(0)},
+}
+
+// The nutrition facts of an ingredient for an amount of it, such as per 100 g
+type IngredientNutrition struct {
+	Ingredient string  `bson:"ingredient" json:"ingredient"`
+	Amount     float64 `bson:"amount" json:"amount"`
+	// A unit known to ConvertAmount, or empty for a count such as 1 egg
+	Unit      string `bson:"unit,omitempty" json:"unit,omitempty"`
+	Nutrition `bson:",inline"`
+}
+
+// Computes the nutrition per serving of a recipe from the nutrition of its
+// ingredients, returning the ingredients that couldn't be counted
+func ComputeNutrition(recipe Recipe, ingredients map[string]IngredientNutrition) (Nutrition, []string) {
+	var total Nutrition
+	var missing []string
+	for _, quantity := range recipe.Quantities {
+		facts, ok := ingredients[quantity.Ingredient]
+		var amount float64
+		if ok && facts.Amount > 0 {
+			amount, ok = ConvertAmount(quantity.Amount, quantity.Unit, facts.Unit)
+		}
+		if !ok || facts.Amount <= 0 {
+			missing = append(missing, quantity.Ingredient)
+			continue
+		}
+		total = total.add(facts.Nutrition, amount/facts.Amount)
+	}
+	servings := float64(recipe.Servings)
+	if servings <= 0 {
+		servings = 1
+	}
+	return Nutrition{}.add(total, 1/servings), missing
+}
+
+// Reads the nutrition facts of every ingredient, by ingredient
+func LoadIngredientNutrition(ctx context.Context, ingredients *mongodb.Collection) (map[string]IngredientNutrition, error) {
+	var facts []IngredientNutrition
+	cursor, err := ingredients.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &facts); err != nil {
+		return nil, err
+	}
+	byIngredient := make(map[string]IngredientNutrition, len(facts))
+	for _, fact := range facts {
+		byIngredient[fact.Ingredient] = fact
+	}
+	return byIngredient, nil
+}
+
+// The update that stores the nutrition per serving of a recipe, and whether
+// every ingredient could be counted. A recipe with an ingredient that can't be
+// counted is left without nutrition rather than given figures that are too low.
+func NutritionUpdate(recipe Recipe, ingredients map[string]IngredientNutrition) (bson.M, bool) {
+	nutrition, missing := ComputeNutrition(recipe, ingredients)
+	if len(missing) > 0 {
+		log.Printf("leaving %s without nutrition, missing %v", recipe.Name, missing)
+		return bson.M{"$unset": bson.M{"nutrition": ""}}, false
+	}
+	return bson.M{"$set": bson.M{"nutrition": nutrition}}, true
+}
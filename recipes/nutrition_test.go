@@ -0,0 +1,30 @@
+package recipes
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestZeroNutrientLimits(t *testing.T) {
+	sugarFree := RecipeQuery{Nutrition: []NutrientLimit{NutritionQualities["sugar free"]}}
+	if !sugarFree.Matches(Recipe{Nutrition: &Nutrition{Sugar: 0}}) || sugarFree.Matches(Recipe{Nutrition: &Nutrition{Sugar: 0.5}}) {
+		t.Error("a maximum of zero should only match recipes without the nutrient")
+	}
+	want := bson.M{"$and": []bson.M{
+		{"nutrition": bson.M{"$exists": true}},
+		{"nutrition.sugar": bson.M{"$lte": 0.0}},
+	}}
+	if filter := sugarFree.filter(); !reflect.DeepEqual(filter, want) {
+		t.Errorf("got filter %v, want %v", filter, want)
+	}
+
+	openEnded := RecipeQuery{Nutrition: []NutrientLimit{{Nutrient: "sugar", Min: Float(0)}}}
+	if !openEnded.Matches(Recipe{Nutrition: &Nutrition{Sugar: 40}}) {
+		t.Error("a minimum of zero without a maximum should match any amount")
+	}
+	if sugarFree.key() == openEnded.key() || sugarFree.key() == (RecipeQuery{Nutrition: []NutrientLimit{{Nutrient: "sugar"}}}).key() {
+		t.Error("limits with different bounds share a cache key")
+	}
+}
@@ -0,0 +1,256 @@
+package recipes
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/mongodb-developer/alexa-golang-example/mongodb"
+	"github.com/mongodb-developer/alexa-golang-example/skill"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// An ingredient a user has at home and how much of it
+type PantryItem struct {
+	Ingredient string  `bson:"ingredient" json:"ingredient"`
+	Amount     float64 `bson:"amount" json:"amount"`
+	// A unit known to ConvertAmount, or empty for a count such as 3 onions
+	Unit string `bson:"unit,omitempty" json:"unit,omitempty"`
+}
+
+// What a user has at home and needs to buy
+type Pantry struct {
+	Items        []PantryItem `bson:"items" json:"items"`
+	ShoppingList []string     `bson:"shoppingList" json:"shoppingList"`
+	// The last recipe whose ingredients were taken out, so it can be undone
+	LastCooked *CookedRecipe `bson:"lastCooked,omitempty" json:"lastCooked,omitempty"`
+	// Counts the saves of the pantry, so one read before another save isn't
+	// saved over it
+	Version int64 `bson:"version" json:"version"`
+}
+
+// A recipe taken out of the pantry, with the pantry and shopping list as they were before
+type CookedRecipe struct {
+	Recipe       string       `bson:"recipe" json:"recipe"`
+	Items        []PantryItem `bson:"items" json:"items"`
+	ShoppingList []string     `bson:"shoppingList" json:"shoppingList"`
+}
+
+// Returned when saving a pantry that was saved by another request since it was read
+var ErrPantryChanged = errors.New("the pantry was changed by another request")
+
+// Describes where the contents of users' pantries are kept
+type PantryStore interface {
+	Pantry(ctx context.Context, userID string) (Pantry, error)
+	// Saves a pantry read by Pantry, or returns ErrPantryChanged when it has
+	// been saved since
+	SavePantry(ctx context.Context, userID string, pantry Pantry) error
+}
+
+// A PantryStore keeping one document per user, {_id: userId, items: [...], shoppingList: [...]}
+type MongoPantryStore struct {
+	collection *mongodb.Collection
+}
+
+func NewMongoPantryStore(collection *mongodb.Collection) *MongoPantryStore {
+	return &MongoPantryStore{collection: collection}
+}
+
+func (store *MongoPantryStore) Pantry(ctx context.Context, userID string) (Pantry, error) {
+	var pantry Pantry
+	err := store.collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&pantry)
+	if err == mongo.ErrNoDocuments {
+		return Pantry{}, nil
+	}
+	return pantry, err
+}
+
+// Saves the pantry only if its document still has the version it was read
+// with. A pantry that doesn't exist yet is inserted, unless another request
+// inserted it first, which fails on its _id.
+func (store *MongoPantryStore) SavePantry(ctx context.Context, userID string, pantry Pantry) error {
+	update := bson.M{"$set": bson.M{"items": pantry.Items, "shoppingList": pantry.ShoppingList, "version": pantry.Version + 1}}
+	if pantry.LastCooked != nil {
+		update["$set"].(bson.M)["lastCooked"] = pantry.LastCooked
+	} else {
+		update["$unset"] = bson.M{"lastCooked": ""}
+	}
+	filter := bson.M{"_id": userID, "version": pantry.Version}
+	if pantry.Version == 0 {
+		// Pantries saved before versions were kept have none
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
+	_, err := store.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrPantryChanged
+	}
+	return err
+}
+
+// How many times a change to a pantry is tried again after another request
+// changed it first
+const pantryRetries = 3
+
+// Reads the pantry of userID, changes it and saves it, reading it again when
+// another request saved it in between. change returns false to leave it as it is.
+func updatePantry(ctx context.Context, store PantryStore, userID string, change func(Pantry) (Pantry, bool)) (Pantry, bool, error) {
+	for attempt := 0; ; attempt++ {
+		pantry, err := store.Pantry(ctx, userID)
+		if err != nil {
+			return Pantry{}, false, err
+		}
+		changed, ok := change(pantry)
+		if !ok {
+			return pantry, false, nil
+		}
+		changed.Version = pantry.Version
+		err = store.SavePantry(ctx, userID, changed)
+		if errors.Is(err, ErrPantryChanged) && attempt < pantryRetries {
+			continue
+		}
+		return pantry, err == nil, err
+	}
+}
+
+// Below this much of an ingredient, it has run out
+const pantryEpsilon = 1e-6
+
+// Takes the ingredients of a recipe out of the pantry, converting units, and
+// adds those that run out to the shopping list. Each ingredient is taken from
+// the first of the pantry's entries for it whose unit it converts to, so one
+// kept as both grams and cups is only taken once. Ingredients the pantry
+// doesn't have are ignored; those whose amounts can't be converted to the
+// unit of any entry are left as they are and returned.
+func (pantry Pantry) Use(recipe Recipe) (Pantry, []string, []string) {
+	used := Pantry{
+		ShoppingList: append([]string(nil), pantry.ShoppingList...),
+		LastCooked:   &CookedRecipe{Recipe: recipe.Name, Items: pantry.Items, ShoppingList: pantry.ShoppingList},
+	}
+	left := make([]float64, len(pantry.Items))
+	taken := make([]bool, len(pantry.Items))
+	for i, item := range pantry.Items {
+		left[i] = item.Amount
+	}
+	var ranOut, unconverted []string
+	for _, quantity := range recipe.Quantities {
+		have, converted := false, false
+		for i, item := range pantry.Items {
+			if normalizeIngredient(item.Ingredient) != normalizeIngredient(quantity.Ingredient) {
+				continue
+			}
+			have = true
+			if amount, ok := ConvertAmount(quantity.Amount, quantity.Unit, item.Unit); ok {
+				left[i] -= amount
+				taken[i], converted = true, true
+				break
+			}
+		}
+		if have && !converted && !containsFold(unconverted, quantity.Ingredient) {
+			unconverted = append(unconverted, quantity.Ingredient)
+		}
+	}
+	for i, item := range pantry.Items {
+		if !taken[i] || left[i] > pantryEpsilon {
+			item.Amount = left[i]
+			used.Items = append(used.Items, item)
+			continue
+		}
+		ranOut = append(ranOut, item.Ingredient)
+		if !containsFold(used.ShoppingList, item.Ingredient) {
+			used.ShoppingList = append(used.ShoppingList, item.Ingredient)
+		}
+	}
+	return used, ranOut, unconverted
+}
+
+// The pantry as it was before the last recipe was taken out of it, and
+// whether there was one
+func (pantry Pantry) Undo() (Pantry, bool) {
+	if pantry.LastCooked == nil {
+		return pantry, false
+	}
+	return Pantry{Items: pantry.LastCooked.Items, ShoppingList: pantry.LastCooked.ShoppingList}, true
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if normalizeIngredient(item) == normalizeIngredient(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Answers "I made the lasagna" by taking its ingredients out of the pantry
+func (connection Connection) madeRecipe(input *skill.HandlerInput) error {
+	recipeName := input.Request.Body.SlotValue("recipe")
+	if recipeName == "" {
+		return errors.New("Recipe name is not present in the request")
+	}
+	if connection.pantry == nil {
+		return Answer(input, "Pantry", input.Messages.Format("pantryUnavailable"), nil)
+	}
+	recipe, err := connection.store.FindByName(input.Context, recipeName)
+	if err != nil && !errors.Is(err, ErrDegraded) {
+		return err
+	}
+	text, pantryErr := connection.takeFromPantry(input, recipe)
+	if pantryErr != nil {
+		return pantryErr
+	}
+	return Answer(input, "Pantry", text, err)
+}
+
+// Takes a recipe cooked from start to finish out of the pantry, saying what
+// was done, or nothing when there's no pantry to take it from
+func (connection Connection) finishedCooking(input *skill.HandlerInput, recipe Recipe) string {
+	if connection.pantry == nil || len(recipe.Quantities) == 0 {
+		return ""
+	}
+	text, err := connection.takeFromPantry(input, recipe)
+	if err != nil {
+		log.Printf("unable to take %s out of the pantry: %v", recipe.Name, err)
+		return ""
+	}
+	return " " + text
+}
+
+func (connection Connection) takeFromPantry(input *skill.HandlerInput, recipe Recipe) (string, error) {
+	if len(recipe.Quantities) == 0 {
+		return input.Messages.Format("noQuantities", recipe.Name), nil
+	}
+	var ranOut, unconverted []string
+	_, _, err := updatePantry(input.Context, connection.pantry, input.UserID(), func(pantry Pantry) (Pantry, bool) {
+		var used Pantry
+		used, ranOut, unconverted = pantry.Use(recipe)
+		return used, true
+	})
+	if err != nil {
+		return "", err
+	}
+	text := input.Messages.Format("tookFromPantry", recipe.Name)
+	if len(ranOut) > 0 {
+		text += input.Messages.Format("ranOut", JoinWithAnd(ranOut))
+	}
+	if len(unconverted) > 0 {
+		text += input.Messages.Format("unconverted", JoinWithAnd(unconverted))
+	}
+	return text + input.Messages.Format("undoPrompt"), nil
+}
+
+// Answers "undo that" by putting back the last recipe taken out of the pantry
+func (connection Connection) undoCooked(input *skill.HandlerInput) error {
+	if connection.pantry == nil {
+		return Answer(input, "Pantry", input.Messages.Format("pantryUnavailable"), nil)
+	}
+	pantry, undone, err := updatePantry(input.Context, connection.pantry, input.UserID(), Pantry.Undo)
+	if err != nil {
+		return err
+	}
+	if !undone {
+		return Answer(input, "Pantry", input.Messages.Format("nothingToUndo"), nil)
+	}
+	return Answer(input, "Pantry", input.Messages.Format("undidCooked", pantry.LastCooked.Recipe), nil)
+}
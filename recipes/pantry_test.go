@@ -0,0 +1,84 @@
+package recipes
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestUseTakesFromOneEntry(t *testing.T) {
+	pantry := Pantry{Items: []PantryItem{
+		{Ingredient: "flour", Amount: 2, Unit: "cup"},
+		{Ingredient: "Flour", Amount: 500, Unit: "g"},
+		{Ingredient: "onion", Amount: 1},
+		{Ingredient: "onion", Amount: 3},
+		{Ingredient: "milk", Amount: 1, Unit: "l"},
+	}}
+	used, ranOut, unconverted := pantry.Use(Recipe{Name: "Pancakes", Quantities: []Quantity{
+		{Ingredient: "flour", Amount: 250, Unit: "g"},
+		{Ingredient: "onion", Amount: 1},
+		{Ingredient: "milk", Amount: 200, Unit: "g"},
+		{Ingredient: "salt", Amount: 1, Unit: "tsp"},
+	}})
+	want := []PantryItem{
+		{Ingredient: "flour", Amount: 2, Unit: "cup"},
+		{Ingredient: "Flour", Amount: 250, Unit: "g"},
+		{Ingredient: "onion", Amount: 3},
+		{Ingredient: "milk", Amount: 1, Unit: "l"},
+	}
+	if !reflect.DeepEqual(used.Items, want) {
+		t.Errorf("got items %+v, want %+v", used.Items, want)
+	}
+	if !reflect.DeepEqual(ranOut, []string{"onion"}) || !reflect.DeepEqual(used.ShoppingList, []string{"onion"}) {
+		t.Errorf("got %v run out and shopping list %v, want only the first onion entry used up", ranOut, used.ShoppingList)
+	}
+	if !reflect.DeepEqual(unconverted, []string{"milk"}) {
+		t.Errorf("got %v unconverted, want milk", unconverted)
+	}
+}
+
+// A PantryStore keeping pantries in memory, which another request saves to
+// the first conflicts times it is read
+type memoryPantries struct {
+	pantries  map[string]Pantry
+	conflicts int
+}
+
+func (store *memoryPantries) Pantry(ctx context.Context, userID string) (Pantry, error) {
+	pantry := store.pantries[userID]
+	if store.conflicts > 0 {
+		store.conflicts--
+		store.pantries[userID] = Pantry{Items: append(pantry.Items, PantryItem{Ingredient: "egg", Amount: 6}), Version: pantry.Version + 1}
+	}
+	return pantry, nil
+}
+
+func (store *memoryPantries) SavePantry(ctx context.Context, userID string, pantry Pantry) error {
+	if store.pantries[userID].Version != pantry.Version {
+		return ErrPantryChanged
+	}
+	pantry.Version++
+	store.pantries[userID] = pantry
+	return nil
+}
+
+func TestUpdatePantryRereadsAfterConflicts(t *testing.T) {
+	store := &memoryPantries{pantries: map[string]Pantry{"user": {Items: []PantryItem{{Ingredient: "egg", Amount: 2}}}}, conflicts: 2}
+	useEgg := func(pantry Pantry) (Pantry, bool) {
+		used, _, _ := pantry.Use(Recipe{Quantities: []Quantity{{Ingredient: "egg", Amount: 1}}})
+		return used, true
+	}
+	if _, _, err := updatePantry(context.Background(), store, "user", useEgg); err != nil {
+		t.Fatal(err)
+	}
+	// Both eggs added by the other requests are kept
+	want := []PantryItem{{Ingredient: "egg", Amount: 1}, {Ingredient: "egg", Amount: 6}, {Ingredient: "egg", Amount: 6}}
+	if got := store.pantries["user"].Items; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	store.conflicts = pantryRetries + 1
+	if _, _, err := updatePantry(context.Background(), store, "user", useEgg); err != ErrPantryChanged {
+		t.Errorf("got %v, want ErrPantryChanged once the retries are used up", err)
+	}
+}
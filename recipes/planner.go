@@ -0,0 +1,399 @@
+package recipes
+
+import (
+	"errors"
+	"log"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mongodb-developer/alexa-golang-example/skill"
+)
+
+// Returned by GeneratePlan when some day has no recipe meeting the constraints
+var ErrNothingToPlan = errors.New("no recipes meet the constraints for every day")
+
+// The days a generated plan covers, in order
+var planDays = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+
+// The days whose dinners have to fit in the weeknight time limit
+var weeknights = map[string]bool{"Monday": true, "Tuesday": true, "Wednesday": true, "Thursday": true, "Friday": true}
+
+// What a generated plan has to satisfy, and what it should make the most of
+type PlanConstraints struct {
+	Days []string
+	// Tags every recipe must have, such as "vegetarian"
+	Diets  []string
+	People int
+	// The most the whole plan may cost, or zero for no budget. Recipes without
+	// a complete cost estimate are left out when there is one.
+	Budget float64
+	// The longest a recipe may take on a weeknight, or zero for no limit.
+	// Recipes that don't say how long they take are left out when there is one.
+	WeeknightMinutes int
+	Weeknights       map[string]bool
+	// Whether a recipe serving twice the people can also be the next day's dinner
+	Leftovers bool
+	// The ingredients the user has at home
+	Pantry map[string]bool
+	// The cost per serving of the recipes with complete estimates, by name
+	Costs map[string]float64
+	// Seeds the search, so a plan can be repeated
+	Seed int64
+}
+
+// A day of a generated plan
+type PlannedMeal struct {
+	Day    string
+	Recipe Recipe
+	// Set when the day eats the previous day's leftovers instead of cooking
+	Leftovers bool
+	// The ingredients of the recipe the user has at home
+	FromPantry []string
+}
+
+// A plan made by GeneratePlan
+type GeneratedPlan struct {
+	Meals []PlannedMeal
+	// What the meals with a cost estimate come to, for all the people
+	Cost float64
+	// Set when the budget couldn't be met
+	OverBudget bool
+	// Set when no plan found kept the same cuisine from being cooked two days
+	// in a row
+	RepeatsCuisine bool
+}
+
+// How much each quality of a plan counts towards its score, lower being better
+const (
+	repeatPenalty    = 15.0
+	cuisinePenalty   = 20.0
+	budgetPenalty    = 100.0
+	pantryReward     = 2.0
+	leftoversReward  = 3.0
+	planSearchRounds = 4000
+)
+
+// Generates a plan by simulated annealing: starting from a random plan, it
+// repeatedly changes one day's recipe, turns a day over to the previous day's
+// leftovers or swaps two days, keeping changes that make the plan better and,
+// less and less often as the search cools, some that make it worse, to get
+// out of local optima. Diets, the weeknight time limit and the budget's need
+// for costs are met by every plan considered; going over budget, repeating a
+// recipe and having a cuisine twice in a row count against a plan, and using
+// the pantry and eating leftovers count for it. The plan returned says when
+// it goes over budget or repeats a cuisine, since the search can't always
+// avoid it.
+func GeneratePlan(recipes []Recipe, constraints PlanConstraints) (GeneratedPlan, error) {
+	if constraints.People <= 0 {
+		constraints.People = 1
+	}
+	var candidates []Recipe
+	for _, recipe := range recipes {
+		if hasTags(recipe, constraints.Diets) {
+			if _, costed := constraints.Costs[recipe.Name]; costed || constraints.Budget == 0 {
+				candidates = append(candidates, recipe)
+			}
+		}
+	}
+	// The candidates each day may cook
+	eligible := make([][]int, len(constraints.Days))
+	for i, day := range constraints.Days {
+		for c, recipe := range candidates {
+			limit := constraints.WeeknightMinutes
+			if !constraints.Weeknights[day] || limit == 0 || (recipe.Minutes > 0 && recipe.Minutes <= limit) {
+				eligible[i] = append(eligible[i], c)
+			}
+		}
+		if len(eligible[i]) == 0 {
+			return GeneratedPlan{}, ErrNothingToPlan
+		}
+	}
+	allowed := make([]map[int]bool, len(eligible))
+	for i, choices := range eligible {
+		allowed[i] = make(map[int]bool, len(choices))
+		for _, c := range choices {
+			allowed[i][c] = true
+		}
+	}
+
+	// A plan is a candidate for each day, or leftoverDay to eat the previous day's
+	const leftoverDay = -1
+	recipeOn := func(plan []int, day int) int {
+		if plan[day] == leftoverDay {
+			return plan[day-1]
+		}
+		return plan[day]
+	}
+	valid := func(plan []int) bool {
+		for day, choice := range plan {
+			if choice != leftoverDay {
+				if !allowed[day][choice] {
+					return false
+				}
+				continue
+			}
+			if !constraints.Leftovers || day == 0 || plan[day-1] == leftoverDay ||
+				candidates[plan[day-1]].Servings < 2*constraints.People {
+				return false
+			}
+		}
+		return true
+	}
+	cost := func(plan []int) float64 {
+		total := 0.0
+		for day := range plan {
+			total += constraints.Costs[candidates[recipeOn(plan, day)].Name] * float64(constraints.People)
+		}
+		return total
+	}
+	score := func(plan []int) float64 {
+		total := 0.0
+		cooked := make(map[int]bool)
+		for day, choice := range plan {
+			if choice == leftoverDay {
+				total -= leftoversReward
+				continue
+			}
+			if cooked[choice] {
+				total += repeatPenalty
+			}
+			cooked[choice] = true
+			cuisine := candidates[choice].Cuisine
+			if day > 0 && cuisine != "" && strings.EqualFold(cuisine, candidates[recipeOn(plan, day-1)].Cuisine) {
+				total += cuisinePenalty
+			}
+			total -= pantryReward * float64(len(fromPantry(candidates[choice], constraints.Pantry)))
+		}
+		spent := cost(plan)
+		if constraints.Budget > 0 && spent > constraints.Budget {
+			total += budgetPenalty * (1 + (spent-constraints.Budget)/constraints.Budget)
+		}
+		// Between otherwise equal plans, the cheaper one
+		return total + spent/1000
+	}
+
+	random := rand.New(rand.NewSource(constraints.Seed))
+	plan := make([]int, len(constraints.Days))
+	for day := range plan {
+		plan[day] = eligible[day][random.Intn(len(eligible[day]))]
+	}
+	current := score(plan)
+	best, bestScore := append([]int(nil), plan...), current
+	next := make([]int, len(plan))
+	for round := 0; round < planSearchRounds; round++ {
+		copy(next, plan)
+		day := random.Intn(len(next))
+		switch move := random.Intn(4); {
+		case move == 0:
+			next[day] = leftoverDay
+		case move == 1:
+			other := random.Intn(len(next))
+			next[day], next[other] = next[other], next[day]
+		default:
+			next[day] = eligible[day][random.Intn(len(eligible[day]))]
+		}
+		if !valid(next) {
+			continue
+		}
+		temperature := 10 * (1 - float64(round)/planSearchRounds)
+		nextScore := score(next)
+		if nextScore <= current || random.Float64() < math.Exp((current-nextScore)/temperature) {
+			plan, next = next, plan
+			current = nextScore
+			if current < bestScore {
+				best, bestScore = append(best[:0], plan...), current
+			}
+		}
+	}
+
+	generated := GeneratedPlan{Cost: cost(best)}
+	generated.OverBudget = constraints.Budget > 0 && generated.Cost > constraints.Budget
+	for day, choice := range best {
+		recipe := candidates[recipeOn(best, day)]
+		meal := PlannedMeal{Day: constraints.Days[day], Recipe: recipe, Leftovers: choice == leftoverDay}
+		if !meal.Leftovers {
+			meal.FromPantry = fromPantry(recipe, constraints.Pantry)
+		}
+		generated.Meals = append(generated.Meals, meal)
+	}
+	generated.RepeatsCuisine = repeatsCuisine(generated.Meals)
+	return generated, nil
+}
+
+// Reports whether a day cooks the same cuisine as the day before. Eating
+// leftovers doesn't count as cooking it again.
+func repeatsCuisine(meals []PlannedMeal) bool {
+	for day := 1; day < len(meals); day++ {
+		cuisine := meals[day].Recipe.Cuisine
+		if !meals[day].Leftovers && cuisine != "" && strings.EqualFold(cuisine, meals[day-1].Recipe.Cuisine) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether a recipe has every one of the tags
+func hasTags(recipe Recipe, tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, has := range recipe.Tags {
+			if strings.EqualFold(has, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// The ingredients of a recipe the user has at home
+func fromPantry(recipe Recipe, pantry map[string]bool) []string {
+	var found []string
+	for _, ingredient := range recipe.Ingredients {
+		if pantry[normalizeIngredient(ingredient)] {
+			found = append(found, ingredient)
+		}
+	}
+	return found
+}
+
+// Answers "plan my dinners for next week", optionally for a diet, a number
+// of people, a budget for the week and a weeknight time limit, and saves the
+// plan with the user's meal plans
+func (connection Connection) generateMealPlan(input *skill.HandlerInput) error {
+	if connection.plans == nil {
+		return Answer(input, "Meal Plan", input.Messages.Format("plannerUnavailable"), nil)
+	}
+	userID := input.UserID()
+	constraints := PlanConstraints{
+		Days:       planDays,
+		Weeknights: weeknights,
+		People:     2,
+		Leftovers:  true,
+		Pantry:     make(map[string]bool),
+		Costs:      make(map[string]float64),
+		Seed:       time.Now().UnixNano(),
+	}
+	if input.Request.Body.SlotValue("diet") != "" {
+		constraints.Diets = []string{tagOf(input.Request.Body.Intent.Slots["diet"])}
+	}
+	if people, err := strconv.Atoi(input.Request.Body.SlotValue("people")); err == nil && people > 0 {
+		constraints.People = people
+	}
+	if budget, err := strconv.ParseFloat(input.Request.Body.SlotValue("budget"), 64); err == nil && budget > 0 {
+		constraints.Budget = budget
+	}
+	if minutes, err := strconv.Atoi(input.Request.Body.SlotValue("minutes")); err == nil && minutes > 0 {
+		constraints.WeeknightMinutes = minutes
+	}
+	if constraints.Budget > 0 && connection.prices == nil {
+		return Answer(input, "Meal Plan", input.Messages.Format("pricesUnavailable"), nil)
+	}
+
+	found, err := connection.store.Query(input.Context, RecipeQuery{Tag: "dinner"})
+	if err != nil && !errors.Is(err, ErrDegraded) {
+		return err
+	}
+	if connection.pantry != nil {
+		pantry, pantryErr := connection.pantry.Pantry(input.Context, userID)
+		if pantryErr != nil {
+			log.Printf("unable to read the pantry: %v", pantryErr)
+		}
+		for _, item := range pantry.Items {
+			constraints.Pantry[normalizeIngredient(item.Ingredient)] = true
+		}
+	}
+	currency := ""
+	if connection.prices != nil {
+		prices, priceErr := connection.prices.Prices(input.Context, regionOf(input.Locale), pricedIngredients(found...))
+		if priceErr != nil && constraints.Budget > 0 {
+			return priceErr
+		} else if priceErr != nil {
+			log.Printf("unable to read prices: %v", priceErr)
+		}
+		for _, recipe := range found {
+			estimate := EstimateCost(recipe, prices)
+			if estimate.Currency != "" && len(estimate.Missing) == 0 && estimate.PerServing > 0 {
+				constraints.Costs[recipe.Name] = estimate.PerServing
+				currency = estimate.Currency
+			}
+		}
+	}
+
+	generated, planErr := GeneratePlan(found, constraints)
+	if planErr == ErrNothingToPlan {
+		return Answer(input, "Meal Plan", input.Messages.Format("nothingToPlan"), err)
+	} else if planErr != nil {
+		return planErr
+	}
+	plan := MealPlan{UserID: userID, Name: input.Messages.Format("generatedPlanName")}
+	var spoken, card []string
+	for i, meal := range generated.Meals {
+		plan.Days = append(plan.Days, MealPlanDay{Day: meal.Day, Recipes: []string{meal.Recipe.Name}})
+		if meal.Leftovers {
+			spoken = append(spoken, input.Messages.Format("plannedLeftovers", meal.Day, meal.Recipe.Name))
+		} else {
+			spoken = append(spoken, input.Messages.Format("plannedMeal", meal.Day, meal.Recipe.Name))
+		}
+		var previous *PlannedMeal
+		if i > 0 {
+			previous = &generated.Meals[i-1]
+		}
+		line := input.Messages.Format("plannedMealCard", meal.Day, meal.Recipe.Name)
+		if reasons := planReasons(input, meal, previous, constraints, currency); len(reasons) > 0 {
+			line += input.Messages.Format("plannedMealReasons", strings.Join(reasons, ", "))
+		}
+		card = append(card, line)
+	}
+	// Planning again replaces the last generated plan rather than adding another
+	if _, saveErr := connection.plans.SaveMealPlanByName(input.Context, plan); saveErr != nil {
+		return saveErr
+	}
+
+	text := input.Messages.Format("generatedPlan", JoinWithAnd(spoken))
+	if generated.OverBudget {
+		text += input.Messages.Format("planOverBudget", money(input, generated.Cost, currency), money(input, constraints.Budget, currency))
+	} else if currency != "" && len(constraints.Costs) > 0 {
+		text += input.Messages.Format("planCost", money(input, generated.Cost, currency))
+	}
+	if generated.RepeatsCuisine {
+		text += input.Messages.Format("planRepeatsCuisine")
+	}
+	text += input.Messages.Format("planSaved")
+	if errors.Is(err, ErrDegraded) {
+		text = input.Messages.Format("degraded") + text
+	}
+	input.Response.Speak(text).SimpleCard("Meal Plan", strings.Join(card, "\n"))
+	return nil
+}
+
+// Why a meal was chosen, as shown on the card
+func planReasons(input *skill.HandlerInput, meal PlannedMeal, previous *PlannedMeal, constraints PlanConstraints, currency string) []string {
+	if meal.Leftovers {
+		return []string{input.Messages.Format("reasonLeftovers", previous.Day)}
+	}
+	var reasons []string
+	for _, diet := range constraints.Diets {
+		reasons = append(reasons, input.Messages.Format("reasonDiet", diet))
+	}
+	if len(meal.FromPantry) > 0 {
+		reasons = append(reasons, input.Messages.Format("reasonPantry", JoinWithAnd(meal.FromPantry)))
+	}
+	if constraints.WeeknightMinutes > 0 && constraints.Weeknights[meal.Day] {
+		reasons = append(reasons, input.Messages.Format("reasonQuick", meal.Recipe.Minutes))
+	}
+	if cost, ok := constraints.Costs[meal.Recipe.Name]; ok && constraints.Budget > 0 {
+		reasons = append(reasons, input.Messages.Format("reasonCost", money(input, cost, currency)))
+	}
+	if previous != nil && meal.Recipe.Cuisine != "" && previous.Recipe.Cuisine != "" &&
+		!strings.EqualFold(meal.Recipe.Cuisine, previous.Recipe.Cuisine) {
+		reasons = append(reasons, input.Messages.Format("reasonVariety", capitalize(previous.Recipe.Cuisine)))
+	}
+	return reasons
+}
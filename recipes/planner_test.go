@@ -0,0 +1,180 @@
+package recipes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// A dinner serving two, of a cuisine
+func dinner(name string, cuisine string) Recipe {
+	return Recipe{Name: name, Cuisine: cuisine, Servings: 2, Tags: []string{"dinner"}}
+}
+
+var seeds = []int64{1, 2, 3, 42, 1234}
+
+func TestGeneratePlanKeepsToBudget(t *testing.T) {
+	recipes := []Recipe{dinner("Steak", "french"), dinner("Rice and Beans", "mexican"), dinner("Dal", "indian"), dinner("Pasta", "italian"), dinner("Omelette", "french")}
+	costs := map[string]float64{"Steak": 12, "Rice and Beans": 1, "Dal": 1.5, "Pasta": 2, "Omelette": 2.5}
+	for _, seed := range seeds {
+		plan, err := GeneratePlan(recipes, PlanConstraints{Days: planDays, People: 2, Budget: 40, Costs: costs, Seed: seed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		spent := 0.0
+		for _, meal := range plan.Meals {
+			spent += costs[meal.Recipe.Name] * 2
+		}
+		if plan.OverBudget || plan.Cost > 40 || plan.Cost != spent {
+			t.Errorf("seed %d: got a cost of %v, over budget %v, want at most 40", seed, plan.Cost, plan.OverBudget)
+		}
+
+		plan, err = GeneratePlan(recipes, PlanConstraints{Days: planDays, People: 2, Budget: 5, Costs: costs, Seed: seed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !plan.OverBudget || len(plan.Meals) != len(planDays) {
+			t.Errorf("seed %d: got %d meals over budget %v, want a week over budget", seed, len(plan.Meals), plan.OverBudget)
+		}
+	}
+
+	// Without a cost, a recipe can't be part of a budgeted plan
+	if _, err := GeneratePlan([]Recipe{dinner("Steak", "french")}, PlanConstraints{Days: planDays, Budget: 40, Seed: 1}); err != ErrNothingToPlan {
+		t.Errorf("got %v, want ErrNothingToPlan", err)
+	}
+}
+
+func TestGeneratePlanVariesCuisine(t *testing.T) {
+	recipes := []Recipe{
+		dinner("Lasagne", "italian"), dinner("Risotto", "italian"), dinner("Pizza", "Italian"),
+		dinner("Tacos", "mexican"), dinner("Enchiladas", "mexican"), dinner("Pad Thai", "thai"), dinner("Curry", "thai"),
+	}
+	for _, seed := range seeds {
+		plan, err := GeneratePlan(recipes, PlanConstraints{Days: planDays, People: 2, Seed: seed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if plan.RepeatsCuisine {
+			t.Errorf("seed %d: the plan repeats a cuisine although it could vary it", seed)
+		}
+		for day := 1; day < len(plan.Meals); day++ {
+			if strings.EqualFold(plan.Meals[day].Recipe.Cuisine, plan.Meals[day-1].Recipe.Cuisine) {
+				t.Errorf("seed %d: %s and %s are both %s", seed, plan.Meals[day-1].Day, plan.Meals[day].Day, plan.Meals[day].Recipe.Cuisine)
+			}
+		}
+	}
+
+	plan, err := GeneratePlan(recipes[:3], PlanConstraints{Days: planDays, People: 2, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.RepeatsCuisine {
+		t.Error("a week of Italian recipes doesn't say it repeats the cuisine")
+	}
+}
+
+func TestGeneratePlanCarriesLeftovers(t *testing.T) {
+	chili, soup := dinner("Chili", "mexican"), dinner("Soup", "french")
+	chili.Servings, soup.Servings = 4, 4
+	recipes := []Recipe{chili, soup, dinner("Salad", "greek")}
+	for _, seed := range seeds {
+		plan, err := GeneratePlan(recipes, PlanConstraints{Days: planDays, People: 2, Leftovers: true, Seed: seed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		leftovers := 0
+		for day, meal := range plan.Meals {
+			if !meal.Leftovers {
+				continue
+			}
+			leftovers++
+			if day == 0 || plan.Meals[day-1].Leftovers || plan.Meals[day-1].Recipe.Name != meal.Recipe.Name || meal.Recipe.Servings < 4 {
+				t.Errorf("seed %d: %s eats leftover %s that weren't cooked the day before", seed, meal.Day, meal.Recipe.Name)
+			}
+		}
+		if leftovers == 0 {
+			t.Errorf("seed %d: no day eats leftovers", seed)
+		}
+
+		// Chili and soup serve only as many as are eating
+		plan, err = GeneratePlan(recipes, PlanConstraints{Days: planDays, People: 4, Leftovers: true, Seed: seed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, meal := range plan.Meals {
+			if meal.Leftovers {
+				t.Errorf("seed %d: %s eats leftovers when there are none", seed, meal.Day)
+			}
+		}
+	}
+}
+
+func TestGeneratePlanMeetsDietAndTime(t *testing.T) {
+	quick, slow, meat := dinner("Stir Fry", "chinese"), dinner("Stew", "irish"), dinner("Roast", "british")
+	quick.Minutes, slow.Minutes, meat.Minutes = 20, 120, 15
+	quick.Tags = append(quick.Tags, "vegetarian")
+	slow.Tags = append(slow.Tags, "vegetarian")
+	plan, err := GeneratePlan([]Recipe{quick, slow, meat}, PlanConstraints{
+		Days: planDays, Weeknights: weeknights, Diets: []string{"Vegetarian"}, WeeknightMinutes: 30, People: 2, Seed: 7,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, meal := range plan.Meals {
+		if meal.Recipe.Name == "Roast" || (weeknights[meal.Day] && meal.Recipe.Name != "Stir Fry") {
+			t.Errorf("got %s on %s", meal.Recipe.Name, meal.Day)
+		}
+	}
+}
+
+// A MealPlanStore keeping plans in memory
+type memoryPlans struct {
+	plans []MealPlan
+}
+
+func (store *memoryPlans) MealPlans(ctx context.Context, userID string) ([]MealPlan, error) {
+	var found []MealPlan
+	for _, plan := range store.plans {
+		if plan.UserID == userID {
+			found = append(found, plan)
+		}
+	}
+	return found, nil
+}
+
+func (store *memoryPlans) SaveMealPlan(ctx context.Context, plan MealPlan) (MealPlan, error) {
+	plan.ID = primitive.NewObjectID()
+	store.plans = append(store.plans, plan)
+	return plan, nil
+}
+
+func (store *memoryPlans) SaveMealPlanByName(ctx context.Context, plan MealPlan) (MealPlan, error) {
+	for i, saved := range store.plans {
+		if saved.UserID == plan.UserID && saved.Name == plan.Name {
+			plan.ID = saved.ID
+			store.plans[i] = plan
+			return plan, nil
+		}
+	}
+	return store.SaveMealPlan(ctx, plan)
+}
+
+func (store *memoryPlans) DeleteMealPlan(ctx context.Context, userID string, id primitive.ObjectID) (bool, error) {
+	return false, nil
+}
+
+func TestGenerateMealPlanReplacesTheLastOne(t *testing.T) {
+	plans := &memoryPlans{}
+	recipes := &stubStore{recipes: []Recipe{dinner("Tacos", "mexican"), dinner("Pad Thai", "thai"), dinner("Lasagne", "italian")}}
+	hosted := NewSkillFrom(Sources{Recipes: recipes, Plans: plans})
+	for i := 0; i < 2; i++ {
+		if _, err := hosted.Invoke(context.Background(), intentRequest("GenerateMealPlanIntent", nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(plans.plans) != 1 || plans.plans[0].Name != messages["en"]["generatedPlanName"] || len(plans.plans[0].Days) != len(planDays) {
+		t.Errorf("got %+v, want one generated plan", plans.plans)
+	}
+}
@@ -0,0 +1,73 @@
+package recipes
+
+import (
+	"strings"
+
+	"github.com/mongodb-developer/alexa-golang-example/skill"
+)
+
+// Answers "be more brief", "give me more detail" and "use normal answers".
+// "briefer" and "more detailed" move one step from the current verbosity.
+func setVerbosity(input *skill.HandlerInput) error {
+	requested := strings.ToLower(input.Request.Body.Intent.Slots["verbosity"].ResolvedValue())
+	preferences := input.Preferences
+	current := 0
+	for i, verbosity := range skill.Verbosities {
+		if verbosity == preferences.Verbosity {
+			current = i
+		}
+	}
+	switch requested {
+	case "briefer":
+		if current > 0 {
+			current--
+		}
+		preferences.Verbosity = skill.Verbosities[current]
+	case "more detailed":
+		if current < len(skill.Verbosities)-1 {
+			current++
+		}
+		preferences.Verbosity = skill.Verbosities[current]
+	case string(skill.Brief), string(skill.Normal), string(skill.Detailed):
+		preferences.Verbosity = skill.Verbosity(requested)
+	default:
+		return Answer(input, "Preferences", input.Messages.Format("verbosityHelp"), nil)
+	}
+	if err := input.SetPreferences(preferences); err != nil {
+		return err
+	}
+	return Answer(input, "Preferences", input.Messages.Format("verbositySet."+string(preferences.Verbosity)), nil)
+}
+
+// Answers "speak slower", "speak faster" and "speak at normal speed"
+func setSpeechRate(input *skill.HandlerInput) error {
+	requested := strings.ToLower(input.Request.Body.Intent.Slots["rate"].ResolvedValue())
+	preferences := input.Preferences
+	current := 0
+	for i, rate := range skill.SpeechRates {
+		if rate == preferences.SpeechRate {
+			current = i
+		}
+	}
+	switch requested {
+	case "slower":
+		if current == 0 {
+			return Answer(input, "Preferences", input.Messages.Format("slowest"), nil)
+		}
+		preferences.SpeechRate = skill.SpeechRates[current-1]
+	case "faster":
+		if current == len(skill.SpeechRates)-1 {
+			return Answer(input, "Preferences", input.Messages.Format("fastest"), nil)
+		}
+		preferences.SpeechRate = skill.SpeechRates[current+1]
+	case "normal":
+		preferences.SpeechRate = skill.DefaultSpeechRate
+	default:
+		return Answer(input, "Preferences", input.Messages.Format("rateHelp"), nil)
+	}
+	if err := input.SetPreferences(preferences); err != nil {
+		return err
+	}
+	// Spoken at the new rate, so the user hears the difference
+	return Answer(input, "Preferences", input.Messages.Format("rateSet"), nil)
+}
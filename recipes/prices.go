@@ -0,0 +1,301 @@
+package recipes
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mongodb-developer/alexa-golang-example/mongodb"
+	"github.com/mongodb-developer/alexa-golang-example/skill"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// What an ingredient costs in a region, such as 3.49 USD per lb of beef in the US
+type Price struct {
+	Ingredient string `bson:"ingredient" json:"ingredient"`
+	// The country part of a locale, such as "US" or "GB"
+	Region   string  `bson:"region" json:"region"`
+	Unit     string  `bson:"unit" json:"unit"`
+	Price    float64 `bson:"price" json:"price"`
+	Currency string  `bson:"currency" json:"currency"`
+}
+
+// Describes where ingredient prices come from
+type PriceStore interface {
+	// The prices of the ingredients in a region, by ingredient as given.
+	// Ingredients are matched ignoring case and spacing.
+	Prices(ctx context.Context, region string, ingredients []string) (map[string]Price, error)
+}
+
+// A PriceStore keeping one document per ingredient and region, with the
+// ingredient normalized
+type MongoPriceStore struct {
+	collection *mongodb.Collection
+}
+
+func NewMongoPriceStore(collection *mongodb.Collection) *MongoPriceStore {
+	return &MongoPriceStore{collection: collection}
+}
+
+func (store *MongoPriceStore) Prices(ctx context.Context, region string, ingredients []string) (map[string]Price, error) {
+	normalized := make([]string, len(ingredients))
+	for i, ingredient := range ingredients {
+		normalized[i] = normalizeIngredient(ingredient)
+	}
+	cursor, err := store.collection.Find(ctx, bson.M{"region": region, "ingredient": bson.M{"$in": normalized}})
+	if err != nil {
+		return nil, err
+	}
+	var prices []Price
+	if err = cursor.All(ctx, &prices); err != nil {
+		return nil, err
+	}
+	found := make(map[string]Price, len(prices))
+	for _, price := range prices {
+		found[price.Ingredient] = price
+	}
+	byIngredient := make(map[string]Price, len(prices))
+	for i, ingredient := range ingredients {
+		if price, ok := found[normalized[i]]; ok {
+			byIngredient[ingredient] = price
+		}
+	}
+	return byIngredient, nil
+}
+
+// Adds or replaces prices read from CSV with the header
+//
+//	ingredient,region,unit,price,currency
+//
+// returning how many were imported. Nothing is imported if any row is invalid.
+// The rows are written unordered, so when some writes fail the others are
+// still made, and the count returned with the error says how many were.
+func (store *MongoPriceStore) Import(ctx context.Context, reader io.Reader) (int, error) {
+	prices, err := readPrices(reader)
+	if err != nil || len(prices) == 0 {
+		return 0, err
+	}
+	var models []mongo.WriteModel
+	for _, price := range prices {
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"ingredient": price.Ingredient, "region": price.Region}).
+			SetReplacement(price).
+			SetUpsert(true))
+	}
+	result, err := store.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	var failed mongo.BulkWriteException
+	if errors.As(err, &failed) {
+		return len(models) - len(failed.WriteErrors), err
+	} else if err != nil {
+		return 0, err
+	}
+	return int(result.UpsertedCount + result.MatchedCount), nil
+}
+
+// Reads and checks the prices in a CSV file for Import
+func readPrices(reader io.Reader) ([]Price, error) {
+	rows, err := csv.NewReader(reader).ReadAll()
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"ingredient", "region", "unit", "price", "currency"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+	var prices []Price
+	for line, row := range rows[1:] {
+		price := Price{
+			Ingredient: normalizeIngredient(row[columns["ingredient"]]),
+			Region:     strings.ToUpper(strings.TrimSpace(row[columns["region"]])),
+			Unit:       strings.TrimSpace(row[columns["unit"]]),
+			Currency:   strings.ToUpper(strings.TrimSpace(row[columns["currency"]])),
+		}
+		if price.Price, err = strconv.ParseFloat(strings.TrimSpace(row[columns["price"]]), 64); err != nil {
+			return nil, fmt.Errorf("line %d: invalid price: %v", line+2, err)
+		}
+		if _, ok := lookupUnit(price.Unit); !ok {
+			return nil, fmt.Errorf("line %d: unknown unit %q", line+2, price.Unit)
+		}
+		if price.Ingredient == "" || price.Region == "" || price.Currency == "" {
+			return nil, fmt.Errorf("line %d: ingredient, region and currency are required", line+2)
+		}
+		prices = append(prices, price)
+	}
+	return prices, nil
+}
+
+// The estimated cost of a recipe
+type CostEstimate struct {
+	Total float64
+	// Zero when the recipe doesn't say how many it serves
+	PerServing float64
+	Currency   string
+	// Ingredients left out of the estimate, because they have no price or
+	// their amount can't be converted to the unit they are priced in
+	Missing []string
+}
+
+// Estimates what a recipe costs to make from the prices of its ingredients
+func EstimateCost(recipe Recipe, prices map[string]Price) CostEstimate {
+	var estimate CostEstimate
+	for _, quantity := range recipe.Quantities {
+		price, ok := prices[quantity.Ingredient]
+		if ok && estimate.Currency != "" && price.Currency != estimate.Currency {
+			ok = false
+		}
+		var amount float64
+		if ok {
+			amount, ok = ConvertAmount(quantity.Amount, quantity.Unit, price.Unit)
+		}
+		if !ok {
+			estimate.Missing = append(estimate.Missing, quantity.Ingredient)
+			continue
+		}
+		estimate.Currency = price.Currency
+		estimate.Total += amount * price.Price
+	}
+	if recipe.Servings > 0 {
+		estimate.PerServing = estimate.Total / float64(recipe.Servings)
+	}
+	return estimate
+}
+
+// The ingredients of recipes that have quantities, for looking up their prices
+func pricedIngredients(recipes ...Recipe) []string {
+	seen := make(map[string]bool)
+	var ingredients []string
+	for _, recipe := range recipes {
+		for _, quantity := range recipe.Quantities {
+			if !seen[quantity.Ingredient] {
+				seen[quantity.Ingredient] = true
+				ingredients = append(ingredients, quantity.Ingredient)
+			}
+		}
+	}
+	return ingredients
+}
+
+// The region prices are looked up in for a locale, such as "US" for "en-US"
+func regionOf(locale string) string {
+	if parts := strings.SplitN(locale, "-", 2); len(parts) == 2 {
+		return strings.ToUpper(parts[1])
+	}
+	return "US"
+}
+
+// Speaks an amount of money in the currency it was priced in
+func money(input *skill.HandlerInput, amount float64, currency string) string {
+	if _, ok := input.Messages["currency"+currency]; ok {
+		return input.Messages.Format("currency"+currency, amount)
+	}
+	return strings.TrimSpace(fmt.Sprintf("%.2f %s", amount, currency))
+}
+
+// Answers "how much does it cost to make chili"
+func (connection Connection) recipeCost(input *skill.HandlerInput) error {
+	recipeName := input.Request.Body.SlotValue("recipe")
+	if recipeName == "" {
+		return errors.New("Recipe name is not present in the request")
+	}
+	if connection.prices == nil {
+		return Answer(input, "Cost", input.Messages.Format("pricesUnavailable"), nil)
+	}
+	recipe, err := connection.store.FindByName(input.Context, recipeName)
+	if err != nil && !errors.Is(err, ErrDegraded) {
+		return err
+	}
+	prices, priceErr := connection.prices.Prices(input.Context, regionOf(input.Locale), pricedIngredients(recipe))
+	if priceErr != nil {
+		return priceErr
+	}
+	estimate := EstimateCost(recipe, prices)
+	if estimate.Currency == "" {
+		return Answer(input, "Cost", input.Messages.Format("noPrices", recipe.Name), err)
+	}
+	total := money(input, estimate.Total, estimate.Currency)
+	text := input.Messages.Format("cost", capitalize(recipe.Name), total)
+	if estimate.PerServing > 0 {
+		text = input.Messages.Format("costPerServing", capitalize(recipe.Name), total, money(input, estimate.PerServing, estimate.Currency))
+	}
+	if len(estimate.Missing) > 0 {
+		text += input.Messages.Format("costMissing", JoinWithAnd(estimate.Missing))
+	}
+	return Answer(input, "Cost", text, err)
+}
+
+// Answers "cheap dinners under five dollars", comparing the cost per serving
+// of recipes with the tag, cheapest first. Recipes missing a price are left
+// out, since their estimates are too low, and so are those that don't say
+// how many they serve.
+func (connection Connection) cheapRecipes(input *skill.HandlerInput) error {
+	limit, err := strconv.ParseFloat(input.Request.Body.SlotValue("amount"), 64)
+	if err != nil || limit <= 0 {
+		return errors.New("Amount is not present in the request")
+	}
+	tag := "dinner"
+	if input.Request.Body.SlotValue("tag") != "" {
+		tag = tagOf(input.Request.Body.Intent.Slots["tag"])
+	}
+	if connection.prices == nil {
+		return Answer(input, "Cost", input.Messages.Format("pricesUnavailable"), nil)
+	}
+	tagged, err := connection.store.Query(input.Context, RecipeQuery{Tag: tag})
+	if err != nil && !errors.Is(err, ErrDegraded) {
+		return err
+	}
+	prices, priceErr := connection.prices.Prices(input.Context, regionOf(input.Locale), pricedIngredients(tagged...))
+	if priceErr != nil {
+		return priceErr
+	}
+	type cheap struct {
+		name string
+		cost float64
+	}
+	var found []cheap
+	currency := ""
+	for _, recipe := range tagged {
+		estimate := EstimateCost(recipe, prices)
+		if estimate.Currency != "" {
+			currency = estimate.Currency
+		}
+		if estimate.Currency == "" || len(estimate.Missing) > 0 || recipe.Servings == 0 || estimate.PerServing > limit {
+			continue
+		}
+		found = append(found, cheap{name: recipe.Name, cost: estimate.PerServing})
+	}
+	if len(found) == 0 {
+		if currency == "" {
+			// No recipe could be priced, so speak the limit in the currency of
+			// the region's prices
+			for _, price := range prices {
+				currency = price.Currency
+				break
+			}
+		}
+		return Answer(input, "Cost", input.Messages.Format("noCheapRecipes", tag, money(input, limit, currency)), err)
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].cost < found[j].cost })
+	if len(found) > maxCheapRecipes {
+		found = found[:maxCheapRecipes]
+	}
+	var listed []string
+	for _, recipe := range found {
+		listed = append(listed, input.Messages.Format("cheapRecipe", recipe.name, money(input, recipe.cost, currency)))
+	}
+	return Answer(input, "Cost", input.Messages.Format("cheapRecipes", money(input, limit, currency), JoinWithAnd(listed)), err)
+}
+
+// The most recipes read out by cheapRecipes
+const maxCheapRecipes = 5
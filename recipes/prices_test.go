@@ -0,0 +1,125 @@
+package recipes
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEstimateCost(t *testing.T) {
+	prices := map[string]Price{
+		"ground beef":  {Ingredient: "ground beef", Unit: "lb", Price: 5, Currency: "USD"},
+		"kidney beans": {Ingredient: "kidney beans", Unit: "can", Price: 1.5, Currency: "USD"},
+		"garlic":       {Ingredient: "garlic", Unit: "clove", Price: 0.25, Currency: "USD"},
+		"onion":        {Ingredient: "onion", Unit: "each", Price: 0.8, Currency: "USD"},
+		"milk":         {Ingredient: "milk", Unit: "l", Price: 1.2, Currency: "GBP"},
+	}
+	tests := []struct {
+		name       string
+		recipe     Recipe
+		total      float64
+		perServing float64
+		missing    []string
+	}{
+		{
+			name: "converted",
+			recipe: Recipe{Servings: 4, Quantities: []Quantity{
+				{Ingredient: "ground beef", Amount: 2, Unit: "lb"},
+				{Ingredient: "kidney beans", Amount: 2, Unit: "cans"},
+				{Ingredient: "garlic", Amount: 4, Unit: "cloves"},
+				{Ingredient: "onion", Amount: 1},
+			}},
+			total:      14.8,
+			perServing: 3.7,
+		},
+		{
+			name: "without servings",
+			recipe: Recipe{Quantities: []Quantity{
+				{Ingredient: "ground beef", Amount: 16, Unit: "oz"},
+			}},
+			total: 5,
+		},
+		{
+			name: "missing",
+			recipe: Recipe{Servings: 2, Quantities: []Quantity{
+				{Ingredient: "ground beef", Amount: 1, Unit: "lb"},
+				{Ingredient: "onion", Amount: 100, Unit: "g"},
+				{Ingredient: "salt", Amount: 1, Unit: "pinch"},
+				{Ingredient: "milk", Amount: 1, Unit: "l"},
+			}},
+			total:      5,
+			perServing: 2.5,
+			missing:    []string{"onion", "salt", "milk"},
+		},
+	}
+	for _, test := range tests {
+		estimate := EstimateCost(test.recipe, prices)
+		if math.Abs(estimate.Total-test.total) > 1e-9 || math.Abs(estimate.PerServing-test.perServing) > 1e-9 ||
+			estimate.Currency != "USD" || !reflect.DeepEqual(estimate.Missing, test.missing) {
+			t.Errorf("%s: got %+v, want a total of %v, %v a serving and %v missing", test.name, estimate, test.total, test.perServing, test.missing)
+		}
+	}
+}
+
+func TestReadPrices(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want []Price
+		err  string
+	}{
+		{
+			name: "normalized",
+			csv:  "Ingredient, Region ,unit,price,currency\n  Ground  Beef ,us,lb,5.49,usd\nKidney Beans,US,cans,0.99,USD\n",
+			want: []Price{
+				{Ingredient: "ground beef", Region: "US", Unit: "lb", Price: 5.49, Currency: "USD"},
+				{Ingredient: "kidney beans", Region: "US", Unit: "cans", Price: 0.99, Currency: "USD"},
+			},
+		},
+		{name: "empty", csv: ""},
+		{name: "missing column", csv: "ingredient,region,unit,price\nonion,US,each,0.8\n", err: `missing column "currency"`},
+		{name: "invalid price", csv: "ingredient,region,unit,price,currency\nonion,US,each,cheap,USD\n", err: "line 2: invalid price"},
+		{name: "unknown unit", csv: "ingredient,region,unit,price,currency\nonion,US,each,0.8,USD\nthyme,US,sprig,0.1,USD\n", err: `line 3: unknown unit "sprig"`},
+		{name: "required", csv: "ingredient,region,unit,price,currency\n ,US,each,0.8,USD\n", err: "line 2: ingredient, region and currency are required"},
+	}
+	for _, test := range tests {
+		prices, err := readPrices(strings.NewReader(test.csv))
+		if test.err != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+				t.Errorf("%s: got %v, want an error starting %q", test.name, err, test.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(prices, test.want) {
+			t.Errorf("%s: got %+v, %v, want %+v", test.name, prices, err, test.want)
+		}
+	}
+}
+
+// A PriceStore with fixed prices
+type stubPrices map[string]Price
+
+func (prices stubPrices) Prices(ctx context.Context, region string, ingredients []string) (map[string]Price, error) {
+	return prices, nil
+}
+
+func TestCheapRecipesSkipsRecipesWithoutServings(t *testing.T) {
+	recipes := &stubStore{recipes: []Recipe{
+		{Name: "Chili", Servings: 4, Quantities: []Quantity{{Ingredient: "ground beef", Amount: 2, Unit: "lb"}}},
+		// Five dollars in all, but for how many isn't known
+		{Name: "Burgers", Quantities: []Quantity{{Ingredient: "ground beef", Amount: 1, Unit: "lb"}}},
+	}}
+	prices := stubPrices{"ground beef": {Ingredient: "ground beef", Unit: "lb", Price: 5, Currency: "USD"}}
+	hosted := NewSkillFrom(Sources{Recipes: recipes, Prices: prices})
+
+	response, err := hosted.Invoke(context.Background(), intentRequest("GetCheapRecipesIntent", map[string]string{"amount": "6"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Under $6.00 a serving, you could make Chili at $2.50."
+	if speech := response.Body.OutputSpeech; speech == nil || speech.Text != want {
+		t.Errorf("got %+v, want %q", speech, want)
+	}
+}
@@ -0,0 +1,83 @@
+package recipes
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// How long after a failed read it is tried again, when that is sooner than the TTL
+const refreshRetry = 30 * time.Second
+
+// A value read whole from the database and kept for a TTL, such as the
+// glossary. The read happens without the lock, so while one caller refreshes
+// a stale value the others are given the previous copy rather than queueing
+// behind it. A failed read isn't tried again for refreshRetry, so an outage
+// costs one database timeout rather than one per request.
+type refresher struct {
+	name string
+	ttl  time.Duration
+	load func(ctx context.Context) (interface{}, error)
+
+	mutex sync.Mutex
+	value interface{}
+	err   error
+	// When the value, or the error, is read again
+	expires time.Time
+	// Closed when the read in progress finishes
+	loading chan struct{}
+}
+
+func newRefresher(name string, ttl time.Duration, load func(ctx context.Context) (interface{}, error)) *refresher {
+	return &refresher{name: name, ttl: ttl, load: load}
+}
+
+// The value, the previous copy when it can't be read again, or the error
+// from the last read when there has never been one
+func (cache *refresher) get(ctx context.Context) (interface{}, error) {
+	cache.mutex.Lock()
+	if !time.Now().Before(cache.expires) && cache.loading == nil {
+		loading := make(chan struct{})
+		cache.loading = loading
+		cache.mutex.Unlock()
+		value, err := cache.load(ctx)
+		cache.mutex.Lock()
+		cache.loaded(ctx, value, err)
+		cache.loading = nil
+		close(loading)
+	}
+	value, err, loading := cache.value, cache.err, cache.loading
+	cache.mutex.Unlock()
+	if value != nil {
+		return value, nil
+	}
+	if loading != nil {
+		// Nothing has been read yet, so wait for the first read
+		select {
+		case <-loading:
+			return cache.get(ctx)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return nil, err
+}
+
+// Records a read, with the mutex held
+func (cache *refresher) loaded(ctx context.Context, value interface{}, err error) {
+	if err == nil {
+		cache.value, cache.err, cache.expires = value, nil, time.Now().Add(cache.ttl)
+		return
+	}
+	if ctx.Err() != nil {
+		// The caller gave up, which says nothing about the database
+		return
+	}
+	log.Printf("unable to read the %s, trying again in %s: %v", cache.name, refreshRetry, err)
+	retry := refreshRetry
+	if cache.ttl < retry {
+		retry = cache.ttl
+	}
+	cache.err, cache.expires = err, time.Now().Add(retry)
+}
@@ -0,0 +1,308 @@
+package recipes
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Returned, wrapped in a DegradedError, when a lookup was answered from the
+// last-known-good cache instead of the database
+var ErrDegraded = errors.New("recipe store is degraded, serving cached results")
+
+// Returned when the circuit breaker is open and there is nothing cached to answer with
+var ErrCircuitOpen = errors.New("recipe store circuit breaker is open")
+
+// Wraps the error that caused a lookup to be served from the cache
+type DegradedError struct {
+	Cause error
+}
+
+func (err *DegradedError) Error() string {
+	return ErrDegraded.Error() + ": " + err.Cause.Error()
+}
+
+func (err *DegradedError) Is(target error) bool {
+	return target == ErrDegraded
+}
+
+func (err *DegradedError) Unwrap() error {
+	return err.Cause
+}
+
+// Metrics for the recipe store, published through expvar under "recipe_store"
+var storeMetrics = expvar.NewMap("recipe_store")
+
+// Server error codes the driver treats as transient
+var retryableCodes = map[int32]bool{
+	6: true, 7: true, 89: true, 91: true, 189: true, 262: true, 9001: true,
+	10107: true, 11600: true, 11602: true, 13435: true, 13436: true,
+}
+
+// Reports whether an error from the driver is worth retrying: network errors,
+// timeouts including server selection, and server errors with a transient
+// code or label
+func IsRetryable(err error) bool {
+	if err == nil || err == mongo.ErrNoDocuments || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if mongo.IsNetworkError(err) || mongo.IsTimeout(err) {
+		return true
+	}
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) {
+		return retryableCodes[commandErr.Code] ||
+			commandErr.HasErrorLabel("RetryableWriteError") ||
+			commandErr.HasErrorLabel("TransientTransactionError")
+	}
+	return false
+}
+
+// Controls how many times and how quickly a failed lookup is retried
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Full jitter backoff: a random delay between zero and the capped exponential delay
+func (policy RetryPolicy) backoff(attempt int) time.Duration {
+	delay := policy.BaseDelay << uint(attempt)
+	if delay <= 0 || delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay)))
+}
+
+const (
+	circuitClosed = "closed"
+	circuitOpen   = "open"
+	circuitHalf   = "half-open"
+)
+
+// Opens after Threshold consecutive failures and lets a single trial request
+// through once Cooldown has passed
+type CircuitBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mutex    sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	// Whether the single trial request let through while half-open is still out
+	trial bool
+}
+
+func (breaker *CircuitBreaker) allow() bool {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+	switch breaker.state {
+	case circuitOpen:
+		if time.Since(breaker.openedAt) < breaker.Cooldown {
+			return false
+		}
+		breaker.transition(circuitHalf)
+		breaker.trial = true
+		return true
+	case circuitHalf:
+		if breaker.trial {
+			return false
+		}
+		breaker.trial = true
+		return true
+	}
+	return true
+}
+
+func (breaker *CircuitBreaker) success() {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+	breaker.failures = 0
+	breaker.trial = false
+	if breaker.state != "" && breaker.state != circuitClosed {
+		breaker.transition(circuitClosed)
+	}
+}
+
+func (breaker *CircuitBreaker) failure() {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+	breaker.failures++
+	breaker.trial = false
+	if breaker.state == circuitHalf || breaker.failures >= breaker.Threshold {
+		breaker.openedAt = time.Now()
+		if breaker.state != circuitOpen {
+			breaker.transition(circuitOpen)
+		}
+	}
+}
+
+// Gives up a request the caller cancelled, which says nothing about the
+// database, so a trial it was lets another through rather than counting
+func (breaker *CircuitBreaker) abandon() {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+	breaker.trial = false
+}
+
+func (breaker *CircuitBreaker) transition(state string) {
+	log.Printf("recipe store circuit breaker %s", state)
+	breaker.state = state
+	storeMetrics.Add("breaker_"+strings.Replace(state, "-", "_", -1), 1)
+}
+
+// A RecipeStore decorator adding classified retries, a circuit breaker and a
+// last-known-good cache that is used while the database is unavailable, and
+// after it an offline store such as a snapshot
+type ResilientStore struct {
+	store   RecipeStore
+	policy  RetryPolicy
+	breaker *CircuitBreaker
+	cache   *resultCache
+	offline RecipeStore
+}
+
+func NewResilientStore(store RecipeStore, policy RetryPolicy, breaker *CircuitBreaker) *ResilientStore {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	return &ResilientStore{
+		store:   store,
+		policy:  policy,
+		breaker: breaker,
+		cache:   newResultCache(defaultCacheSize, defaultCacheTTL),
+	}
+}
+
+// Keeps the results of at most size lookups, each for at most ttl, to answer
+// with while the database is unavailable
+func (store *ResilientStore) WithCache(size int, ttl time.Duration) *ResilientStore {
+	store.cache = newResultCache(size, ttl)
+	return store
+}
+
+// Answers lookups that aren't cached from offline, such as a SnapshotStore,
+// while the database is unavailable
+func (store *ResilientStore) WithFallback(offline RecipeStore) *ResilientStore {
+	store.offline = offline
+	return store
+}
+
+func (store *ResilientStore) FindByName(ctx context.Context, name string) (Recipe, error) {
+	recipes, err := store.do(ctx, "name:"+name, func(ctx context.Context, source RecipeStore) ([]Recipe, error) {
+		recipe, err := source.FindByName(ctx, name)
+		if err != nil && !errors.Is(err, ErrDegraded) {
+			return nil, err
+		}
+		return []Recipe{recipe}, err
+	})
+	if len(recipes) == 0 {
+		return Recipe{}, err
+	}
+	return recipes[0], err
+}
+
+func (store *ResilientStore) FindByNames(ctx context.Context, names []string) ([]Recipe, error) {
+	return store.do(ctx, "names:"+strings.Join(names, "\x00"), func(ctx context.Context, source RecipeStore) ([]Recipe, error) {
+		return source.FindByNames(ctx, names)
+	})
+}
+
+func (store *ResilientStore) FindByIngredients(ctx context.Context, ingredients []string) ([]Recipe, error) {
+	return store.do(ctx, "ingredients:"+strings.Join(ingredients, "\x00"), func(ctx context.Context, source RecipeStore) ([]Recipe, error) {
+		return source.FindByIngredients(ctx, ingredients)
+	})
+}
+
+func (store *ResilientStore) Query(ctx context.Context, query RecipeQuery) ([]Recipe, error) {
+	return store.do(ctx, "query:"+query.key(), func(ctx context.Context, source RecipeStore) ([]Recipe, error) {
+		return source.Query(ctx, query)
+	})
+}
+
+// Makes the lookup against the database, or against the offline store when
+// the database is unavailable and the lookup isn't cached
+func (store *ResilientStore) do(ctx context.Context, key string, lookup func(context.Context, RecipeStore) ([]Recipe, error)) ([]Recipe, error) {
+	storeMetrics.Add("calls", 1)
+	if !store.breaker.allow() {
+		storeMetrics.Add("rejected", 1)
+		return store.fallback(ctx, key, lookup, ErrCircuitOpen)
+	}
+	// Counts the lookup as failed if it panics, so a trial request doesn't
+	// leave the breaker half-open
+	settled := false
+	defer func() {
+		if !settled {
+			storeMetrics.Add("failures", 1)
+			store.breaker.failure()
+		}
+	}()
+	var err error
+	for attempt := 0; attempt < store.policy.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			storeMetrics.Add("retries", 1)
+			select {
+			case <-time.After(store.policy.backoff(attempt - 1)):
+			case <-ctx.Done():
+				settled = true
+				store.breaker.abandon()
+				return nil, ctx.Err()
+			}
+		}
+		var recipes []Recipe
+		recipes, err = lookup(ctx, store.store)
+		switch {
+		case err == nil:
+			settled = true
+			store.breaker.success()
+			store.remember(key, recipes)
+			return recipes, nil
+		case ctx.Err() != nil:
+			// The caller gave up, which isn't the database's failure
+			settled = true
+			store.breaker.abandon()
+			return nil, err
+		case !IsRetryable(err):
+			// The database answered, so it is healthy even if the lookup failed
+			settled = true
+			store.breaker.success()
+			return nil, err
+		}
+	}
+	settled = true
+	storeMetrics.Add("failures", 1)
+	store.breaker.failure()
+	return store.fallback(ctx, key, lookup, err)
+}
+
+func (store *ResilientStore) remember(key string, recipes []Recipe) {
+	store.cache.put(key, recipes)
+}
+
+func (store *ResilientStore) fallback(ctx context.Context, key string, lookup func(context.Context, RecipeStore) ([]Recipe, error), cause error) ([]Recipe, error) {
+	if recipes, ok := store.cache.get(key); ok {
+		storeMetrics.Add("cache_hits", 1)
+		return recipes, &DegradedError{Cause: cause}
+	}
+	if store.offline == nil {
+		return nil, cause
+	}
+	recipes, err := lookup(ctx, store.offline)
+	if !errors.Is(err, ErrDegraded) {
+		// The offline store doesn't have it either
+		return nil, cause
+	}
+	storeMetrics.Add("offline_hits", 1)
+	return recipes, err
+}
@@ -0,0 +1,189 @@
+package recipes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+// A RecipeStore answering every lookup with recipes and err
+type stubStore struct {
+	recipes []Recipe
+	err     error
+	calls   int
+}
+
+func (store *stubStore) FindByName(ctx context.Context, name string) (Recipe, error) {
+	store.calls++
+	if store.err != nil || len(store.recipes) == 0 {
+		return Recipe{}, store.err
+	}
+	return store.recipes[0], nil
+}
+
+func (store *stubStore) FindByNames(ctx context.Context, names []string) ([]Recipe, error) {
+	store.calls++
+	return store.recipes, store.err
+}
+
+func (store *stubStore) FindByIngredients(ctx context.Context, ingredients []string) ([]Recipe, error) {
+	store.calls++
+	return store.recipes, store.err
+}
+
+func (store *stubStore) Query(ctx context.Context, query RecipeQuery) ([]Recipe, error) {
+	store.calls++
+	return store.recipes, store.err
+}
+
+var networkError = mongo.CommandError{Message: "connection(test) closed", Labels: []string{"NetworkError"}}
+
+func TestResilientStoreRecoversFromCancelledTrial(t *testing.T) {
+	database := &stubStore{err: networkError}
+	breaker := &CircuitBreaker{Threshold: 1, Cooldown: 10 * time.Millisecond}
+	opener := NewResilientStore(database, RetryPolicy{MaxAttempts: 1}, breaker)
+	if _, err := opener.FindByNames(context.Background(), []string{"Pancakes"}); err == nil {
+		t.Fatal("the lookup succeeded against a failing database")
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// The half-open trial is cancelled while it waits to retry
+	store := NewResilientStore(database, RetryPolicy{MaxAttempts: 2, BaseDelay: time.Hour, MaxDelay: time.Hour}, breaker)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := store.FindByNames(ctx, []string{"Pancakes"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	database.err, database.recipes = nil, []Recipe{{Name: "Pancakes"}}
+	found, err := store.FindByNames(context.Background(), []string{"Pancakes"})
+	if err != nil || len(found) != 1 {
+		t.Fatalf("after the cooldown got %v, %v, want the recipe from the database", found, err)
+	}
+}
+
+func TestResilientStoreServesCacheWhileOpen(t *testing.T) {
+	database := &stubStore{recipes: []Recipe{{Name: "Pancakes"}}}
+	store := NewResilientStore(database, RetryPolicy{MaxAttempts: 1}, &CircuitBreaker{Threshold: 1, Cooldown: time.Hour})
+	ctx := context.Background()
+	if _, err := store.FindByNames(ctx, []string{"Pancakes"}); err != nil {
+		t.Fatal(err)
+	}
+
+	database.err = networkError
+	found, err := store.FindByNames(ctx, []string{"Pancakes"})
+	if !errors.Is(err, ErrDegraded) || len(found) != 1 {
+		t.Fatalf("got %v, %v, want the cached recipe and ErrDegraded", found, err)
+	}
+	calls := database.calls
+	if _, err := store.FindByNames(ctx, []string{"Waffles"}); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("got %v, want ErrCircuitOpen", err)
+	}
+	if database.calls != calls {
+		t.Fatalf("the database was called while the breaker was open")
+	}
+}
+
+func TestResultCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newResultCache(2, time.Hour)
+	cache.put("a", []Recipe{{Name: "A"}})
+	cache.put("b", []Recipe{{Name: "B"}})
+	cache.get("a")
+	cache.put("c", []Recipe{{Name: "C"}})
+	if _, ok := cache.get("b"); ok {
+		t.Error("b is still cached after being evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.get(key); !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+}
+
+func TestResultCacheExpires(t *testing.T) {
+	cache := newResultCache(10, 10*time.Millisecond)
+	cache.put("a", []Recipe{{Name: "A"}})
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := cache.get("a"); ok {
+		t.Error("a is still cached after its TTL")
+	}
+	if len(cache.entries) != 0 || cache.order.Len() != 0 {
+		t.Error("the expired result was not removed")
+	}
+}
+
+func TestResilientStoreFallsBackToSnapshot(t *testing.T) {
+	database := &stubStore{err: networkError}
+	snapshot := &SnapshotStore{recipes: []Recipe{{Name: "Pancakes"}}, byName: map[string]Recipe{"Pancakes": {Name: "Pancakes"}}}
+	store := NewResilientStore(database, RetryPolicy{MaxAttempts: 1}, &CircuitBreaker{Threshold: 5, Cooldown: time.Hour}).WithFallback(snapshot)
+	ctx := context.Background()
+
+	recipe, err := store.FindByName(ctx, "Pancakes")
+	if !errors.Is(err, ErrOffline) || recipe.Name != "Pancakes" {
+		t.Fatalf("got %v, %v, want the recipe from the snapshot and ErrOffline", recipe, err)
+	}
+	if _, err := store.FindByName(ctx, "Waffles"); !IsRetryable(err) {
+		t.Fatalf("got %v for a recipe missing from the snapshot, want the database error", err)
+	}
+
+	database.err, database.recipes = nil, []Recipe{{Name: "Pancakes", Servings: 4}}
+	recipe, err = store.FindByName(ctx, "Pancakes")
+	if err != nil || recipe.Servings != 4 {
+		t.Fatalf("got %v, %v, want the recipe from the database once it recovers", recipe, err)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{networkError, true},
+		{fmt.Errorf("server selection error: %w", topology.ErrServerSelectionTimeout), true},
+		{context.DeadlineExceeded, true},
+		{mongo.CommandError{Code: 50, Name: "MaxTimeMSExpired"}, true},
+		{mongo.CommandError{Code: 10107, Name: "NotWritablePrimary"}, true},
+		{mongo.CommandError{Code: 112, Labels: []string{"TransientTransactionError"}}, true},
+		{mongo.CommandError{Code: 18, Name: "AuthenticationFailed"}, false},
+		{mongo.ErrNoDocuments, false},
+		{context.Canceled, false},
+		// Errors are told apart by type and label, not by what they say
+		{errors.New("connection(localhost:27017) server selection failed: i/o timeout"), false},
+	}
+	for _, test := range tests {
+		if got := IsRetryable(test.err); got != test.want {
+			t.Errorf("IsRetryable(%v) = %v, want %v", test.err, got, test.want)
+		}
+	}
+}
+
+func TestResilientStoreDoesNotCountCancelledLookups(t *testing.T) {
+	database := &stubStore{err: networkError}
+	breaker := &CircuitBreaker{Threshold: 1, Cooldown: time.Hour}
+	store := NewResilientStore(database, RetryPolicy{MaxAttempts: 2, BaseDelay: time.Hour, MaxDelay: time.Hour}, breaker)
+
+	// Cancelled while waiting to retry
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := store.FindByNames(ctx, []string{"Pancakes"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	// Cancelled during the lookup
+	cancelled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	database.err = context.Canceled
+	if _, err := store.FindByNames(cancelled, []string{"Pancakes"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+
+	database.err, database.recipes = nil, []Recipe{{Name: "Pancakes"}}
+	if found, err := store.FindByNames(context.Background(), []string{"Pancakes"}); err != nil || len(found) != 1 {
+		t.Fatalf("got %v, %v, want the breaker still closed and the recipe from the database", found, err)
+	}
+}
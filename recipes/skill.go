@@ -0,0 +1,507 @@
+package recipes
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/mongodb-developer/alexa-golang-example/alexa"
+	"github.com/mongodb-developer/alexa-golang-example/linking"
+	"github.com/mongodb-developer/alexa-golang-example/skill"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Stores a handle to the recipe store being used by the skill's handlers
+type Connection struct {
+	store    RecipeStore
+	glossary GlossaryStore
+	prices   PriceStore
+	taxonomy TaxonomyStore
+	plans    MealPlanStore
+	goals    NutritionGoalStore
+	pantry   PantryStore
+}
+
+// The data the skill is built on. Only Recipes is required; the intents
+// backed by a missing source say that it isn't available.
+type Sources struct {
+	Recipes  RecipeStore
+	Glossary GlossaryStore
+	Prices   PriceStore
+	Taxonomy TaxonomyStore
+	Plans    MealPlanStore
+	Goals    NutritionGoalStore
+	Pantry   PantryStore
+	// How to say the recipe names and ingredients Alexa gets wrong
+	Lexicon *skill.Lexicon
+	// Resolves linked accounts, so meal plans, goals and the pantry are kept
+	// under the user the web and mobile apps know
+	Accounts linking.Authenticator
+}
+
+// The phrases the skill speaks, by locale
+var messages = skill.Catalog{
+	"en": {
+		// Spoken ahead of any answer that was served from the last-known-good cache
+		"degraded": "I'm having trouble reaching the recipe database, so this answer may be out of date. ",
+		"about":    "Created by Nic Raboy in Tracy, CA",
+		"unknown":  "The intent was unrecognized",
+		"apology":  "Sorry, I had trouble with that request. Please try again.",
+		"notFound": "I couldn't find a recipe called %s.",
+		"relink":   "Please link your Amazon account again in the Alexa app, so I can find your meal plans and pantry.",
+		"noSteps":  "I don't have the steps for %s yet, only its ingredients.",
+		"step":     "Step %d of %d. %s",
+		"finished": "That was the last step. Enjoy your %s!",
+		// For a recipe the request doesn't name
+		"recipeNotFound": "I couldn't find that recipe.",
+		// An ingredient and the recipes using it, when listing several recipes' ingredients
+		"ingredientFor":      "%s for %s",
+		"recipesMissing":     "I couldn't find %s",
+		"ingredientsMissing": "%s. I couldn't find %s",
+		// Reprompts while cooking
+		"cookingPrompt":       "Say next when you're ready for the next step.",
+		"termPrompt":          "Say next when you're ready, or ask what's that to hear what %s means.",
+		"unknownTerm":         "Sorry, I don't know the term %s yet.",
+		"noStepTerms":         "Ask me about a cooking term, for example, what does fold mean?",
+		"glossaryUnavailable": "The cooking glossary isn't available right now.",
+		// Spoken when the database can't be reached and nothing is cached
+		"unavailable": "I can't reach the recipe database right now. Please try again in a minute.",
+		// Temperatures are given in Celsius and Fahrenheit, and each locale picks one
+		"temperature":         "%[2]d degrees Celsius",
+		"fridge":              "fridge",
+		"safeTemperature":     "The safe internal temperature for %s is %s.",
+		"safeTemperatureRest": "The safe internal temperature for %s is %s, followed by a %d minute rest.",
+		"storage":             "%s can be kept for %s in the %s, and %s in the freezer.",
+		"freezer":             "%s can be kept for %s in the freezer.",
+		"noFreezing":          "%s can be kept for %s in the %s, but freezing isn't recommended.",
+		"unknownFood":         "Sorry, I don't have food safety information for %s.",
+		"doneness":            " Cook the %s to %s inside.",
+		"cost":                "%s costs about %s to make.",
+		"costPerServing":      "%s costs about %s to make, or %s a serving.",
+		"costMissing":         " That doesn't include %s, which I don't have prices for.",
+		"noPrices":            "I don't have prices for the ingredients of %s yet.",
+		"pricesUnavailable":   "I don't have any ingredient prices right now.",
+		"cheapRecipe":         "%s at %s",
+		"cheapRecipes":        "Under %s a serving, you could make %s.",
+		"noCheapRecipes":      "I couldn't find a %s under %s a serving.",
+		"nutritionResult":     "%s with %s",
+		"nutritionResults":    "Try %s.",
+		"noNutritionResults":  "I couldn't find any recipes like that.",
+		"nutritionHelp":       "Tell me what you're after, for example, high protein dinners under 600 calories.",
+		"goalHelp":            "Tell me the nutrient and amount, for example, set my daily calorie goal to 2000.",
+		"goalsUnavailable":    "I can't keep track of nutrition goals right now.",
+		"goalSet":             "Your daily goal is now %s.",
+		"nothingPlanned":      "You don't have anything planned for %s.",
+		"planned":             "You've planned %s for %s.",
+		"goalComparison":      " That's %s.",
+		"goalUnder":           "%s under your goal",
+		"goalOver":            "%s over your goal",
+		"goalMet":             "right on your %s goal",
+		"uncounted":           " That doesn't count %s, which I don't have nutrition for.",
+		"plannerUnavailable":  "I can't plan meals right now.",
+		"nothingToPlan":       "I couldn't find enough recipes to plan that week. Try asking with fewer limits.",
+		"generatedPlanName":   "Dinners for next week",
+		"generatedPlan":       "Here are your dinners for next week: %s.",
+		"plannedMeal":         "%[2]s on %[1]s",
+		"plannedLeftovers":    "leftover %[2]s on %[1]s",
+		"planCost":            " That comes to about %s.",
+		"planOverBudget":      " That comes to about %s. I couldn't keep it under %s.",
+		"planRepeatsCuisine":  " I couldn't avoid having the same cuisine two nights in a row.",
+		"planSaved":           " I've saved it with your meal plans.",
+		"plannedMealCard":     "%s: %s",
+		"plannedMealReasons":  " (%s)",
+		"reasonLeftovers":     "leftovers from %s",
+		"reasonDiet":          "%s",
+		"reasonPantry":        "uses the %s you have",
+		"reasonQuick":         "ready in %d minutes",
+		"reasonCost":          "%s a serving",
+		"reasonVariety":       "a change from %s",
+		"pantryUnavailable":   "I can't keep track of your pantry right now.",
+		"noQuantities":        "I don't know how much of each ingredient %s uses, so I've left your pantry as it was.",
+		"tookFromPantry":      "I've taken what you used for %s out of your pantry.",
+		"ranOut":              " I've added %s to your shopping list, since you've run out.",
+		"unconverted":         " I couldn't work out how much of your %s that used, so that's unchanged.",
+		"undoPrompt":          " If you didn't make it, say undo that.",
+		"nothingToUndo":       "There's nothing to undo in your pantry.",
+		"undidCooked":         "OK, I've put back what you used for %s.",
+		"amountOf":            "%s %s of %s",
+		"countOf":             "%s %s",
+		"prepIngredients":     "For %d portions of %s, you'll need %s.",
+		"prepTask":            "%s %s for %s",
+		"prepTasks":           " Before you start, %s.",
+		"prepContainers":      " Then divide each recipe between %d containers.",
+		"prepShoppingCard":    "You'll need:\n%s",
+		"prepTasksCard":       "Before you start:\n%s",
+		"prepUnmeasured":      " I don't know how much %s needs, so I've only listed its ingredients.",
+		"prepUnscaled":        " I don't know how many %s serves, so that's for one batch.",
+		"prepMissing":         " I couldn't find %s.",
+		"prepNotFound":        "I couldn't find %s.",
+		"withPrep":            "%s (%s)",
+		// Changing how the skill speaks
+		"verbosityHelp":         "You can ask me to be more brief or to give you more detail.",
+		"verbositySet.brief":    "OK, I'll keep it short.",
+		"verbositySet.normal":   "OK, I'll go back to my usual answers.",
+		"verbositySet.detailed": "OK, I'll give you more detail.",
+		"rateHelp":              "You can ask me to speak slower or faster.",
+		"rateSet":               "OK, how's this?",
+		"slowest":               "I'm already speaking as slowly as I can.",
+		"fastest":               "I'm already speaking as fast as I can.",
+		// Shorter versions for users who want brief answers
+		"degraded.brief":            "This may be out of date. ",
+		"cookingPrompt.brief":       "Say next when you're ready.",
+		"undoPrompt.brief":          "",
+		"planSaved.brief":           "",
+		"notFound.brief":            "I couldn't find %s.",
+		"ingredientFor.brief":       "%[1]s",
+		"unavailable.brief":         "I can't reach the recipes right now.",
+		"relink.brief":              "Please link your Amazon account again in the Alexa app.",
+		"noSteps.brief":             "I only have the ingredients for %s.",
+		"finished.brief":            "Done. Enjoy your %s!",
+		"termPrompt.brief":          "Say next, or ask what %s means.",
+		"safeTemperature.brief":     "Cook %s to %s.",
+		"safeTemperatureRest.brief": "Cook %s to %s, then rest it %d minutes.",
+		"doneness.brief":            " Cook the %s to %s.",
+		"costMissing.brief":         " That leaves out %s.",
+		"uncounted.brief":           " That leaves out %s.",
+		"nutritionHelp.brief":       "Tell me what you're after, like high protein.",
+		"goalSet.brief":             "OK, %s a day.",
+		"tookFromPantry.brief":      "I've updated your pantry for %s.",
+		"ranOut.brief":              " I've added %s to your shopping list.",
+		"unconverted.brief":         " I've left your %s as it was.",
+		"undidCooked.brief":         "OK, I've put back what %s used.",
+		"generatedPlan.brief":       "Next week: %s.",
+		"prepIngredients.brief":     "For %d portions of %s: %s.",
+		// Amounts of each nutrient, by name
+		"amount.calories":      "%.0f calories",
+		"amount.protein":       "%.0f grams of protein",
+		"amount.fat":           "%.0f grams of fat",
+		"amount.carbohydrates": "%.0f grams of carbohydrates",
+		"amount.fiber":         "%.0f grams of fiber",
+		"amount.sugar":         "%.0f grams of sugar",
+		"amount.sodium":        "%.0f milligrams of sodium",
+		// Units of measure by name, for one and for any other amount
+		"unit.gram":         "gram",
+		"units.gram":        "grams",
+		"unit.kilogram":     "kilogram",
+		"units.kilogram":    "kilograms",
+		"unit.ounce":        "ounce",
+		"units.ounce":       "ounces",
+		"unit.pound":        "pound",
+		"units.pound":       "pounds",
+		"unit.milliliter":   "milliliter",
+		"units.milliliter":  "milliliters",
+		"unit.liter":        "liter",
+		"units.liter":       "liters",
+		"unit.teaspoon":     "teaspoon",
+		"units.teaspoon":    "teaspoons",
+		"unit.tablespoon":   "tablespoon",
+		"units.tablespoon":  "tablespoons",
+		"unit.cup":          "cup",
+		"units.cup":         "cups",
+		"unit.fluid ounce":  "fluid ounce",
+		"units.fluid ounce": "fluid ounces",
+		"unit.pint":         "pint",
+		"units.pint":        "pints",
+		"unit.quart":        "quart",
+		"units.quart":       "quarts",
+		"unit.gallon":       "gallon",
+		"units.gallon":      "gallons",
+		"unit.dozen":        "dozen",
+		"units.dozen":       "dozen",
+		"unit.clove":        "clove",
+		"units.clove":       "cloves",
+		"unit.can":          "can",
+		"units.can":         "cans",
+		"unit.pinch":        "pinch",
+		"units.pinch":       "pinches",
+		"unit.slice":        "slice",
+		"units.slice":       "slices",
+		"unit.bunch":        "bunch",
+		"units.bunch":       "bunches",
+		// Amounts of money, by ISO currency code
+		"currencyUSD": "$%.2f",
+		"currencyCAD": "$%.2f",
+		"currencyAUD": "$%.2f",
+		"currencyGBP": "£%.2f",
+		"currencyEUR": "€%.2f",
+		"currencyINR": "₹%.2f",
+	},
+	"en-GB": {
+		"amount.fiber": "%.0f grams of fibre",
+		// Units spelled the British way, and those that differ in size
+		"unit.milliliter":   "millilitre",
+		"units.milliliter":  "millilitres",
+		"unit.liter":        "litre",
+		"units.liter":       "litres",
+		"unit.fluid ounce":  "US fluid ounce",
+		"units.fluid ounce": "US fluid ounces",
+	},
+	"en-US": {
+		"temperature": "%[1]d degrees Fahrenheit",
+		"fridge":      "refrigerator",
+	},
+}
+
+// Builds the recipe manager skill on top of a recipe store
+func NewSkill(store RecipeStore) *skill.Skill {
+	return NewSkillFrom(Sources{Recipes: store})
+}
+
+// Builds the recipe manager skill on top of all of its sources
+func NewSkillFrom(sources Sources) *skill.Skill {
+	connection := Connection{
+		store: sources.Recipes, glossary: sources.Glossary, prices: sources.Prices, taxonomy: sources.Taxonomy,
+		plans: sources.Plans, goals: sources.Goals, pantry: sources.Pantry,
+	}
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	renderers := []skill.ResponseInterceptor{
+		skill.SSMLLimitInterceptor(skill.MaxSpeechLength),
+		skill.AnalyticsInterceptor(skill.LogAnalyticsRecorder{Logger: logger}),
+		skill.SaveAttributesInterceptor(),
+	}
+	if sources.Lexicon != nil {
+		renderers = append([]skill.ResponseInterceptor{skill.PronunciationInterceptor(sources.Lexicon)}, renderers...)
+	}
+	preparers := []skill.RequestInterceptor{
+		skill.LoadAttributesInterceptor(),
+		skill.LocaleInterceptor(messages, "en-US"),
+		skill.LoggingInterceptor(logger),
+	}
+	if sources.Accounts != nil {
+		preparers = append(preparers, skill.AccountLinkingInterceptor(sources.Accounts))
+	}
+	return skill.New().AddRequestInterceptors(preparers...).AddResponseInterceptors(renderers...).AddRequestHandlers(
+		skill.IntentHandler(connection.ingredientsForRecipe, "GetIngredientsForRecipeIntent"),
+		skill.IntentHandler(connection.ingredientsForRecipes, "GetIngredientsForRecipesIntent"),
+		skill.IntentHandler(connection.recipesFromIngredients, "GetRecipeFromIngredientsIntent"),
+		skill.IntentHandler(connection.startCooking, "StartCookingIntent"),
+		cookingHandler(connection.nextStep, "AMAZON.NextIntent"),
+		cookingHandler(connection.previousStep, "AMAZON.PreviousIntent"),
+		cookingHandler(connection.repeatStep, "AMAZON.RepeatIntent"),
+		skill.IntentHandler(connection.explainTerm, "ExplainTermIntent"),
+		skill.IntentHandler(connection.explainStepTerms, "WhatIsThatIntent"),
+		skill.IntentHandler(safeTemperature, "GetSafeTemperatureIntent"),
+		skill.IntentHandler(storageTime, "GetStorageTimeIntent"),
+		skill.IntentHandler(connection.recipeCost, "GetRecipeCostIntent"),
+		skill.IntentHandler(connection.cheapRecipes, "GetCheapRecipesIntent"),
+		skill.IntentHandler(connection.recipesByNutrition, "FindRecipesByNutritionIntent"),
+		skill.IntentHandler(connection.setNutritionGoal, "SetNutritionGoalIntent"),
+		skill.IntentHandler(connection.plannedNutrition, "GetPlannedNutritionIntent"),
+		skill.IntentHandler(connection.generateMealPlan, "GenerateMealPlanIntent"),
+		skill.IntentHandler(connection.madeRecipe, "MadeRecipeIntent"),
+		skill.IntentHandler(connection.undoCooked, "UndoCookedIntent"),
+		skill.IntentHandler(connection.mealPrep, "MealPrepIntent"),
+		skill.IntentHandler(setVerbosity, "SetVerbosityIntent"),
+		skill.IntentHandler(setSpeechRate, "SetSpeechRateIntent"),
+		skill.IntentHandler(about, "AboutIntent"),
+		skill.FallbackHandler(unknown),
+	).AddErrorHandlers(
+		skill.NewErrorHandler(isNotFound, notFound),
+		skill.NewErrorHandler(IsUnavailable, Unavailable),
+		skill.NewErrorHandler(skill.IsUnlinked, relink),
+	)
+}
+
+func (connection Connection) ingredientsForRecipe(input *skill.HandlerInput) error {
+	recipeName := input.Request.Body.SlotValue("recipe")
+	if recipeName == "" {
+		return errors.New("Recipe name is not present in the request")
+	}
+	recipe, err := connection.store.FindByName(input.Context, recipeName)
+	if input.Preferences.Verbosity != skill.Detailed || len(recipe.Quantities) == 0 {
+		return Answer(input, "Ingredients", strings.Join(recipe.Ingredients, ", "), err)
+	}
+	var ingredients []string
+	for _, quantity := range recipe.Quantities {
+		ingredient := speakQuantity(input, quantity.Ingredient, quantity.Amount, quantity.Unit)
+		if quantity.Prep != "" {
+			ingredient = input.Messages.Format("withPrep", ingredient, quantity.Prep)
+		}
+		ingredients = append(ingredients, ingredient)
+	}
+	return Answer(input, "Ingredients", strings.Join(ingredients, ", "), err)
+}
+
+func (connection Connection) ingredientsForRecipes(input *skill.HandlerInput) error {
+	var recipeNames []string
+	for _, slot := range []string{"recipeone", "recipetwo", "recipethree"} {
+		if recipeName := input.Request.Body.SlotValue(slot); recipeName != "" {
+			recipeNames = append(recipeNames, recipeName)
+		}
+	}
+	if len(recipeNames) == 0 {
+		return errors.New("Recipe names are not present in the request")
+	}
+	recipes, err := connection.store.FindByNames(input.Context, recipeNames)
+	return Answer(input, "Ingredients", mergeIngredients(input, recipeNames, recipes), err)
+}
+
+func (connection Connection) recipesFromIngredients(input *skill.HandlerInput) error {
+	ingredient1 := input.Request.Body.SlotValue("ingredientone")
+	ingredient2 := input.Request.Body.SlotValue("ingredienttwo")
+	recipes, err := connection.findByIngredients(input, []string{ingredient1, ingredient2})
+	var recipeList []string
+	for _, recipe := range recipes {
+		recipeList = append(recipeList, recipe.Name)
+	}
+	return Answer(input, "Recipes", strings.Join(recipeList, ", "), err)
+}
+
+// Finds recipes using the ingredients, or kinds of them when there is a
+// taxonomy, so "cheese" finds recipes with cheddar, ranked after those with
+// cheese itself
+func (connection Connection) findByIngredients(input *skill.HandlerInput, ingredients []string) ([]Recipe, error) {
+	if connection.taxonomy == nil {
+		return connection.store.FindByIngredients(input.Context, ingredients)
+	}
+	taxonomy, err := connection.taxonomy.Taxonomy(input.Context)
+	if err != nil {
+		log.Printf("unable to read the ingredient taxonomy: %v", err)
+		return connection.store.FindByIngredients(input.Context, ingredients)
+	}
+	var groups [][]string
+	var distances []map[string]int
+	for _, ingredient := range ingredients {
+		kinds, distance := taxonomy.Expand(ingredient)
+		if normalizeIngredient(ingredient) != ingredient {
+			// Recipes written with capitals or spaces still match as asked
+			kinds = append([]string{ingredient}, kinds...)
+		}
+		groups = append(groups, kinds)
+		distances = append(distances, distance)
+	}
+	recipes, err := connection.store.Query(input.Context, RecipeQuery{IngredientGroups: groups})
+	rankByKinds(recipes, distances)
+	return recipes, err
+}
+
+func about(input *skill.HandlerInput) error {
+	return Answer(input, "About", input.Messages.Format("about"), nil)
+}
+
+func unknown(input *skill.HandlerInput) error {
+	return Answer(input, "Unknown Request", input.Messages.Format("unknown"), nil)
+}
+
+func isNotFound(input *skill.HandlerInput, err error) bool {
+	return errors.Is(err, mongo.ErrNoDocuments)
+}
+
+func notFound(input *skill.HandlerInput, err error) error {
+	text := input.Messages.Format("recipeNotFound")
+	if names := requestedRecipes(input); len(names) > 0 {
+		text = input.Messages.Format("notFound", JoinWithAnd(names))
+	}
+	input.Response.Speak(text).SimpleCard("Not Found", text)
+	return nil
+}
+
+// The slots intents name recipes in
+var recipeSlots = []string{"recipe", "recipeone", "recipetwo", "recipethree"}
+
+// The recipes a request names, or the recipe being cooked when it names none
+func requestedRecipes(input *skill.HandlerInput) []string {
+	var names []string
+	for _, slot := range recipeSlots {
+		if name := input.Request.Body.SlotValue(slot); name != "" {
+			names = append(names, name)
+		}
+	}
+	if cooking, ok := input.Attributes.SessionAttributes()[cookingRecipeAttribute].(string); ok && len(names) == 0 && cooking != "" {
+		names = append(names, cooking)
+	}
+	return names
+}
+
+// Asks the user to link their account again, with a card in the Alexa app
+func relink(input *skill.HandlerInput, err error) error {
+	input.Response.Speak(input.Messages.Format("relink")).LinkAccountCard()
+	return nil
+}
+
+// Reports whether the store failed because the database could not be reached
+// in time, rather than because of the request. Skills sharing the recipe
+// stores register it with Unavailable.
+func IsUnavailable(input *skill.HandlerInput, err error) bool {
+	return errors.Is(err, ErrCircuitOpen) || errors.Is(err, context.DeadlineExceeded) || IsRetryable(err)
+}
+
+// Apologises with the skill's "unavailable" message
+func Unavailable(input *skill.HandlerInput, err error) error {
+	log.Printf("store unavailable: %v", err)
+	text := input.Messages.Format("unavailable")
+	input.Response.Speak(text).SimpleCard("Unavailable", text)
+	return nil
+}
+
+// The recipe tag a slot names, resolved to its canonical value, or with a
+// plural "s" dropped when entity resolution didn't match, so "soups" is "soup"
+func tagOf(slot alexa.Slot) string {
+	tag := strings.ToLower(slot.ResolvedValue())
+	if slot.ResolvedValue() == slot.Value {
+		tag = strings.TrimSuffix(tag, "s")
+	}
+	return tag
+}
+
+// Speaks text and shows it on a simple card, noting when the store answered
+// from its cache. Any other store error is returned to the error handlers.
+func Answer(input *skill.HandlerInput, title string, text string, err error) error {
+	if errors.Is(err, ErrDegraded) {
+		text = input.Messages.Format("degraded") + text
+	} else if err != nil {
+		return err
+	}
+	input.Response.Speak(text).SimpleCard(title, text)
+	return nil
+}
+
+// Builds a single de-duplicated ingredient list for several recipes, noting
+// which of the requested recipes each ingredient belongs to unless the user
+// wants brief answers
+func mergeIngredients(input *skill.HandlerInput, recipeNames []string, recipes []Recipe) string {
+	found := make(map[string]Recipe)
+	for _, recipe := range recipes {
+		found[recipe.Name] = recipe
+	}
+	var ingredients []string
+	usedBy := make(map[string][]string)
+	var missing []string
+	for _, recipeName := range recipeNames {
+		recipe, ok := found[recipeName]
+		if !ok {
+			missing = append(missing, recipeName)
+			continue
+		}
+		for _, ingredient := range recipe.Ingredients {
+			key := strings.ToLower(strings.TrimSpace(ingredient))
+			if _, seen := usedBy[key]; !seen {
+				ingredients = append(ingredients, key)
+			}
+			if names := usedBy[key]; len(names) == 0 || names[len(names)-1] != recipe.Name {
+				usedBy[key] = append(names, recipe.Name)
+			}
+		}
+	}
+	var parts []string
+	for _, ingredient := range ingredients {
+		parts = append(parts, input.Messages.Format("ingredientFor", ingredient, JoinWithAnd(usedBy[ingredient])))
+	}
+	text := strings.Join(parts, ", ")
+	if len(missing) > 0 && text != "" {
+		return input.Messages.Format("ingredientsMissing", text, JoinWithAnd(missing))
+	} else if len(missing) > 0 {
+		return input.Messages.Format("recipesMissing", JoinWithAnd(missing))
+	}
+	return text
+}
+
+// Joins a list the way it would be spoken, for example "a, b and c"
+func JoinWithAnd(items []string) string {
+	if len(items) < 2 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
@@ -0,0 +1,94 @@
+package recipes
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mongodb-developer/alexa-golang-example/alexa"
+	"github.com/mongodb-developer/alexa-golang-example/skill"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// A PersistenceAdapter for a database that can't be reached
+type unreachableAdapter struct{}
+
+func (unreachableAdapter) Load(ctx context.Context, request alexa.Request) (map[string]interface{}, error) {
+	return nil, networkError
+}
+
+func (unreachableAdapter) Save(ctx context.Context, request alexa.Request, attributes map[string]interface{}) error {
+	return networkError
+}
+
+// An IntentRequest for intent with the given slot values
+func intentRequest(intent string, slots map[string]string) alexa.Request {
+	request := alexa.Request{Body: alexa.RequestBody{Type: "IntentRequest", Intent: &alexa.Intent{Name: intent, Slots: map[string]alexa.Slot{}}}}
+	for name, value := range slots {
+		request.Body.Intent.Slots[name] = alexa.Slot{Name: name, Value: value}
+	}
+	return request
+}
+
+func TestSkillAnswersFromSnapshotDuringOutage(t *testing.T) {
+	pancakes := Recipe{Name: "Pancakes", Ingredients: []string{"flour", "milk", "eggs"}}
+	snapshot := &SnapshotStore{recipes: []Recipe{pancakes}, byName: map[string]Recipe{"Pancakes": pancakes}}
+	store := NewResilientStore(&stubStore{err: networkError}, RetryPolicy{MaxAttempts: 1}, &CircuitBreaker{Threshold: 5, Cooldown: time.Hour}).WithFallback(snapshot)
+	hosted := NewSkill(store).WithPersistenceAdapter(unreachableAdapter{})
+
+	response, err := hosted.Invoke(context.Background(), intentRequest("GetIngredientsForRecipeIntent", map[string]string{"recipe": "Pancakes"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	speech := response.Body.OutputSpeech
+	if speech == nil || !strings.HasPrefix(speech.Text, messages["en"]["degraded"]) || !strings.HasSuffix(speech.Text, "flour, milk, eggs") {
+		t.Fatalf("got %+v, want the ingredients from the snapshot", speech)
+	}
+}
+
+func TestMergeIngredients(t *testing.T) {
+	recipes := []Recipe{
+		{Name: "Pancakes", Ingredients: []string{"flour", "Milk", "eggs"}},
+		{Name: "Omelette", Ingredients: []string{"eggs", "milk ", "cheese"}},
+	}
+	tests := []struct {
+		verbosity skill.Verbosity
+		names     []string
+		want      string
+	}{
+		{skill.Normal, []string{"Pancakes", "Omelette"}, "flour for Pancakes, milk for Pancakes and Omelette, eggs for Pancakes and Omelette, cheese for Omelette"},
+		{skill.Brief, []string{"Pancakes", "Omelette"}, "flour, milk, eggs, cheese"},
+		{skill.Normal, []string{"Omelette", "Waffles", "Crepes"}, "eggs for Omelette, milk for Omelette, cheese for Omelette. I couldn't find Waffles and Crepes"},
+		{skill.Brief, []string{"Waffles"}, "I couldn't find Waffles"},
+	}
+	for _, test := range tests {
+		input := &skill.HandlerInput{Messages: messages.For("en-US", "en-US").ForVerbosity(test.verbosity)}
+		if got := mergeIngredients(input, test.names, recipes); got != test.want {
+			t.Errorf("%v with verbosity %v: got %q, want %q", test.names, test.verbosity, got, test.want)
+		}
+	}
+}
+
+func TestNotFoundNamesTheRecipe(t *testing.T) {
+	hosted := NewSkill(&stubStore{err: mongo.ErrNoDocuments})
+
+	response, err := hosted.Invoke(context.Background(), intentRequest("GetIngredientsForRecipeIntent", map[string]string{"recipe": "Waffles"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "I couldn't find a recipe called Waffles."; response.Body.OutputSpeech == nil || response.Body.OutputSpeech.Text != want {
+		t.Errorf("got %+v, want %q", response.Body.OutputSpeech, want)
+	}
+
+	// Cooking mode names the recipe in the session rather than a slot
+	request := intentRequest("AMAZON.NextIntent", nil)
+	request.Session = &alexa.Session{Attributes: map[string]alexa.Value{cookingRecipeAttribute: alexa.StringOf("Waffles")}}
+	response, err = hosted.Invoke(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "I couldn't find a recipe called Waffles."; response.Body.OutputSpeech == nil || response.Body.OutputSpeech.Text != want {
+		t.Errorf("in cooking mode got %+v, want %q", response.Body.OutputSpeech, want)
+	}
+}
@@ -0,0 +1,162 @@
+package recipes
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The snapshot bundled with the deployment when SNAPSHOT_PATH isn't set
+const DefaultSnapshotPath = "recipes.snapshot.json.gz"
+
+// Wrapped in a DegradedError by every SnapshotStore lookup so answers carry
+// the same spoken note as cached ones
+var ErrOffline = errors.New("database unreachable, serving from the offline snapshot")
+
+// A read-only RecipeStore serving lookups from a snapshot of the recipes collection
+type SnapshotStore struct {
+	recipes []Recipe
+	byName  map[string]Recipe
+}
+
+// Writes every recipe in the collection to a gzipped JSON file, replacing the
+// file atomically so a half-written snapshot is never bundled
+func WriteSnapshot(ctx context.Context, collection *mongo.Collection, path string) error {
+	var recipes []Recipe
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	if err = cursor.All(ctx, &recipes); err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(filepath.Dir(path), ".snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	writer := gzip.NewWriter(file)
+	if err = json.NewEncoder(writer).Encode(recipes); err != nil {
+		file.Close()
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// Reads a snapshot written by WriteSnapshot
+func LoadSnapshot(path string) (*SnapshotStore, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	var recipes []Recipe
+	if err = json.NewDecoder(reader).Decode(&recipes); err != nil {
+		return nil, err
+	}
+	store := &SnapshotStore{recipes: recipes, byName: make(map[string]Recipe)}
+	for _, recipe := range recipes {
+		store.byName[recipe.Name] = recipe
+	}
+	return store, nil
+}
+
+// Every recipe in the snapshot
+func (store *SnapshotStore) Recipes() []Recipe {
+	return store.recipes
+}
+
+func (store *SnapshotStore) FindByName(ctx context.Context, name string) (Recipe, error) {
+	recipe, ok := store.byName[name]
+	if !ok {
+		return Recipe{}, mongo.ErrNoDocuments
+	}
+	return recipe, &DegradedError{Cause: ErrOffline}
+}
+
+func (store *SnapshotStore) FindByNames(ctx context.Context, names []string) ([]Recipe, error) {
+	var recipes []Recipe
+	for _, name := range names {
+		if recipe, ok := store.byName[name]; ok {
+			recipes = append(recipes, recipe)
+		}
+	}
+	return recipes, &DegradedError{Cause: ErrOffline}
+}
+
+func (store *SnapshotStore) FindByIngredients(ctx context.Context, ingredients []string) ([]Recipe, error) {
+	var recipes []Recipe
+	for _, recipe := range store.recipes {
+		if containsAll(recipe.Ingredients, ingredients) {
+			recipes = append(recipes, recipe)
+		}
+	}
+	return recipes, &DegradedError{Cause: ErrOffline}
+}
+
+func (store *SnapshotStore) Query(ctx context.Context, query RecipeQuery) ([]Recipe, error) {
+	var recipes []Recipe
+	for _, recipe := range store.recipes {
+		if query.Matches(recipe) {
+			recipes = append(recipes, recipe)
+		}
+	}
+	return recipes, &DegradedError{Cause: ErrOffline}
+}
+
+// Mirrors a case-insensitive $regex on an array: some value must contain query,
+// which is already lower case
+func containsSubstring(values []string, query string) bool {
+	for _, value := range values {
+		if strings.Contains(strings.ToLower(value), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// Mirrors the $in operator on an array: some wanted value must appear in values
+func containsAny(values []string, wanted []string) bool {
+	for _, value := range values {
+		for _, candidate := range wanted {
+			if value == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Mirrors the $all operator: every wanted value must appear in values
+func containsAll(values []string, wanted []string) bool {
+	present := make(map[string]bool, len(values))
+	for _, value := range values {
+		present[value] = true
+	}
+	for _, value := range wanted {
+		if !present[value] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,177 @@
+// Package recipes implements the recipe manager skill and the recipe store
+// it is backed by.
+package recipes
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/mongodb-developer/alexa-golang-example/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// A data structure representation of the collection schema
+type Recipe struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	Name        string             `bson:"name" json:"name"`
+	Ingredients []string           `bson:"ingredients" json:"ingredients"`
+	// The method, one instruction per step, read out in cooking mode
+	Steps []string `bson:"steps,omitempty" json:"steps,omitempty"`
+	// How much of each ingredient is used, for costing
+	Quantities []Quantity `bson:"quantities,omitempty" json:"quantities,omitempty"`
+	Servings   int        `bson:"servings,omitempty" json:"servings,omitempty"`
+	// Kinds of dish the recipe is, such as "dinner" or "soup", and the diets
+	// it suits, such as "vegetarian"
+	Tags []string `bson:"tags,omitempty" json:"tags,omitempty"`
+	// Such as "italian", so planned meals can vary
+	Cuisine string `bson:"cuisine,omitempty" json:"cuisine,omitempty"`
+	// How long the recipe takes from start to finish
+	Minutes int `bson:"minutes,omitempty" json:"minutes,omitempty"`
+	// Per serving, as stored by webhooks.Catalog.UpdateNutrition
+	Nutrition *Nutrition `bson:"nutrition,omitempty" json:"nutrition,omitempty"`
+}
+
+// An amount of an ingredient, such as 2 cups of flour
+type Quantity struct {
+	Ingredient string  `bson:"ingredient" json:"ingredient"`
+	Amount     float64 `bson:"amount" json:"amount"`
+	// A unit known to ConvertAmount, or empty for a count such as 2 onions
+	Unit string `bson:"unit,omitempty" json:"unit,omitempty"`
+	// What to do to the ingredient before cooking, such as "chop" or "dice"
+	Prep string `bson:"prep,omitempty" json:"prep,omitempty"`
+}
+
+// Describes the recipe lookups made by the Alexa intents
+type RecipeStore interface {
+	FindByName(ctx context.Context, name string) (Recipe, error)
+	FindByNames(ctx context.Context, names []string) ([]Recipe, error)
+	FindByIngredients(ctx context.Context, ingredients []string) ([]Recipe, error)
+	// Finds the recipes matching every condition set in the query
+	Query(ctx context.Context, query RecipeQuery) ([]Recipe, error)
+}
+
+// The less common recipe lookups, combined. Conditions left empty match every recipe.
+type RecipeQuery struct {
+	// Recipes using at least one ingredient from every group
+	IngredientGroups [][]string
+	// Recipes whose name or ingredients contain Text, ignoring case
+	Text string
+	// Recipes with the tag, such as "dinner"
+	Tag string
+	// Recipes with nutrition whose nutrition per serving is within every limit
+	Nutrition []NutrientLimit
+}
+
+// Identifies the query, for caching its results
+func (query RecipeQuery) key() string {
+	var groups []string
+	for _, group := range query.IngredientGroups {
+		groups = append(groups, strings.Join(group, "\x00"))
+	}
+	key := strings.Join(groups, "\x01") + "\x02" + query.Text + "\x02" + query.Tag
+	for _, limit := range query.Nutrition {
+		key += "\x02" + limit.key()
+	}
+	return key
+}
+
+// The MongoDB filter for the query
+func (query RecipeQuery) filter() bson.M {
+	var clauses []bson.M
+	for _, group := range query.IngredientGroups {
+		clauses = append(clauses, bson.M{"ingredients": bson.M{"$in": group}})
+	}
+	if query.Text != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(query.Text), Options: "i"}
+		clauses = append(clauses, bson.M{"$or": []bson.M{{"name": pattern}, {"ingredients": pattern}}})
+	}
+	if query.Tag != "" {
+		clauses = append(clauses, bson.M{"tags": query.Tag})
+	}
+	if len(query.Nutrition) > 0 {
+		clauses = append(clauses, bson.M{"nutrition": bson.M{"$exists": true}})
+	}
+	for _, limit := range query.Nutrition {
+		bounds := bson.M{}
+		if limit.Min != nil {
+			bounds["$gte"] = *limit.Min
+		}
+		if limit.Max != nil {
+			bounds["$lte"] = *limit.Max
+		}
+		if len(bounds) > 0 {
+			clauses = append(clauses, bson.M{"nutrition." + limit.Nutrient: bounds})
+		}
+	}
+	if len(clauses) == 0 {
+		return bson.M{}
+	}
+	return bson.M{"$and": clauses}
+}
+
+// Reports whether the recipe matches the query, as the filter would in MongoDB
+func (query RecipeQuery) Matches(recipe Recipe) bool {
+	for _, group := range query.IngredientGroups {
+		if !containsAny(recipe.Ingredients, group) {
+			return false
+		}
+	}
+	if text := strings.ToLower(query.Text); text != "" && !strings.Contains(strings.ToLower(recipe.Name), text) && !containsSubstring(recipe.Ingredients, text) {
+		return false
+	}
+	if query.Tag != "" && !containsAll(recipe.Tags, []string{query.Tag}) {
+		return false
+	}
+	if len(query.Nutrition) > 0 && recipe.Nutrition == nil {
+		return false
+	}
+	for _, limit := range query.Nutrition {
+		if !limit.allows(*recipe.Nutrition) {
+			return false
+		}
+	}
+	return true
+}
+
+// A RecipeStore backed by the recipes collection in MongoDB
+type MongoRecipeStore struct {
+	collection *mongodb.Collection
+}
+
+func NewMongoRecipeStore(collection *mongodb.Collection) *MongoRecipeStore {
+	return &MongoRecipeStore{collection: collection}
+}
+
+func (store *MongoRecipeStore) FindByName(ctx context.Context, name string) (Recipe, error) {
+	var recipe Recipe
+	if err := store.collection.FindOne(ctx, bson.M{"name": name}).Decode(&recipe); err != nil {
+		return Recipe{}, err
+	}
+	return recipe, nil
+}
+
+func (store *MongoRecipeStore) FindByNames(ctx context.Context, names []string) ([]Recipe, error) {
+	return store.find(ctx, bson.M{"name": bson.M{"$in": names}})
+}
+
+func (store *MongoRecipeStore) FindByIngredients(ctx context.Context, ingredients []string) ([]Recipe, error) {
+	return store.find(ctx, bson.M{"ingredients": bson.M{"$all": ingredients}})
+}
+
+func (store *MongoRecipeStore) Query(ctx context.Context, query RecipeQuery) ([]Recipe, error) {
+	return store.find(ctx, query.filter())
+}
+
+func (store *MongoRecipeStore) find(ctx context.Context, filter interface{}) ([]Recipe, error) {
+	var recipes []Recipe
+	cursor, err := store.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &recipes); err != nil {
+		return nil, err
+	}
+	return recipes, nil
+}
@@ -0,0 +1,118 @@
+package recipes
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mongodb-developer/alexa-golang-example/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// An ingredient and the more general one it is a kind of, such as cheddar
+// and hard cheese. The most general ingredients have no parent.
+type IngredientKind struct {
+	Name   string `bson:"name" json:"name"`
+	Parent string `bson:"parent,omitempty" json:"parent,omitempty"`
+}
+
+// Describes where the ingredient taxonomy comes from
+type TaxonomyStore interface {
+	Taxonomy(ctx context.Context) (*Taxonomy, error)
+}
+
+// A hierarchy of ingredients, such as cheddar → hard cheese → cheese → dairy
+type Taxonomy struct {
+	children map[string][]string
+}
+
+func NewTaxonomy(kinds []IngredientKind) *Taxonomy {
+	taxonomy := &Taxonomy{children: make(map[string][]string)}
+	for _, kind := range kinds {
+		name, parent := normalizeIngredient(kind.Name), normalizeIngredient(kind.Parent)
+		if parent != "" && parent != name {
+			taxonomy.children[parent] = append(taxonomy.children[parent], name)
+		}
+	}
+	return taxonomy
+}
+
+// The ingredient followed by every kind of it, nearest first, with how many
+// levels below it each one is. The ingredient itself is at distance zero.
+func (taxonomy *Taxonomy) Expand(ingredient string) ([]string, map[string]int) {
+	ingredient = normalizeIngredient(ingredient)
+	expanded := []string{ingredient}
+	distance := map[string]int{ingredient: 0}
+	for next := 0; next < len(expanded); next++ {
+		for _, child := range taxonomy.children[expanded[next]] {
+			// A cycle in the data would otherwise expand forever
+			if _, seen := distance[child]; !seen {
+				distance[child] = distance[expanded[next]] + 1
+				expanded = append(expanded, child)
+			}
+		}
+	}
+	return expanded, distance
+}
+
+// An ingredient in lower case with single spaces, for comparing names
+func normalizeIngredient(ingredient string) string {
+	return strings.Join(strings.Fields(strings.ToLower(ingredient)), " ")
+}
+
+// A TaxonomyStore reading the ingredientTaxonomy collection, one document per
+// ingredient, kept for a while like the glossary
+type MongoTaxonomyStore struct {
+	cache *refresher
+}
+
+func NewMongoTaxonomyStore(collection *mongodb.Collection, ttl time.Duration) *MongoTaxonomyStore {
+	return &MongoTaxonomyStore{cache: newRefresher("ingredient taxonomy", ttl, func(ctx context.Context) (interface{}, error) {
+		var kinds []IngredientKind
+		cursor, err := collection.Find(ctx, bson.M{})
+		if err == nil {
+			err = cursor.All(ctx, &kinds)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return NewTaxonomy(kinds), nil
+	})}
+}
+
+// The taxonomy, read again once it is older than the TTL. The previous copy
+// is kept when it can't be.
+func (store *MongoTaxonomyStore) Taxonomy(ctx context.Context) (*Taxonomy, error) {
+	taxonomy, err := store.cache.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return taxonomy.(*Taxonomy), nil
+}
+
+// Orders recipes found by ingredient groups so those using the ingredients
+// asked for come before those using more specific kinds of them. distances
+// holds, for each group, how far each of its ingredients is from the one asked for.
+func rankByKinds(recipes []Recipe, distances []map[string]int) {
+	score := func(recipe Recipe) int {
+		total := 0
+		for _, distance := range distances {
+			nearest := -1
+			for _, ingredient := range recipe.Ingredients {
+				if d, ok := distance[normalizeIngredient(ingredient)]; ok && (nearest < 0 || d < nearest) {
+					nearest = d
+				}
+			}
+			if nearest > 0 {
+				total += nearest
+			}
+		}
+		return total
+	}
+	scores := make(map[string]int, len(recipes))
+	for _, recipe := range recipes {
+		scores[recipe.Name] = score(recipe)
+	}
+	sort.SliceStable(recipes, func(i, j int) bool { return scores[recipes[i].Name] < scores[recipes[j].Name] })
+}
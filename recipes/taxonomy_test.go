@@ -0,0 +1,37 @@
+package recipes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRankByKinds(t *testing.T) {
+	taxonomy := NewTaxonomy([]IngredientKind{
+		{Name: "hard cheese", Parent: "cheese"},
+		{Name: "Cheddar", Parent: "hard cheese"},
+		{Name: "brie", Parent: "cheese"},
+		{Name: "rigatoni", Parent: "pasta"},
+	})
+	var distances []map[string]int
+	for _, ingredient := range []string{"cheese", "pasta"} {
+		_, distance := taxonomy.Expand(ingredient)
+		distances = append(distances, distance)
+	}
+	found := []Recipe{
+		{Name: "Cheddar Rigatoni", Ingredients: []string{"cheddar", "rigatoni"}},
+		{Name: "Brie Pasta", Ingredients: []string{"brie", "pasta"}},
+		{Name: "Cheese Pasta", Ingredients: []string{"Cheese", "pasta"}},
+		{Name: "Hard Cheese Rigatoni", Ingredients: []string{"hard cheese", "rigatoni"}},
+		// The nearest kind of each group counts, so the cheese makes up for the cheddar
+		{Name: "Three Cheese Pasta", Ingredients: []string{"cheddar", "cheese", "brie", "pasta"}},
+	}
+	rankByKinds(found, distances)
+	var names []string
+	for _, recipe := range found {
+		names = append(names, recipe.Name)
+	}
+	want := []string{"Cheese Pasta", "Three Cheese Pasta", "Brie Pasta", "Hard Cheese Rigatoni", "Cheddar Rigatoni"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,122 @@
+package recipes
+
+import "strings"
+
+// A unit of measure as a multiple of the base unit of its dimension: grams
+// for mass, millilitres for volume and single items for counts. Things
+// counted in cloves, cans and the like are each a dimension of their own.
+type unit struct {
+	dimension string
+	factor    float64
+}
+
+// The units recipes and prices are written in, by every spelling accepted.
+// Volumes use US customary measures.
+var units = map[string]unit{
+	"":      {"count", 1},
+	"each":  {"count", 1},
+	"piece": {"count", 1},
+	"dozen": {"count", 12},
+
+	"clove": {"clove", 1},
+	"can":   {"can", 1},
+	"pinch": {"pinch", 1},
+	"slice": {"slice", 1},
+	"bunch": {"bunch", 1},
+
+	"g":        {"mass", 1},
+	"gram":     {"mass", 1},
+	"kg":       {"mass", 1000},
+	"kilogram": {"mass", 1000},
+	"oz":       {"mass", 28.349523125},
+	"ounce":    {"mass", 28.349523125},
+	"lb":       {"mass", 453.59237},
+	"pound":    {"mass", 453.59237},
+
+	"ml":         {"volume", 1},
+	"milliliter": {"volume", 1},
+	"millilitre": {"volume", 1},
+	"l":          {"volume", 1000},
+	"liter":      {"volume", 1000},
+	"litre":      {"volume", 1000},
+	"tsp":        {"volume", 4.92892159375},
+	"teaspoon":   {"volume", 4.92892159375},
+	"tbsp":       {"volume", 14.78676478125},
+	"tablespoon": {"volume", 14.78676478125},
+	"cup":        {"volume", 236.5882365},
+	"fl oz":      {"volume", 29.5735295625},
+	"pint":       {"volume", 473.176473},
+	"quart":      {"volume", 946.352946},
+	"gallon":     {"volume", 3785.411784},
+}
+
+// The full names of abbreviated units and the usual spelling of others, for
+// speaking them. Single items are spoken as a count, so have no name.
+var unitNames = map[string]string{
+	"":           "",
+	"each":       "",
+	"piece":      "",
+	"g":          "gram",
+	"kg":         "kilogram",
+	"oz":         "ounce",
+	"lb":         "pound",
+	"ml":         "milliliter",
+	"millilitre": "milliliter",
+	"l":          "liter",
+	"litre":      "liter",
+	"tsp":        "teaspoon",
+	"tbsp":       "tablespoon",
+	"fl oz":      "fluid ounce",
+}
+
+// Looks up a unit, ignoring case, a trailing period and plurals
+func lookupUnit(name string) (unit, bool) {
+	key, ok := unitKey(name)
+	return units[key], ok
+}
+
+// The spelling a unit is listed under in units
+func unitKey(name string) (string, bool) {
+	name = normalizeUnit(name)
+	for _, key := range []string{name, strings.TrimSuffix(name, "s"), strings.TrimSuffix(name, "es")} {
+		if _, ok := units[key]; ok {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// The name a known unit is spoken by, in the singular, which is empty for
+// single items
+func unitName(name string) (string, bool) {
+	key, ok := unitKey(name)
+	if !ok {
+		return "", false
+	}
+	if full, ok := unitNames[key]; ok {
+		return full, true
+	}
+	return key, true
+}
+
+func normalizeUnit(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
+
+// Converts amount from one unit to another of the same dimension. Converting
+// between mass and volume would need each ingredient's density, so it fails.
+// A unit missing from the table still converts to itself.
+func ConvertAmount(amount float64, from string, to string) (float64, bool) {
+	if normalizeUnit(from) == normalizeUnit(to) {
+		return amount, true
+	}
+	fromUnit, ok := lookupUnit(from)
+	if !ok {
+		return 0, false
+	}
+	toUnit, ok := lookupUnit(to)
+	if !ok || fromUnit.dimension != toUnit.dimension {
+		return 0, false
+	}
+	return amount * fromUnit.factor / toUnit.factor, true
+}
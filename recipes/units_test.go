@@ -0,0 +1,35 @@
+package recipes
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConvertAmount(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		from, to string
+		want     float64
+		ok       bool
+	}{
+		{2, "lb", "kg", 0.90718474, true},
+		{3, "Cups", "ml", 709.7647095, true},
+		{1, "tbsp.", "tsp", 3, true},
+		{2, "dozen", "", 24, true},
+		{4, "cloves", "clove", 4, true},
+		{2, "pinches", "pinch", 2, true},
+		{1, "can", "Can", 1, true},
+		{3, "sprig", "sprig", 3, true},
+		{1, "sprig", "bunch", 0, false},
+		{2, "clove", "", 0, false},
+		{1, "can", "slice", 0, false},
+		{1, "cup", "g", 0, false},
+		{1, "handful", "cup", 0, false},
+	}
+	for _, test := range tests {
+		got, ok := ConvertAmount(test.amount, test.from, test.to)
+		if ok != test.ok || math.Abs(got-test.want) > 1e-6 {
+			t.Errorf("ConvertAmount(%v, %q, %q) = %v, %v, want %v, %v", test.amount, test.from, test.to, got, ok, test.want, test.ok)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Package secrets looks up secret values, such as database connection
+// strings, from the environment, local files or AWS.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// Looks up secret values, such as the database URI, by name
+type Provider interface {
+	Secret(ctx context.Context, name string) (string, error)
+}
+
+// Reads secrets from environment variables, where name is the variable name
+type Env struct{}
+
+func (Env) Secret(ctx context.Context, name string) (string, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// Reads secrets from local files, where name is the path of the file
+type File struct{}
+
+func (File) Secret(ctx context.Context, name string) (string, error) {
+	value, err := ioutil.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(value)), nil
+}
+
+// An in-memory Provider that stands in for the AWS providers locally
+type Static map[string]string
+
+func (secrets Static) Secret(ctx context.Context, name string) (string, error) {
+	value, ok := secrets[name]
+	if !ok {
+		return "", fmt.Errorf("secret %s is not defined", name)
+	}
+	return value, nil
+}
+
+// The part of the Secrets Manager client used by SecretsManager
+type SecretsManagerAPI interface {
+	GetSecretValue(ctx context.Context, input *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// Reads secrets from AWS Secrets Manager, where name is the secret ID or ARN
+type SecretsManager struct {
+	Client SecretsManagerAPI
+}
+
+func (secrets SecretsManager) Secret(ctx context.Context, name string) (string, error) {
+	output, err := secrets.Client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	})
+	if err != nil {
+		return "", err
+	}
+	if output.SecretString == nil {
+		return "", fmt.Errorf("secret %s has no string value", name)
+	}
+	return *output.SecretString, nil
+}
+
+// The part of the SSM client used by ParameterStore
+type ParameterStoreAPI interface {
+	GetParameter(ctx context.Context, input *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+}
+
+// Reads secrets from SSM Parameter Store, decrypting SecureString parameters
+type ParameterStore struct {
+	Client ParameterStoreAPI
+}
+
+func (secrets ParameterStore) Secret(ctx context.Context, name string) (string, error) {
+	output, err := secrets.Client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+	if output.Parameter == nil || output.Parameter.Value == nil {
+		return "", fmt.Errorf("parameter %s has no value", name)
+	}
+	return *output.Parameter.Value, nil
+}
+
+type cachedSecret struct {
+	value     string
+	fetchedAt time.Time
+}
+
+// A fetch from the provider in progress, which other callers asking for the
+// same secret wait for instead of fetching it again
+type fetch struct {
+	done  chan struct{}
+	value string
+	err   error
+}
+
+// Caches secrets from another provider for TTL so warm invocations don't
+// call AWS, while still picking up rotated values once they expire
+type Cached struct {
+	Provider Provider
+	TTL      time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]cachedSecret
+	fetches map[string]*fetch
+}
+
+func (secrets *Cached) Secret(ctx context.Context, name string) (string, error) {
+	secrets.mutex.Lock()
+	if entry, ok := secrets.entries[name]; ok && time.Since(entry.fetchedAt) < secrets.TTL {
+		secrets.mutex.Unlock()
+		return entry.value, nil
+	}
+	current, fetching := secrets.fetches[name]
+	if !fetching {
+		current = &fetch{done: make(chan struct{})}
+		if secrets.fetches == nil {
+			secrets.fetches = make(map[string]*fetch)
+		}
+		secrets.fetches[name] = current
+	}
+	secrets.mutex.Unlock()
+
+	if !fetching {
+		// The provider is called without the lock, so a slow fetch of one
+		// secret doesn't hold up callers of the others
+		current.value, current.err = secrets.Provider.Secret(ctx, name)
+		secrets.mutex.Lock()
+		// Unless the secret was invalidated while it was being fetched
+		if secrets.fetches[name] == current {
+			delete(secrets.fetches, name)
+			if current.err == nil {
+				if secrets.entries == nil {
+					secrets.entries = make(map[string]cachedSecret)
+				}
+				secrets.entries[name] = cachedSecret{value: current.value, fetchedAt: time.Now()}
+			}
+		}
+		secrets.mutex.Unlock()
+		close(current.done)
+	}
+	select {
+	case <-current.done:
+		return current.value, current.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Forgets a cached secret, for example after the database rejected it. A
+// fetch already in progress isn't waited for by later callers.
+func (secrets *Cached) Invalidate(name string) {
+	secrets.mutex.Lock()
+	defer secrets.mutex.Unlock()
+	delete(secrets.entries, name)
+	delete(secrets.fetches, name)
+}
+
+// Builds the provider and secret name for the database URI from
+// ATLAS_URI_PROVIDER (env, file, secretsmanager or ssm), ATLAS_URI_SECRET and
+// SECRETS_TTL. With nothing set, the URI is read from ATLAS_URI as before.
+func NewFromEnv(ctx context.Context) (*Cached, string, error) {
+	name := os.Getenv("ATLAS_URI_SECRET")
+	ttl := 5 * time.Minute
+	if value := os.Getenv("SECRETS_TTL"); value != "" {
+		var err error
+		if ttl, err = time.ParseDuration(value); err != nil {
+			return nil, "", fmt.Errorf("SECRETS_TTL: %v", err)
+		}
+	}
+
+	var provider Provider
+	switch kind := os.Getenv("ATLAS_URI_PROVIDER"); kind {
+	case "", "env":
+		provider = Env{}
+		if name == "" {
+			name = "ATLAS_URI"
+		}
+	case "file":
+		provider = File{}
+	case "secretsmanager", "ssm":
+		awsConfig, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		if kind == "ssm" {
+			provider = ParameterStore{Client: ssm.NewFromConfig(awsConfig)}
+		} else {
+			provider = SecretsManager{Client: secretsmanager.NewFromConfig(awsConfig)}
+		}
+	default:
+		return nil, "", fmt.Errorf("ATLAS_URI_PROVIDER: unknown provider %q", kind)
+	}
+	if name == "" {
+		return nil, "", errors.New("ATLAS_URI_SECRET must name the secret holding the database URI")
+	}
+	return &Cached{Provider: provider, TTL: ttl}, name, nil
+}
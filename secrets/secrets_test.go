@@ -0,0 +1,136 @@
+package secrets
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A Provider standing in for AWS that counts its calls and can be held up
+type countingProvider struct {
+	values Static
+	// Fetches of these secrets wait until the channel is closed
+	blocked map[string]chan struct{}
+
+	mutex sync.Mutex
+	calls map[string]int
+}
+
+func (provider *countingProvider) Secret(ctx context.Context, name string) (string, error) {
+	provider.mutex.Lock()
+	if provider.calls == nil {
+		provider.calls = make(map[string]int)
+	}
+	provider.calls[name]++
+	provider.mutex.Unlock()
+	if release, ok := provider.blocked[name]; ok {
+		<-release
+	}
+	return provider.values.Secret(ctx, name)
+}
+
+func (provider *countingProvider) callsFor(name string) int {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+	return provider.calls[name]
+}
+
+func TestStatic(t *testing.T) {
+	secrets := Static{"ATLAS_URI": "mongodb://localhost"}
+	if value, err := secrets.Secret(context.Background(), "ATLAS_URI"); err != nil || value != "mongodb://localhost" {
+		t.Errorf("got %q, %v, want the defined value", value, err)
+	}
+	if _, err := secrets.Secret(context.Background(), "MISSING"); err == nil {
+		t.Error("got no error for an undefined secret")
+	}
+}
+
+func TestCachedServesUntilTTL(t *testing.T) {
+	provider := &countingProvider{values: Static{"uri": "first"}}
+	cached := &Cached{Provider: provider, TTL: 20 * time.Millisecond}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if value, err := cached.Secret(ctx, "uri"); err != nil || value != "first" {
+			t.Fatalf("got %q, %v, want first", value, err)
+		}
+	}
+	if calls := provider.callsFor("uri"); calls != 1 {
+		t.Fatalf("the provider was called %d times within the TTL, want once", calls)
+	}
+
+	provider.values["uri"] = "rotated"
+	time.Sleep(30 * time.Millisecond)
+	if value, _ := cached.Secret(ctx, "uri"); value != "rotated" {
+		t.Fatalf("got %q after the TTL, want the rotated value", value)
+	}
+}
+
+func TestCachedInvalidate(t *testing.T) {
+	provider := &countingProvider{values: Static{"uri": "first"}}
+	cached := &Cached{Provider: provider, TTL: time.Hour}
+	ctx := context.Background()
+	cached.Secret(ctx, "uri")
+
+	provider.values["uri"] = "rotated"
+	cached.Invalidate("uri")
+	if value, _ := cached.Secret(ctx, "uri"); value != "rotated" {
+		t.Fatalf("got %q after invalidating, want the rotated value", value)
+	}
+}
+
+func TestCachedDoesNotCacheErrors(t *testing.T) {
+	provider := &countingProvider{values: Static{}}
+	cached := &Cached{Provider: provider, TTL: time.Hour}
+	ctx := context.Background()
+	if _, err := cached.Secret(ctx, "uri"); err == nil {
+		t.Fatal("got no error for an undefined secret")
+	}
+	provider.values["uri"] = "defined"
+	if value, err := cached.Secret(ctx, "uri"); err != nil || value != "defined" {
+		t.Fatalf("got %q, %v, want the value defined after the error", value, err)
+	}
+}
+
+func TestCachedFetchesOutsideTheLock(t *testing.T) {
+	release := make(chan struct{})
+	provider := &countingProvider{values: Static{"slow": "slow value", "fast": "fast value"}, blocked: map[string]chan struct{}{"slow": release}}
+	cached := &Cached{Provider: provider, TTL: time.Hour}
+	ctx := context.Background()
+
+	var waiting sync.WaitGroup
+	values := make([]string, 3)
+	for i := range values {
+		waiting.Add(1)
+		go func(i int) {
+			defer waiting.Done()
+			values[i], _ = cached.Secret(ctx, "slow")
+		}(i)
+	}
+
+	fast := make(chan string)
+	go func() {
+		value, _ := cached.Secret(ctx, "fast")
+		fast <- value
+	}()
+	select {
+	case value := <-fast:
+		if value != "fast value" {
+			t.Errorf("got %q, want fast value", value)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("a slow fetch of one secret held up another")
+	}
+
+	close(release)
+	waiting.Wait()
+	for _, value := range values {
+		if value != "slow value" {
+			t.Errorf("got %q, want slow value", value)
+		}
+	}
+	if calls := provider.callsFor("slow"); calls != 1 {
+		t.Errorf("concurrent callers fetched the secret %d times, want once", calls)
+	}
+}
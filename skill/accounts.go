@@ -0,0 +1,42 @@
+package skill
+
+import (
+	"errors"
+
+	"github.com/mongodb-developer/alexa-golang-example/linking"
+)
+
+// Reports whether a request was refused because the access token of the
+// user's linked account is no longer accepted, so they need to link it again
+func IsUnlinked(input *HandlerInput, err error) bool {
+	return errors.Is(err, linking.ErrUnauthenticated)
+}
+
+// Resolves the access token of a linked account, sent in
+// context.System.user.accessToken, to the user the web and mobile apps know,
+// and sets it as the input's UserID. Requests from users who haven't linked
+// their account keep the Alexa userId.
+func AccountLinkingInterceptor(auth linking.Authenticator) RequestInterceptor {
+	return RequestInterceptorFunc(func(input *HandlerInput) error {
+		token := input.Request.User().AccessToken
+		if token == "" {
+			return nil
+		}
+		userID, err := auth.Authenticate(input.Context, token)
+		if err != nil {
+			return err
+		}
+		input.LinkedUserID = userID
+		return nil
+	})
+}
+
+// The user whose data handlers read and write: the linked account's user when
+// AccountLinkingInterceptor found one, so the skill and the apps share it,
+// and the Alexa userId otherwise
+func (input *HandlerInput) UserID() string {
+	if input.LinkedUserID != "" {
+		return input.LinkedUserID
+	}
+	return input.Request.User().UserID
+}
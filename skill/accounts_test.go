@@ -0,0 +1,56 @@
+package skill
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mongodb-developer/alexa-golang-example/alexa"
+	"github.com/mongodb-developer/alexa-golang-example/linking"
+)
+
+// An Authenticator knowing the user of each access token
+type tokenAuth map[string]string
+
+func (auth tokenAuth) Authenticate(ctx context.Context, token string) (string, error) {
+	if userID, ok := auth[token]; ok {
+		return userID, nil
+	}
+	return "", linking.ErrUnauthenticated
+}
+
+// Invokes a skill with the interceptor for a request from user, and returns
+// the user the handler saw and the error handlers were given
+func userOf(t *testing.T, user alexa.User) (string, error) {
+	var seen string
+	var handled error
+	skill := New().AddRequestInterceptors(
+		AccountLinkingInterceptor(tokenAuth{"linked-token": "amzn1.account.LINKED"}),
+	).AddRequestHandlers(
+		FallbackHandler(func(input *HandlerInput) error {
+			seen = input.UserID()
+			return nil
+		}),
+	).AddErrorHandlers(
+		NewErrorHandler(IsUnlinked, func(input *HandlerInput, err error) error {
+			handled = err
+			return nil
+		}),
+	)
+	request := alexa.Request{Context: &alexa.Context{System: alexa.System{User: user}}}
+	if _, err := skill.Invoke(context.Background(), request); err != nil {
+		t.Fatal(err)
+	}
+	return seen, handled
+}
+
+func TestAccountLinkingInterceptor(t *testing.T) {
+	if userID, _ := userOf(t, alexa.User{UserID: "amzn1.ask.account.ALEXA", AccessToken: "linked-token"}); userID != "amzn1.account.LINKED" {
+		t.Errorf("got %q for a linked account, want the Login with Amazon user", userID)
+	}
+	if userID, _ := userOf(t, alexa.User{UserID: "amzn1.ask.account.ALEXA"}); userID != "amzn1.ask.account.ALEXA" {
+		t.Errorf("got %q without a linked account, want the Alexa user", userID)
+	}
+	if userID, err := userOf(t, alexa.User{UserID: "amzn1.ask.account.ALEXA", AccessToken: "expired-token"}); userID != "" || err == nil {
+		t.Errorf("got %q and error %v for a rejected token, want the request refused as unlinked", userID, err)
+	}
+}
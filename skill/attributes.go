@@ -0,0 +1,151 @@
+package skill
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/mongodb-developer/alexa-golang-example/alexa"
+	"github.com/mongodb-developer/alexa-golang-example/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Returned when persistent attributes are used by a skill without a PersistenceAdapter
+var ErrNoPersistence = errors.New("skill has no persistence adapter")
+
+// How long loading the persistent attributes may take, so a database that
+// can't be reached delays a request by no more than this
+const PersistenceTimeout = time.Second
+
+// Loads and saves attributes that outlive a session, usually per user
+type PersistenceAdapter interface {
+	Load(ctx context.Context, request alexa.Request) (map[string]interface{}, error)
+	Save(ctx context.Context, request alexa.Request, attributes map[string]interface{}) error
+}
+
+// Gives handlers access to session attributes, which are sent back to Alexa
+// with the response, and persistent attributes, which are loaded lazily
+type AttributesManager struct {
+	ctx        context.Context
+	request    alexa.Request
+	adapter    PersistenceAdapter
+	session    map[string]interface{}
+	persistent map[string]interface{}
+	loaded     bool
+	// Why the persistent attributes couldn't be loaded, so the load isn't
+	// tried again for the rest of the request
+	loadErr error
+	// The persistent attributes as JSON when last loaded or saved, to tell
+	// whether a handler changed them, however deeply
+	saved []byte
+}
+
+func newAttributesManager(ctx context.Context, request alexa.Request, adapter PersistenceAdapter) *AttributesManager {
+	session := make(map[string]interface{})
+	if request.Session != nil {
+		for key, value := range request.Session.Attributes {
+			session[key] = value.Interface()
+		}
+	}
+	return &AttributesManager{ctx: ctx, request: request, adapter: adapter, session: session}
+}
+
+// The session attributes, which handlers may modify in place
+func (manager *AttributesManager) SessionAttributes() map[string]interface{} {
+	return manager.session
+}
+
+// The persistent attributes, loaded on first use within PersistenceTimeout,
+// which handlers may modify in place before calling SavePersistentAttributes
+func (manager *AttributesManager) PersistentAttributes() (map[string]interface{}, error) {
+	if manager.adapter == nil {
+		return nil, ErrNoPersistence
+	}
+	if manager.loadErr != nil {
+		return nil, manager.loadErr
+	}
+	if !manager.loaded {
+		ctx, cancel := context.WithTimeout(manager.ctx, PersistenceTimeout)
+		attributes, err := manager.adapter.Load(ctx, manager.request)
+		cancel()
+		if err != nil {
+			manager.loadErr = err
+			return nil, err
+		}
+		if attributes == nil {
+			attributes = make(map[string]interface{})
+		}
+		manager.persistent, manager.loaded = attributes, true
+		manager.saved = encodeAttributes(attributes)
+	}
+	return manager.persistent, nil
+}
+
+// Reports whether the persistent attributes were changed since they were
+// loaded or last saved
+func (manager *AttributesManager) PersistentAttributesChanged() bool {
+	return manager.loaded && (manager.saved == nil || !bytes.Equal(encodeAttributes(manager.persistent), manager.saved))
+}
+
+// Saves the persistent attributes if a handler changed them
+func (manager *AttributesManager) SavePersistentAttributes() error {
+	if manager.adapter == nil {
+		return ErrNoPersistence
+	}
+	if !manager.PersistentAttributesChanged() {
+		return nil
+	}
+	if err := manager.adapter.Save(manager.ctx, manager.request, manager.persistent); err != nil {
+		return err
+	}
+	manager.saved = encodeAttributes(manager.persistent)
+	return nil
+}
+
+// The attributes as JSON, whose object keys are sorted so equal attributes
+// encode the same, or nil when they can't be encoded, which counts as changed.
+// Handlers keep nested maps and slices, such as the pantry's undo state, so
+// comparing the attributes themselves would miss changes made inside them.
+func encodeAttributes(attributes map[string]interface{}) []byte {
+	encoded, err := json.Marshal(attributes)
+	if err != nil {
+		return nil
+	}
+	return encoded
+}
+
+// Stores persistent attributes in a MongoDB collection, one document per user
+type MongoPersistenceAdapter struct {
+	collection *mongodb.Collection
+}
+
+func NewMongoPersistenceAdapter(collection *mongodb.Collection) *MongoPersistenceAdapter {
+	return &MongoPersistenceAdapter{collection: collection}
+}
+
+func (adapter *MongoPersistenceAdapter) Load(ctx context.Context, request alexa.Request) (map[string]interface{}, error) {
+	var document struct {
+		Attributes map[string]interface{} `bson:"attributes"`
+	}
+	err := adapter.collection.FindOne(ctx, bson.M{"_id": request.User().UserID}).Decode(&document)
+	if err == mongo.ErrNoDocuments {
+		return make(map[string]interface{}), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return document.Attributes, nil
+}
+
+func (adapter *MongoPersistenceAdapter) Save(ctx context.Context, request alexa.Request, attributes map[string]interface{}) error {
+	_, err := adapter.collection.UpdateOne(ctx,
+		bson.M{"_id": request.User().UserID},
+		bson.M{"$set": bson.M{"attributes": attributes}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
@@ -0,0 +1,125 @@
+package skill
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mongodb-developer/alexa-golang-example/alexa"
+)
+
+// A PersistenceAdapter keeping attributes in memory and counting saves
+type memoryAdapter struct {
+	attributes map[string]interface{}
+	saves      int
+}
+
+// A copy of attributes sharing nothing with them, as a database would keep
+func cloneAttributes(attributes map[string]interface{}) map[string]interface{} {
+	encoded, _ := json.Marshal(attributes)
+	var cloned map[string]interface{}
+	json.Unmarshal(encoded, &cloned)
+	return cloned
+}
+
+func (adapter *memoryAdapter) Load(ctx context.Context, request alexa.Request) (map[string]interface{}, error) {
+	return cloneAttributes(adapter.attributes), nil
+}
+
+func (adapter *memoryAdapter) Save(ctx context.Context, request alexa.Request, attributes map[string]interface{}) error {
+	adapter.saves++
+	adapter.attributes = cloneAttributes(attributes)
+	return nil
+}
+
+func TestSavePersistentAttributesOnlyWhenChanged(t *testing.T) {
+	adapter := &memoryAdapter{attributes: map[string]interface{}{"verbosity": "brief"}}
+	manager := newAttributesManager(context.Background(), alexa.Request{}, adapter)
+
+	attributes, err := manager.PersistentAttributes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	attributes["verbosity"] = "brief"
+	if err := manager.SavePersistentAttributes(); err != nil {
+		t.Fatal(err)
+	}
+	if adapter.saves != 0 {
+		t.Fatalf("saved %d times without a change", adapter.saves)
+	}
+
+	attributes["speechRate"] = "slow"
+	if err := manager.SavePersistentAttributes(); err != nil {
+		t.Fatal(err)
+	}
+	if adapter.saves != 1 || adapter.attributes["speechRate"] != "slow" {
+		t.Fatalf("got %d saves of %v, want the change saved once", adapter.saves, adapter.attributes)
+	}
+	if err := manager.SavePersistentAttributes(); err != nil {
+		t.Fatal(err)
+	}
+	if adapter.saves != 1 {
+		t.Fatal("saved again without a further change")
+	}
+}
+
+func TestSavePersistentAttributesWithoutLoading(t *testing.T) {
+	adapter := &memoryAdapter{}
+	manager := newAttributesManager(context.Background(), alexa.Request{}, adapter)
+	if err := manager.SavePersistentAttributes(); err != nil || adapter.saves != 0 {
+		t.Fatalf("got %v and %d saves, want nothing saved", err, adapter.saves)
+	}
+}
+
+// A PersistenceAdapter whose loads wait for the context and count themselves
+type hangingAdapter struct {
+	loads int
+}
+
+func (adapter *hangingAdapter) Load(ctx context.Context, request alexa.Request) (map[string]interface{}, error) {
+	adapter.loads++
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (adapter *hangingAdapter) Save(ctx context.Context, request alexa.Request, attributes map[string]interface{}) error {
+	return nil
+}
+
+func TestPersistentAttributesLoadFailsOnce(t *testing.T) {
+	adapter := &hangingAdapter{}
+	manager := newAttributesManager(context.Background(), alexa.Request{}, adapter)
+	started := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := manager.PersistentAttributes(); err == nil {
+			t.Fatal("loaded attributes from an unreachable database")
+		}
+	}
+	if elapsed := time.Since(started); adapter.loads != 1 || elapsed > 2*PersistenceTimeout {
+		t.Errorf("tried %d loads in %s, want one given up after %s", adapter.loads, elapsed, PersistenceTimeout)
+	}
+}
+
+func TestSavePersistentAttributesWhenNestedValuesChange(t *testing.T) {
+	adapter := &memoryAdapter{attributes: map[string]interface{}{
+		"undo": map[string]interface{}{"items": []interface{}{"rice"}},
+	}}
+	manager := newAttributesManager(context.Background(), alexa.Request{}, adapter)
+	attributes, err := manager.PersistentAttributes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	undo := attributes["undo"].(map[string]interface{})
+	undo["items"].([]interface{})[0] = "flour"
+	if err := manager.SavePersistentAttributes(); err != nil {
+		t.Fatal(err)
+	}
+	if adapter.saves != 1 || adapter.attributes["undo"].(map[string]interface{})["items"].([]interface{})[0] != "flour" {
+		t.Fatalf("got %d saves of %v, want the change inside the nested value saved", adapter.saves, adapter.attributes)
+	}
+	undo["items"] = append(undo["items"].([]interface{}), "milk")
+	if !manager.PersistentAttributesChanged() {
+		t.Fatal("a value appended to a nested slice isn't seen as a change")
+	}
+}
@@ -0,0 +1,176 @@
+package skill
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mongodb-developer/alexa-golang-example/alexa"
+)
+
+// Just enough of every supported event shape to tell them apart
+type eventProbe struct {
+	HTTPMethod     string `json:"httpMethod"`
+	RouteKey       string `json:"routeKey"`
+	RequestContext struct {
+		DomainName string           `json:"domainName"`
+		HTTP       *json.RawMessage `json:"http"`
+	} `json:"requestContext"`
+}
+
+// The result of dispatching an Alexa request carried in an HTTP event body
+type httpResult struct {
+	status int
+	body   string
+}
+
+var jsonHeaders = map[string]string{"Content-Type": "application/json"}
+
+// Processes a single Alexa request, such as Skill.Invoke
+type InvokeFunc func(ctx context.Context, request alexa.Request) (alexa.Response, error)
+
+// Verifies the requests that arrive over HTTP, sharing the certificates it has fetched
+var defaultVerifier = &Verifier{}
+
+// Returns a Lambda handler that accepts direct Alexa invocations as well as
+// Alexa requests wrapped in API Gateway REST and HTTP API proxy events or
+// Lambda Function URL events, answering each in the shape its caller expects.
+// Requests that arrive over HTTP must be signed by Alexa.
+func EventHandler(invoke InvokeFunc) func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	return func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return handleEvent(ctx, invoke, defaultVerifier, payload)
+	}
+}
+
+func handleEvent(ctx context.Context, invoke InvokeFunc, verifier *Verifier, payload json.RawMessage) (interface{}, error) {
+	var probe eventProbe
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return nil, err
+	}
+	switch {
+	case probe.HTTPMethod != "":
+		var event events.APIGatewayProxyRequest
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, err
+		}
+		result := dispatchHTTP(ctx, invoke, verifier, event.HTTPMethod, headerOf(event.Headers, event.MultiValueHeaders), event.Body, event.IsBase64Encoded)
+		return events.APIGatewayProxyResponse{StatusCode: result.status, Headers: jsonHeaders, Body: result.body}, nil
+	case probe.RequestContext.HTTP != nil && isFunctionURL(probe):
+		var event events.LambdaFunctionURLRequest
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, err
+		}
+		result := dispatchHTTP(ctx, invoke, verifier, event.RequestContext.HTTP.Method, headerOf(event.Headers, nil), event.Body, event.IsBase64Encoded)
+		return events.LambdaFunctionURLResponse{StatusCode: result.status, Headers: jsonHeaders, Body: result.body}, nil
+	case probe.RequestContext.HTTP != nil:
+		var event events.APIGatewayV2HTTPRequest
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, err
+		}
+		result := dispatchHTTP(ctx, invoke, verifier, event.RequestContext.HTTP.Method, headerOf(event.Headers, nil), event.Body, event.IsBase64Encoded)
+		return events.APIGatewayV2HTTPResponse{StatusCode: result.status, Headers: jsonHeaders, Body: result.body}, nil
+	default:
+		var request alexa.Request
+		if err := json.Unmarshal(payload, &request); err != nil {
+			return nil, err
+		}
+		return invoke(ctx, request)
+	}
+}
+
+// Returns an HTTP handler that dispatches the Alexa request in the body of
+// each POST signed by Alexa, for running the skill as a plain web server
+func HTTPHandler(invoke InvokeFunc) http.Handler {
+	return NewHTTPHandler(invoke, defaultVerifier)
+}
+
+// Returns an HTTP handler that checks requests with verifier. A nil verifier
+// accepts requests Alexa didn't sign, such as those of a load test.
+func NewHTTPHandler(invoke InvokeFunc, verifier *Verifier) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, err := ioutil.ReadAll(request.Body)
+		result := errorResult(http.StatusBadRequest)
+		if err == nil {
+			result = dispatchHTTP(request.Context(), invoke, verifier, request.Method, request.Header, string(body), false)
+		}
+		for name, value := range jsonHeaders {
+			writer.Header().Set(name, value)
+		}
+		writer.WriteHeader(result.status)
+		io.WriteString(writer, result.body)
+	})
+}
+
+// Function URL events share the HTTP API v2 payload format, but always use
+// the $default route and a lambda-url domain
+func isFunctionURL(probe eventProbe) bool {
+	return (probe.RouteKey == "" || probe.RouteKey == "$default") &&
+		strings.Contains(probe.RequestContext.DomainName, ".lambda-url.")
+}
+
+// The headers of a proxy event, which keep the case they were sent in or are
+// lowercased. REST API events may carry them only as multiple values, which
+// take precedence.
+func headerOf(headers map[string]string, multiValueHeaders map[string][]string) http.Header {
+	header := make(http.Header, len(headers))
+	for name, values := range multiValueHeaders {
+		for _, value := range values {
+			header.Add(name, value)
+		}
+	}
+	for name, value := range headers {
+		if _, ok := header[http.CanonicalHeaderKey(name)]; !ok {
+			header.Set(name, value)
+		}
+	}
+	return header
+}
+
+func dispatchHTTP(ctx context.Context, invoke InvokeFunc, verifier *Verifier, method string, header http.Header, body string, isBase64Encoded bool) httpResult {
+	if method != http.MethodPost {
+		return errorResult(http.StatusMethodNotAllowed)
+	}
+	if isBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return errorResult(http.StatusBadRequest)
+		}
+		body = string(decoded)
+	}
+	if verifier != nil {
+		if err := verifier.Verify(ctx, header, []byte(body)); errors.Is(err, ErrUnverified) {
+			log.Printf("rejected request: %v", err)
+			return errorResult(http.StatusBadRequest)
+		} else if err != nil {
+			log.Printf("unable to verify request: %v", err)
+			return errorResult(http.StatusInternalServerError)
+		}
+	}
+	var request alexa.Request
+	if err := json.Unmarshal([]byte(body), &request); err != nil {
+		return errorResult(http.StatusBadRequest)
+	}
+	response, err := invoke(ctx, request)
+	if err != nil {
+		log.Printf("unable to dispatch request: %v", err)
+		return errorResult(http.StatusInternalServerError)
+	}
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("unable to encode response: %v", err)
+		return errorResult(http.StatusInternalServerError)
+	}
+	return httpResult{status: http.StatusOK, body: string(encoded)}
+}
+
+func errorResult(status int) httpResult {
+	encoded, _ := json.Marshal(map[string]string{"message": http.StatusText(status)})
+	return httpResult{status: status, body: string(encoded)}
+}
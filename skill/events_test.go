@@ -0,0 +1,136 @@
+package skill
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mongodb-developer/alexa-golang-example/alexa"
+)
+
+// The fixture in testdata/events with the placeholders filled in from a
+// request the signer signed
+func eventFixture(t *testing.T, name string, method string, request *http.Request) json.RawMessage {
+	template, err := ioutil.ReadFile(filepath.Join("testdata", "events", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return json.RawMessage(strings.NewReplacer(
+		"$BASE64_BODY", strconv.Quote(base64.StdEncoding.EncodeToString(body)),
+		"$BODY", strconv.Quote(string(body)),
+		"$METHOD", method,
+		"$CHAIN", request.Header.Get("SignatureCertChainUrl"),
+		"$SIGNATURE", request.Header.Get("Signature-256"),
+	).Replace(string(template)))
+}
+
+func TestHandleEventAnswersEachShape(t *testing.T) {
+	signer := newTestSigner(t, alexaCertName)
+	tests := []struct {
+		fixture string
+		method  string
+		want    interface{}
+	}{
+		{"apigateway-rest.json", http.MethodPost, events.APIGatewayProxyResponse{}},
+		{"apigateway-rest-multivalue.json", http.MethodPost, events.APIGatewayProxyResponse{}},
+		{"apigateway-http.json", http.MethodPost, events.APIGatewayV2HTTPResponse{}},
+		{"function-url.json", http.MethodPost, events.LambdaFunctionURLResponse{}},
+		{"apigateway-rest.json", http.MethodGet, events.APIGatewayProxyResponse{}},
+		{"function-url.json", http.MethodGet, events.LambdaFunctionURLResponse{}},
+	}
+	for _, test := range tests {
+		invoked := false
+		invoke := func(ctx context.Context, request alexa.Request) (alexa.Response, error) {
+			invoked = request.Body.Type == "LaunchRequest"
+			return alexa.Response{Version: "1.0"}, nil
+		}
+		payload := eventFixture(t, test.fixture, test.method, signer.request(t, time.Now()))
+		result, err := handleEvent(context.Background(), invoke, signer.verifier(), payload)
+		if err != nil {
+			t.Fatalf("%s: %v", test.fixture, err)
+		}
+		if reflect.TypeOf(result) != reflect.TypeOf(test.want) {
+			t.Errorf("%s: got a %T, want a %T", test.fixture, result, test.want)
+			continue
+		}
+		status := reflect.ValueOf(result).FieldByName("StatusCode").Interface().(int)
+		if want := test.method == http.MethodPost; invoked != want || (status == http.StatusOK) != want {
+			t.Errorf("%s over %s: got status %d, invoked %v", test.fixture, test.method, status, invoked)
+		}
+	}
+}
+
+func TestHandleEventRejectsUnsignedEvents(t *testing.T) {
+	signer := newTestSigner(t, alexaCertName)
+	request := signer.request(t, time.Now())
+	request.Header.Del("Signature-256")
+	invoked := false
+	invoke := func(ctx context.Context, request alexa.Request) (alexa.Response, error) {
+		invoked = true
+		return alexa.Response{}, nil
+	}
+	result, err := handleEvent(context.Background(), invoke, signer.verifier(), eventFixture(t, "apigateway-rest.json", http.MethodPost, request))
+	if response, ok := result.(events.APIGatewayProxyResponse); err != nil || !ok || response.StatusCode != http.StatusBadRequest || invoked {
+		t.Errorf("got %+v, %v, invoked %v, want the event rejected", result, err, invoked)
+	}
+}
+
+func TestHandleEventInvokesDirectly(t *testing.T) {
+	payload := json.RawMessage(`{"version":"1.0","session":{"sessionId":"session"},"request":{"type":"LaunchRequest"}}`)
+	invoke := func(ctx context.Context, request alexa.Request) (alexa.Response, error) {
+		return alexa.Response{Version: request.Session.SessionID}, nil
+	}
+	// Direct invocations are signed by Lambda, not Alexa, so aren't verified
+	result, err := handleEvent(context.Background(), invoke, &Verifier{}, payload)
+	if response, ok := result.(alexa.Response); err != nil || !ok || response.Version != "session" {
+		t.Errorf("got %+v, %v, want the skill's response", result, err)
+	}
+}
+
+func TestIsFunctionURL(t *testing.T) {
+	tests := []struct {
+		routeKey, domainName string
+		want                 bool
+	}{
+		{"$default", "abc123.lambda-url.us-east-1.on.aws", true},
+		{"", "abc123.lambda-url.eu-west-1.on.aws", true},
+		{"POST /alexa", "abc123.lambda-url.us-east-1.on.aws", false},
+		{"$default", "abc123.execute-api.us-east-1.amazonaws.com", false},
+		{"$default", "alexa.example.com", false},
+	}
+	for _, test := range tests {
+		var probe eventProbe
+		probe.RouteKey, probe.RequestContext.DomainName = test.routeKey, test.domainName
+		if got := isFunctionURL(probe); got != test.want {
+			t.Errorf("route %q on %s: got %v, want %v", test.routeKey, test.domainName, got, test.want)
+		}
+	}
+}
+
+func TestHeaderOf(t *testing.T) {
+	header := headerOf(
+		map[string]string{"signature-256": "single", "content-type": "application/json"},
+		map[string][]string{"Signature-256": {"multi"}, "x-forwarded-for": {"1.2.3.4", "5.6.7.8"}},
+	)
+	want := http.Header{
+		"Signature-256":   {"multi"},
+		"Content-Type":    {"application/json"},
+		"X-Forwarded-For": {"1.2.3.4", "5.6.7.8"},
+	}
+	if !reflect.DeepEqual(header, want) {
+		t.Errorf("got %v, want %v", header, want)
+	}
+}
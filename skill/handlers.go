@@ -0,0 +1,94 @@
+package skill
+
+import "github.com/mongodb-developer/alexa-golang-example/alexa"
+
+// Adapts a function to the Handle half of a Handler
+type HandlerFunc func(input *HandlerInput) error
+
+type predicateHandler struct {
+	canHandle func(input *HandlerInput) bool
+	handle    HandlerFunc
+}
+
+func (handler predicateHandler) CanHandle(input *HandlerInput) bool {
+	return handler.canHandle(input)
+}
+
+func (handler predicateHandler) Handle(input *HandlerInput) error {
+	return handler.handle(input)
+}
+
+// Creates a Handler from a pair of functions
+func NewHandler(canHandle func(input *HandlerInput) bool, handle HandlerFunc) Handler {
+	return predicateHandler{canHandle: canHandle, handle: handle}
+}
+
+// Handles IntentRequests for any of the named intents
+func IntentHandler(handle HandlerFunc, intentNames ...string) Handler {
+	return NewHandler(func(input *HandlerInput) bool {
+		if input.Request.Body.Type != alexa.IntentRequest {
+			return false
+		}
+		for _, name := range intentNames {
+			if input.Request.Body.IntentName() == name {
+				return true
+			}
+		}
+		return false
+	}, handle)
+}
+
+// Handles every request of the given type, such as LaunchRequest
+func RequestTypeHandler(requestType string, handle HandlerFunc) Handler {
+	return NewHandler(func(input *HandlerInput) bool {
+		return input.Request.Body.Type == requestType
+	}, handle)
+}
+
+// Handles every request, for use as the last handler of a skill
+func FallbackHandler(handle HandlerFunc) Handler {
+	return NewHandler(func(input *HandlerInput) bool { return true }, handle)
+}
+
+// Adapts a function to a RequestInterceptor
+type RequestInterceptorFunc func(input *HandlerInput) error
+
+func (interceptor RequestInterceptorFunc) Process(input *HandlerInput) error {
+	return interceptor(input)
+}
+
+// Adapts a function to a ResponseInterceptor
+type ResponseInterceptorFunc func(input *HandlerInput, response *alexa.Response) error
+
+func (interceptor ResponseInterceptorFunc) Process(input *HandlerInput, response *alexa.Response) error {
+	return interceptor(input, response)
+}
+
+type predicateErrorHandler struct {
+	canHandle func(input *HandlerInput, err error) bool
+	handle    func(input *HandlerInput, err error) error
+}
+
+func (handler predicateErrorHandler) CanHandle(input *HandlerInput, err error) bool {
+	return handler.canHandle(input, err)
+}
+
+func (handler predicateErrorHandler) Handle(input *HandlerInput, err error) error {
+	return handler.handle(input, err)
+}
+
+// Creates an ErrorHandler from a pair of functions
+func NewErrorHandler(canHandle func(input *HandlerInput, err error) bool, handle func(input *HandlerInput, err error) error) ErrorHandler {
+	return predicateErrorHandler{canHandle: canHandle, handle: handle}
+}
+
+// Handles every error by speaking the given apology and ending the session
+func SpeakErrorHandler(title string, speech string) ErrorHandler {
+	return NewErrorHandler(
+		func(input *HandlerInput, err error) bool { return true },
+		func(input *HandlerInput, err error) error {
+			input.Response.Speak(speech).SimpleCard(title, speech)
+			return nil
+		},
+	)
+}
@@ -0,0 +1,259 @@
+package skill
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mongodb-developer/alexa-golang-example/alexa"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Loads the persistent attributes before the handler runs. Failing to load
+// them is logged rather than failing the request, so answers that don't need
+// them, such as recipes served from a cache or snapshot during an outage,
+// are still given; handlers asking for them get the error instead. Skills
+// without a persistence adapter are left alone.
+func LoadAttributesInterceptor() RequestInterceptor {
+	return RequestInterceptorFunc(func(input *HandlerInput) error {
+		if _, err := input.Attributes.PersistentAttributes(); err != nil && err != ErrNoPersistence {
+			log.Printf("unable to load persistent attributes for %s: %v", input.Request.Body.RequestID, err)
+		}
+		return nil
+	})
+}
+
+// Saves the persistent attributes once the response is built, if a handler
+// changed them
+func SaveAttributesInterceptor() ResponseInterceptor {
+	return ResponseInterceptorFunc(func(input *HandlerInput, response *alexa.Response) error {
+		if err := input.Attributes.SavePersistentAttributes(); err != nil && err != ErrNoPersistence {
+			return err
+		}
+		return nil
+	})
+}
+
+// Localized strings, keyed by message name
+type Messages map[string]string
+
+// Formats the named message with fmt.Sprintf, returning the name itself when
+// the message is missing so gaps are audible rather than silent
+func (messages Messages) Format(name string, args ...interface{}) string {
+	format, ok := messages[name]
+	if !ok {
+		return name
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Messages for every supported locale, keyed by locale ("en-GB") or language ("en")
+type Catalog map[string]Messages
+
+// The messages for a locale, falling back to the language and then to the
+// default locale for anything not translated
+func (catalog Catalog) For(locale string, defaultLocale string) Messages {
+	messages := make(Messages)
+	candidates := []string{defaultLocale, strings.SplitN(defaultLocale, "-", 2)[0], strings.SplitN(locale, "-", 2)[0], locale}
+	for _, candidate := range candidates {
+		for name, text := range catalog[candidate] {
+			messages[name] = text
+		}
+	}
+	return messages
+}
+
+// Sets the locale of the request, or defaultLocale when the request has none,
+// and the messages to speak in it
+func LocaleInterceptor(catalog Catalog, defaultLocale string) RequestInterceptor {
+	return RequestInterceptorFunc(func(input *HandlerInput) error {
+		input.Locale = input.Request.Body.Locale
+		if input.Locale == "" {
+			input.Locale = defaultLocale
+		}
+		input.Messages = catalog.For(input.Locale, defaultLocale)
+		return nil
+	})
+}
+
+// Logs the type, intent and locale of every request
+func LoggingInterceptor(logger *log.Logger) RequestInterceptor {
+	return RequestInterceptorFunc(func(input *HandlerInput) error {
+		body := input.Request.Body
+		logger.Printf("request %s: type=%s intent=%s locale=%s", body.RequestID, body.Type, body.IntentName(), body.Locale)
+		return nil
+	})
+}
+
+// A summary of a handled request for usage analytics
+type AnalyticsEvent struct {
+	RequestID     string        `json:"requestId" bson:"requestId"`
+	ApplicationID string        `json:"applicationId" bson:"applicationId"`
+	UserID        string        `json:"userId" bson:"userId"`
+	RequestType   string        `json:"requestType" bson:"requestType"`
+	Intent        string        `json:"intent,omitempty" bson:"intent,omitempty"`
+	Locale        string        `json:"locale,omitempty" bson:"locale,omitempty"`
+	Duration      time.Duration `json:"durationNanos" bson:"durationNanos"`
+	EndedSession  bool          `json:"endedSession" bson:"endedSession"`
+	Timestamp     time.Time     `json:"timestamp" bson:"timestamp"`
+}
+
+// Stores analytics events
+type AnalyticsRecorder interface {
+	Record(ctx context.Context, event AnalyticsEvent) error
+}
+
+// Writes analytics events to a logger as JSON, for CloudWatch Logs Insights
+type LogAnalyticsRecorder struct {
+	Logger *log.Logger
+}
+
+func (recorder LogAnalyticsRecorder) Record(ctx context.Context, event AnalyticsEvent) error {
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	recorder.Logger.Printf("analytics %s", encoded)
+	return nil
+}
+
+// Inserts analytics events into a MongoDB collection
+type MongoAnalyticsRecorder struct {
+	Collection *mongo.Collection
+}
+
+func (recorder MongoAnalyticsRecorder) Record(ctx context.Context, event AnalyticsEvent) error {
+	_, err := recorder.Collection.InsertOne(ctx, event)
+	return err
+}
+
+// Records an analytics event for every response. Failing to record is logged
+// rather than failing the request.
+func AnalyticsInterceptor(recorder AnalyticsRecorder) ResponseInterceptor {
+	return ResponseInterceptorFunc(func(input *HandlerInput, response *alexa.Response) error {
+		event := AnalyticsEvent{
+			RequestID:     input.Request.Body.RequestID,
+			ApplicationID: input.Request.ApplicationID(),
+			UserID:        input.Request.User().UserID,
+			RequestType:   input.Request.Body.Type,
+			Intent:        input.Request.Body.IntentName(),
+			Locale:        input.Locale,
+			Duration:      time.Since(input.StartedAt),
+			EndedSession:  response.Body.ShouldEndSession != nil && *response.Body.ShouldEndSession,
+			Timestamp:     input.StartedAt,
+		}
+		if err := recorder.Record(input.Context, event); err != nil {
+			log.Printf("unable to record analytics for %s: %v", event.RequestID, err)
+		}
+		return nil
+	})
+}
+
+// The most characters Alexa accepts in output speech, reprompts and cards
+const MaxSpeechLength = 8000
+
+// Shortens output speech, reprompts and card text that exceed maxLength,
+// cutting at the last sentence, or else word, that fits and keeping SSML
+// well formed
+func SSMLLimitInterceptor(maxLength int) ResponseInterceptor {
+	return ResponseInterceptorFunc(func(input *HandlerInput, response *alexa.Response) error {
+		limitSpeech(response.Body.OutputSpeech, maxLength)
+		if response.Body.Reprompt != nil {
+			limitSpeech(response.Body.Reprompt.OutputSpeech, maxLength)
+		}
+		if card := response.Body.Card; card != nil {
+			card.Content = truncateText(card.Content, maxLength)
+			card.Text = truncateText(card.Text, maxLength)
+		}
+		return nil
+	})
+}
+
+func limitSpeech(speech *alexa.OutputSpeech, maxLength int) {
+	if speech == nil {
+		return
+	}
+	speech.Text = truncateText(speech.Text, maxLength)
+	speech.SSML = truncateSSML(speech.SSML, maxLength)
+}
+
+// Cuts plain text at the last sentence end that fits, or the last word end
+// when no sentence ends in time, without splitting a character
+func truncateText(text string, maxLength int) string {
+	if len(text) <= maxLength {
+		return text
+	}
+	cut := text[:maxLength]
+	for len(cut) > 0 && !utf8.RuneStart(text[len(cut)]) {
+		cut = cut[:len(cut)-1]
+	}
+	if end := strings.LastIndexAny(cut, ".!?"); end > 0 {
+		return cut[:end+1]
+	}
+	if space := strings.LastIndexByte(cut, ' '); space > 0 {
+		return cut[:space]
+	}
+	return cut
+}
+
+// Cuts SSML at the last sentence end outside a tag that fits once the tags
+// open at that point are closed again, or at the last word end or tag when
+// no sentence ends in time, such as in a long list of ingredients
+func truncateSSML(ssml string, maxLength int) string {
+	if len(ssml) <= maxLength {
+		return ssml
+	}
+	var open []string
+	sentenceCut, sentenceClosing := -1, ""
+	wordCut, wordClosing := -1, ""
+	// The tags that close those open, if cutting at cut leaves room for them
+	closing := func(cut int) (string, bool) {
+		var tags strings.Builder
+		for i := len(open) - 1; i >= 0; i-- {
+			tags.WriteString("</" + open[i] + ">")
+		}
+		return tags.String(), cut+tags.Len() <= maxLength
+	}
+	for i := 0; i < len(ssml) && i <= maxLength; i++ {
+		switch ssml[i] {
+		case '<':
+			end := strings.IndexByte(ssml[i:], '>')
+			if end < 0 {
+				i = len(ssml)
+				continue
+			}
+			tag := ssml[i+1 : i+end]
+			switch {
+			case strings.HasPrefix(tag, "/"):
+				if len(open) > 0 {
+					open = open[:len(open)-1]
+				}
+			case !strings.HasSuffix(tag, "/") && len(strings.Fields(tag)) > 0:
+				open = append(open, strings.Fields(tag)[0])
+			}
+			i += end
+			if tags, ok := closing(i + 1); ok {
+				wordCut, wordClosing = i+1, tags
+			}
+		case ' ':
+			if tags, ok := closing(i); ok {
+				wordCut, wordClosing = i, tags
+			}
+		case '.', '!', '?':
+			if tags, ok := closing(i + 1); ok {
+				sentenceCut, sentenceClosing = i+1, tags
+			}
+		}
+	}
+	switch {
+	case sentenceCut >= 0:
+		return ssml[:sentenceCut] + sentenceClosing
+	case wordCut >= 0:
+		return ssml[:wordCut] + wordClosing
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package skill
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		text      string
+		maxLength int
+		want      string
+	}{
+		{"Short enough.", 20, "Short enough."},
+		{"First sentence. Second sentence.", 20, "First sentence."},
+		// Without a sentence end in time, the last whole word is kept
+		{"flour, milk, eggs, butter, sugar", 20, "flour, milk, eggs,"},
+		// é is two bytes, and the cut falls between them
+		{"crème brûlée", 9, "crème"},
+		{"éééé", 3, "é"},
+	}
+	for _, test := range tests {
+		got := truncateText(test.text, test.maxLength)
+		if got != test.want || !utf8.ValidString(got) {
+			t.Errorf("truncateText(%q, %d) = %q, want %q", test.text, test.maxLength, got, test.want)
+		}
+	}
+}
+
+func TestTruncateSSML(t *testing.T) {
+	tests := []struct {
+		ssml      string
+		maxLength int
+		want      string
+	}{
+		{"<speak>Short.</speak>", 30, "<speak>Short.</speak>"},
+		{"<speak>One. Two. Three.</speak>", 24, "<speak>One. Two.</speak>"},
+		// Tags open at the cut are closed, and must fit too
+		{`<speak><prosody rate="slow">One. Two. Three.</prosody></speak>`, 50, `<speak><prosody rate="slow">One.</prosody></speak>`},
+		// A list without sentence ends is cut after a word rather than emptied
+		{"<speak>flour, milk, eggs, butter, sugar, salt</speak>", 40, "<speak>flour, milk, eggs,</speak>"},
+		{`<speak><emphasis>flour</emphasis>, milk</speak>`, 42, "<speak><emphasis>flour</emphasis>,</speak>"},
+		// Or after a tag when there isn't a whole word
+		{"<speak><emphasis>flourmilkeggs</emphasis></speak>", 40, "<speak><emphasis></emphasis></speak>"},
+	}
+	for _, test := range tests {
+		got := truncateSSML(test.ssml, test.maxLength)
+		if got != test.want {
+			t.Errorf("truncateSSML(%q, %d) = %q, want %q", test.ssml, test.maxLength, got, test.want)
+		}
+		if len(got) > test.maxLength {
+			t.Errorf("truncateSSML(%q, %d) is %d long", test.ssml, test.maxLength, len(got))
+		}
+	}
+
+	long := "<speak>" + strings.Repeat("noodles, ", 1000) + "</speak>"
+	if got := truncateSSML(long, MaxSpeechLength); !strings.HasSuffix(got, "noodles,</speak>") || len(got) > MaxSpeechLength {
+		t.Errorf("got %d characters ending %q, want the list cut after a word", len(got), got[len(got)-20:])
+	}
+}
@@ -0,0 +1,275 @@
+package skill
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mongodb-developer/alexa-golang-example/alexa"
+)
+
+// The lexicon read when LEXICON_PATH isn't set
+const DefaultLexiconPath = "pronunciations.json"
+
+// How to say a word or phrase Alexa gets wrong, by its IPA or X-SAMPA
+// phonemes or by the language it comes from. An entry with none of them
+// records that Alexa's own pronunciation is fine.
+type Pronunciation struct {
+	Word   string `json:"word"`
+	IPA    string `json:"ipa,omitempty"`
+	XSAMPA string `json:"xSampa,omitempty"`
+	// A locale such as "it-IT", spoken with a <lang> tag
+	Lang string `json:"lang,omitempty"`
+}
+
+// Whether the entry changes how the word is spoken
+func (pronunciation Pronunciation) Marked() bool {
+	return pronunciation.IPA != "" || pronunciation.XSAMPA != "" || pronunciation.Lang != ""
+}
+
+// Pronunciations by word, applied to speech by PronunciationInterceptor
+type Lexicon struct {
+	byWord map[string]Pronunciation
+	// Matches the marked words, longest first so phrases win over the words in them
+	pattern *regexp.Regexp
+}
+
+func NewLexicon(entries []Pronunciation) *Lexicon {
+	lexicon := &Lexicon{byWord: make(map[string]Pronunciation)}
+	for _, entry := range entries {
+		entry.Word = strings.ToLower(strings.TrimSpace(entry.Word))
+		lexicon.byWord[entry.Word] = entry
+	}
+	lexicon.compile()
+	return lexicon
+}
+
+// Reads a lexicon written by Save
+func LoadLexicon(path string) (*Lexicon, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Pronunciation
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	return NewLexicon(entries), nil
+}
+
+// Writes the lexicon as JSON, one entry per line in alphabetical order so
+// changes to it are easy to review
+func (lexicon *Lexicon) Save(path string) error {
+	var lines []string
+	for _, entry := range lexicon.Entries() {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, "  "+string(line))
+	}
+	return ioutil.WriteFile(path, []byte("[\n"+strings.Join(lines, ",\n")+"\n]\n"), os.FileMode(0644))
+}
+
+// Every entry, in alphabetical order
+func (lexicon *Lexicon) Entries() []Pronunciation {
+	var entries []Pronunciation
+	for _, entry := range lexicon.byWord {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Word < entries[j].Word })
+	return entries
+}
+
+// Adds an entry, replacing any for the same word
+func (lexicon *Lexicon) Set(entry Pronunciation) {
+	entry.Word = strings.ToLower(strings.TrimSpace(entry.Word))
+	lexicon.byWord[entry.Word] = entry
+	lexicon.compile()
+}
+
+func (lexicon *Lexicon) compile() {
+	var marked []string
+	for word, entry := range lexicon.byWord {
+		if entry.Marked() {
+			marked = append(marked, word)
+		}
+	}
+	sort.Slice(marked, func(i, j int) bool { return len(marked[i]) > len(marked[j]) })
+	lexicon.pattern = nil
+	if len(marked) > 0 {
+		for i, word := range marked {
+			marked[i] = regexp.QuoteMeta(word)
+		}
+		lexicon.pattern = regexp.MustCompile("(?i)" + strings.Join(marked, "|"))
+	}
+}
+
+// Reports whether the word has an entry of its own or is part of a phrase that has
+func (lexicon *Lexicon) Covers(word string) bool {
+	word = strings.ToLower(word)
+	if _, ok := lexicon.byWord[word]; ok {
+		return true
+	}
+	for phrase := range lexicon.byWord {
+		for _, part := range strings.Fields(phrase) {
+			if part == word {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// A word no entry covers and how many groups of texts it is used in
+type MissingWord struct {
+	Word string
+	Uses int
+}
+
+// The words of the texts, such as the name and ingredients of each recipe,
+// that have no entry, the most used first. A word is counted once per group.
+func (lexicon *Lexicon) Missing(groups [][]string) []MissingWord {
+	uses := make(map[string]int)
+	for _, texts := range groups {
+		seen := make(map[string]bool)
+		for _, text := range texts {
+			for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+				return !unicode.IsLetter(r) && r != '\''
+			}) {
+				if !seen[word] && !lexicon.Covers(word) {
+					seen[word] = true
+					uses[word]++
+				}
+			}
+		}
+	}
+	var missing []MissingWord
+	for word, count := range uses {
+		missing = append(missing, MissingWord{Word: word, Uses: count})
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		if missing[i].Uses != missing[j].Uses {
+			return missing[i].Uses > missing[j].Uses
+		}
+		return missing[i].Word < missing[j].Word
+	})
+	return missing
+}
+
+// Converts plain text to SSML, marking up the words in the lexicon
+func (lexicon *Lexicon) Markup(text string) string {
+	return lexicon.markup(text, EscapeSSML)
+}
+
+// Marks up the whole words in text that are in the lexicon, escaping the rest with escape
+func (lexicon *Lexicon) markup(text string, escape func(string) string) string {
+	if lexicon == nil || lexicon.pattern == nil {
+		return escape(text)
+	}
+	var marked strings.Builder
+	last := 0
+	for _, match := range lexicon.pattern.FindAllStringIndex(text, -1) {
+		start, end := match[0], match[1]
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if isWordRune(before) || isWordRune(after) {
+			continue
+		}
+		marked.WriteString(escape(text[last:start]))
+		marked.WriteString(lexicon.tag(text[start:end], escape))
+		last = end
+	}
+	marked.WriteString(escape(text[last:]))
+	return marked.String()
+}
+
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+var attributeEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// Wraps a word as spoken in the tag for its pronunciation
+func (lexicon *Lexicon) tag(spoken string, escape func(string) string) string {
+	entry := lexicon.byWord[strings.ToLower(spoken)]
+	switch {
+	case entry.IPA != "":
+		return `<phoneme alphabet="ipa" ph="` + attributeEscaper.Replace(entry.IPA) + `">` + escape(spoken) + "</phoneme>"
+	case entry.XSAMPA != "":
+		return `<phoneme alphabet="x-sampa" ph="` + attributeEscaper.Replace(entry.XSAMPA) + `">` + escape(spoken) + "</phoneme>"
+	}
+	return `<lang xml:lang="` + attributeEscaper.Replace(entry.Lang) + `">` + escape(spoken) + "</lang>"
+}
+
+// Tags whose text is already marked up and is left alone
+var pronouncedTags = map[string]bool{"phoneme": true, "lang": true, "say-as": true, "sub": true}
+
+// Marks up the text between the tags of SSML, which is already escaped
+func (lexicon *Lexicon) markupSSML(ssml string) string {
+	var marked strings.Builder
+	skipping := 0
+	for len(ssml) > 0 {
+		next := strings.IndexByte(ssml, '<')
+		if next < 0 {
+			next = len(ssml)
+		}
+		if skipping == 0 {
+			marked.WriteString(lexicon.markup(ssml[:next], func(text string) string { return text }))
+		} else {
+			marked.WriteString(ssml[:next])
+		}
+		ssml = ssml[next:]
+		if len(ssml) == 0 {
+			break
+		}
+		end := strings.IndexByte(ssml, '>')
+		if end < 0 {
+			marked.WriteString(ssml)
+			break
+		}
+		tag := ssml[1:end]
+		if fields := strings.Fields(strings.TrimPrefix(tag, "/")); len(fields) > 0 && pronouncedTags[fields[0]] && !strings.HasSuffix(tag, "/") {
+			if strings.HasPrefix(tag, "/") {
+				skipping--
+			} else {
+				skipping++
+			}
+		}
+		marked.WriteString(ssml[:end+1])
+		ssml = ssml[end+1:]
+	}
+	return marked.String()
+}
+
+// Marks up the words in the lexicon wherever they are spoken, in the output
+// speech and the reprompt, turning plain text into SSML when a word is found.
+// Add it before SSMLLimitInterceptor.
+func PronunciationInterceptor(lexicon *Lexicon) ResponseInterceptor {
+	return ResponseInterceptorFunc(func(input *HandlerInput, response *alexa.Response) error {
+		lexicon.pronounce(response.Body.OutputSpeech)
+		if response.Body.Reprompt != nil {
+			lexicon.pronounce(response.Body.Reprompt.OutputSpeech)
+		}
+		return nil
+	})
+}
+
+func (lexicon *Lexicon) pronounce(speech *alexa.OutputSpeech) {
+	if speech == nil || lexicon == nil {
+		return
+	}
+	if speech.Type == alexa.SSML {
+		speech.SSML = lexicon.markupSSML(speech.SSML)
+		return
+	}
+	escaped := EscapeSSML(speech.Text)
+	if marked := lexicon.Markup(speech.Text); marked != escaped {
+		speech.Type, speech.Text, speech.SSML = alexa.SSML, "", "<speak>"+marked+"</speak>"
+	}
+}
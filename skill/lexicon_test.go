@@ -0,0 +1,134 @@
+package skill
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mongodb-developer/alexa-golang-example/alexa"
+)
+
+// The name and ingredients of each recipe in testdata/recipes.json
+func recipeTexts(t *testing.T) [][]string {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "recipes.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recipes []struct {
+		Name        string   `json:"name"`
+		Ingredients []string `json:"ingredients"`
+	}
+	if err := json.Unmarshal(data, &recipes); err != nil {
+		t.Fatal(err)
+	}
+	var groups [][]string
+	for _, recipe := range recipes {
+		groups = append(groups, append([]string{recipe.Name}, recipe.Ingredients...))
+	}
+	return groups
+}
+
+func describe(words []MissingWord) string {
+	var described []string
+	for _, word := range words {
+		described = append(described, fmt.Sprintf("%s (%d)", word.Word, word.Uses))
+	}
+	return strings.Join(described, ", ")
+}
+
+// Fails for every word of the sample recipes the shipped lexicon has no entry for
+func TestLexiconCoversSampleRecipes(t *testing.T) {
+	lexicon, err := LoadLexicon(filepath.Join("..", DefaultLexiconPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing := lexicon.Missing(recipeTexts(t)); len(missing) > 0 {
+		t.Errorf("no pronunciation for %s; add them with go run ./cmd/lexicon add", describe(missing))
+	}
+}
+
+func TestMissingListsUncoveredWords(t *testing.T) {
+	lexicon := NewLexicon([]Pronunciation{
+		{Word: "gnocchi", IPA: "ˈnjoʊki"},
+		{Word: "rice noodles"},
+		{Word: "onion"},
+	})
+	missing := lexicon.Missing([][]string{
+		{"Gnocchi Bolognese", "gnocchi", "garlic", "onion", "garlic"},
+		{"Chicken Pho", "chicken", "rice noodles", "Garlic"},
+	})
+	// Garlic is used by both recipes, and counted once in the first; noodles
+	// and rice are covered by the phrase
+	if got, want := describe(missing), "garlic (2), bolognese (1), chicken (1), pho (1)"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestMarkup(t *testing.T) {
+	lexicon := NewLexicon([]Pronunciation{
+		{Word: "pho", IPA: "fʌ"},
+		{Word: "pho bo", IPA: "fʌ ˈbɔ"},
+		{Word: "gnocchi", XSAMPA: `"njoUki`},
+		{Word: "bruschetta", Lang: "it-IT"},
+		{Word: "onion"},
+	})
+	tests := map[string]string{
+		// Whole words only, in any case, keeping the case they were written in
+		"Pho, not phone or phos": `<phoneme alphabet="ipa" ph="fʌ">Pho</phoneme>, not phone or phos`,
+		"gnocchi2 and gnocchi":   `gnocchi2 and <phoneme alphabet="x-sampa" ph="&quot;njoUki">gnocchi</phoneme>`,
+		// Phrases win over the words in them
+		"pho bo or pho": `<phoneme alphabet="ipa" ph="fʌ ˈbɔ">pho bo</phoneme> or <phoneme alphabet="ipa" ph="fʌ">pho</phoneme>`,
+		// The text around and inside the tags is escaped
+		"Bruschetta & <onion>": `<lang xml:lang="it-IT">Bruschetta</lang> &amp; &lt;onion&gt;`,
+		"fish & chips":         "fish &amp; chips",
+	}
+	for text, want := range tests {
+		if got := lexicon.Markup(text); got != want {
+			t.Errorf("Markup(%q) = %s, want %s", text, got, want)
+		}
+	}
+	if got := (*Lexicon)(nil).Markup("pho & gnocchi"); got != "pho &amp; gnocchi" {
+		t.Errorf("got %s from a nil lexicon, want the text escaped", got)
+	}
+}
+
+func TestMarkupSSML(t *testing.T) {
+	lexicon := NewLexicon([]Pronunciation{
+		{Word: "pho", IPA: "fʌ"},
+		{Word: "quinoa", IPA: "ˈkiːnwɑː"},
+	})
+	tests := map[string]string{
+		// Text between other tags is marked up
+		"<speak>Try <emphasis>pho</emphasis>.<break time=\"1s\"/> Or quinoa</speak>": `<speak>Try <emphasis><phoneme alphabet="ipa" ph="fʌ">pho</phoneme></emphasis>.<break time="1s"/> Or <phoneme alphabet="ipa" ph="ˈkiːnwɑː">quinoa</phoneme></speak>`,
+		// Text already given a pronunciation is left alone, however deeply nested
+		`<speak><sub alias="fuh">pho</sub> and <lang xml:lang="en-GB"><emphasis>quinoa</emphasis></lang> then pho</speak>`: `<speak><sub alias="fuh">pho</sub> and <lang xml:lang="en-GB"><emphasis>quinoa</emphasis></lang> then <phoneme alphabet="ipa" ph="fʌ">pho</phoneme></speak>`,
+		`<speak><say-as interpret-as="spell-out">pho</say-as> <phoneme alphabet="ipa" ph="fo">pho</phoneme></speak>`:       `<speak><say-as interpret-as="spell-out">pho</say-as> <phoneme alphabet="ipa" ph="fo">pho</phoneme></speak>`,
+		// Escaped text isn't escaped again, and tag attributes aren't words
+		`<speak>pho &amp; <audio src="https://example.com/pho.mp3"/></speak>`: `<speak><phoneme alphabet="ipa" ph="fʌ">pho</phoneme> &amp; <audio src="https://example.com/pho.mp3"/></speak>`,
+	}
+	for ssml, want := range tests {
+		if got := lexicon.markupSSML(ssml); got != want {
+			t.Errorf("markupSSML(%s)\n got %s\nwant %s", ssml, got, want)
+		}
+	}
+}
+
+func TestPronunciationInterceptor(t *testing.T) {
+	interceptor := PronunciationInterceptor(NewLexicon([]Pronunciation{{Word: "pho", IPA: "fʌ"}}))
+	response := alexa.Response{Body: alexa.ResponseBody{
+		OutputSpeech: &alexa.OutputSpeech{Type: alexa.PlainText, Text: "Pho & noodles"},
+		Reprompt:     &alexa.Reprompt{OutputSpeech: &alexa.OutputSpeech{Type: alexa.PlainText, Text: "Anything else?"}},
+	}}
+	if err := interceptor.Process(&HandlerInput{}, &response); err != nil {
+		t.Fatal(err)
+	}
+	if speech := response.Body.OutputSpeech; speech.Type != alexa.SSML || speech.SSML != `<speak><phoneme alphabet="ipa" ph="fʌ">Pho</phoneme> &amp; noodles</speak>` {
+		t.Errorf("got %+v, want the speech turned into SSML with pho marked up", speech)
+	}
+	if reprompt := response.Body.Reprompt.OutputSpeech; reprompt.Type != alexa.PlainText || reprompt.Text != "Anything else?" {
+		t.Errorf("got %+v, want a reprompt without lexicon words left as plain text", reprompt)
+	}
+}
@@ -0,0 +1,142 @@
+package skill
+
+import (
+	"log"
+	"strings"
+
+	"github.com/mongodb-developer/alexa-golang-example/alexa"
+)
+
+// How much detail a user wants in answers
+type Verbosity string
+
+const (
+	Brief    Verbosity = "brief"
+	Normal   Verbosity = "normal"
+	Detailed Verbosity = "detailed"
+)
+
+// The verbosities from least to most detailed
+var Verbosities = []Verbosity{Brief, Normal, Detailed}
+
+// The SSML prosody rates a user can choose, from slowest to fastest
+var SpeechRates = []string{"x-slow", "slow", "medium", "fast", "x-fast"}
+
+// The rate Alexa speaks at unless told otherwise
+const DefaultSpeechRate = "medium"
+
+// How a user likes to be spoken to
+type Preferences struct {
+	Verbosity  Verbosity
+	SpeechRate string
+}
+
+// The attributes preferences are kept in
+const (
+	verbosityAttribute  = "verbosity"
+	speechRateAttribute = "speechRate"
+)
+
+// Where preferences are kept: the persistent attributes, or the session
+// attributes for skills without a persistence adapter
+func preferenceAttributes(input *HandlerInput) (map[string]interface{}, error) {
+	attributes, err := input.Attributes.PersistentAttributes()
+	if err == ErrNoPersistence {
+		return input.Attributes.SessionAttributes(), nil
+	}
+	return attributes, err
+}
+
+// Sets the user's preferences on the input and, once LocaleInterceptor has
+// set the messages, swaps in the variants for their verbosity, so a message
+// named "undoPrompt.brief" is used for "undoPrompt" by users who want brief
+// answers. New runs it after the skill's own request interceptors.
+func PreferencesInterceptor() RequestInterceptor {
+	return RequestInterceptorFunc(func(input *HandlerInput) error {
+		input.Preferences = Preferences{Verbosity: Normal, SpeechRate: DefaultSpeechRate}
+		attributes, err := preferenceAttributes(input)
+		if err != nil {
+			// Not knowing how the user likes to be spoken to isn't worth failing the request over
+			log.Printf("unable to read the preferences for %s, using the defaults: %v", input.Request.Body.RequestID, err)
+			input.Messages = input.Messages.ForVerbosity(Normal)
+			return nil
+		}
+		for _, verbosity := range Verbosities {
+			if attributes[verbosityAttribute] == string(verbosity) {
+				input.Preferences.Verbosity = verbosity
+			}
+		}
+		for _, rate := range SpeechRates {
+			if attributes[speechRateAttribute] == rate {
+				input.Preferences.SpeechRate = rate
+			}
+		}
+		input.Messages = input.Messages.ForVerbosity(input.Preferences.Verbosity)
+		return nil
+	})
+}
+
+// Changes the user's preferences, which are saved with the persistent
+// attributes by SaveAttributesInterceptor
+func (input *HandlerInput) SetPreferences(preferences Preferences) error {
+	attributes, err := preferenceAttributes(input)
+	if err != nil {
+		return err
+	}
+	attributes[verbosityAttribute] = string(preferences.Verbosity)
+	attributes[speechRateAttribute] = preferences.SpeechRate
+	input.Preferences = preferences
+	return nil
+}
+
+// The messages with the variants for a verbosity in place of the messages they vary
+func (messages Messages) ForVerbosity(verbosity Verbosity) Messages {
+	varied := make(Messages, len(messages))
+	for name, text := range messages {
+		varied[name] = text
+	}
+	suffix := "." + string(verbosity)
+	for name, text := range messages {
+		if strings.HasSuffix(name, suffix) {
+			varied[strings.TrimSuffix(name, suffix)] = text
+		}
+	}
+	return varied
+}
+
+// Renders the output speech and reprompt at the user's speech rate, turning
+// plain text into SSML when the rate isn't the default. New runs it before
+// the skill's own response interceptors, so SSMLLimitInterceptor limits what is sent.
+func SpeechRateInterceptor() ResponseInterceptor {
+	return ResponseInterceptorFunc(func(input *HandlerInput, response *alexa.Response) error {
+		rate := input.Preferences.SpeechRate
+		if rate == "" || rate == DefaultSpeechRate {
+			return nil
+		}
+		withRate(response.Body.OutputSpeech, rate)
+		if response.Body.Reprompt != nil {
+			withRate(response.Body.Reprompt.OutputSpeech, rate)
+		}
+		return nil
+	})
+}
+
+func withRate(speech *alexa.OutputSpeech, rate string) {
+	if speech == nil {
+		return
+	}
+	ssml := speech.SSML
+	if speech.Type != alexa.SSML {
+		ssml = "<speak>" + EscapeSSML(speech.Text) + "</speak>"
+	}
+	inner := strings.TrimSuffix(strings.TrimPrefix(ssml, "<speak>"), "</speak>")
+	speech.Type, speech.Text = alexa.SSML, ""
+	speech.SSML = `<speak><prosody rate="` + rate + `">` + inner + "</prosody></speak>"
+}
+
+var ssmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// Escapes plain text for use in SSML
+func EscapeSSML(text string) string {
+	return ssmlEscaper.Replace(text)
+}
@@ -0,0 +1,81 @@
+package skill
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mongodb-developer/alexa-golang-example/alexa"
+)
+
+func TestNewAppliesPreferences(t *testing.T) {
+	catalog := Catalog{"en": {
+		"suggestion":       "How about pho tonight? It's quick to make.",
+		"suggestion.brief": "How about pho?",
+	}}
+	skill := New().AddRequestInterceptors(
+		LocaleInterceptor(catalog, "en-US"),
+	).AddResponseInterceptors(
+		PronunciationInterceptor(NewLexicon([]Pronunciation{{Word: "pho", IPA: "fʌ"}})),
+		SSMLLimitInterceptor(MaxSpeechLength),
+	).AddRequestHandlers(
+		FallbackHandler(func(input *HandlerInput) error {
+			input.Response.Speak(input.Messages.Format("suggestion"))
+			return nil
+		}),
+	)
+	request := alexa.Request{Session: &alexa.Session{Attributes: map[string]alexa.Value{
+		"verbosity":  alexa.StringOf("brief"),
+		"speechRate": alexa.StringOf("slow"),
+	}}}
+	response, err := skill.Invoke(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<speak><prosody rate="slow">How about <phoneme alphabet="ipa" ph="fʌ">pho</phoneme>?</prosody></speak>`
+	if speech := response.Body.OutputSpeech; speech == nil || speech.SSML != want {
+		t.Errorf("got %+v, want the brief message at a slow rate with pho marked up", speech)
+	}
+
+	response, err = skill.Invoke(context.Background(), alexa.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if speech := response.Body.OutputSpeech; speech == nil || speech.Type != alexa.SSML || speech.SSML != `<speak>How about <phoneme alphabet="ipa" ph="fʌ">pho</phoneme> tonight? It's quick to make.</speak>` {
+		t.Errorf("got %+v, want the normal message at the default rate", speech)
+	}
+}
+
+// A PersistenceAdapter for a database that can't be reached
+type failingAdapter struct{}
+
+func (failingAdapter) Load(ctx context.Context, request alexa.Request) (map[string]interface{}, error) {
+	return nil, errors.New("server selection error: server selection timeout")
+}
+
+func (failingAdapter) Save(ctx context.Context, request alexa.Request, attributes map[string]interface{}) error {
+	return errors.New("server selection error: server selection timeout")
+}
+
+func TestPreferencesDefaultWhenAttributesCantBeLoaded(t *testing.T) {
+	var seen Preferences
+	skill := New().WithPersistenceAdapter(failingAdapter{}).AddRequestInterceptors(
+		LocaleInterceptor(Catalog{"en": {"done": "Done."}}, "en-US"),
+	).AddRequestHandlers(
+		FallbackHandler(func(input *HandlerInput) error {
+			seen = input.Preferences
+			input.Response.Speak(input.Messages.Format("done"))
+			return nil
+		}),
+	)
+	response, err := skill.Invoke(context.Background(), alexa.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen != (Preferences{Verbosity: Normal, SpeechRate: DefaultSpeechRate}) {
+		t.Errorf("got preferences %+v, want the defaults", seen)
+	}
+	if speech := response.Body.OutputSpeech; speech == nil || speech.Text != "Done." {
+		t.Errorf("got %+v, want the handler's answer", speech)
+	}
+}
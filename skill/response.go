@@ -0,0 +1,67 @@
+package skill
+
+import "github.com/mongodb-developer/alexa-golang-example/alexa"
+
+// Builds the response to a request. Without any calls it produces an empty
+// response that ends the session.
+type ResponseBuilder struct {
+	response alexa.Response
+}
+
+func NewResponseBuilder() *ResponseBuilder {
+	return &ResponseBuilder{response: alexa.Response{Version: "1.0"}}
+}
+
+// Sets plain text speech
+func (builder *ResponseBuilder) Speak(text string) *ResponseBuilder {
+	builder.response.Body.OutputSpeech = &alexa.OutputSpeech{Type: alexa.PlainText, Text: text}
+	return builder
+}
+
+// Sets SSML speech, wrapping it in <speak> tags
+func (builder *ResponseBuilder) SpeakSSML(ssml string) *ResponseBuilder {
+	builder.response.Body.OutputSpeech = &alexa.OutputSpeech{Type: alexa.SSML, SSML: "<speak>" + ssml + "</speak>"}
+	return builder
+}
+
+// Sets what Alexa says if the user doesn't answer, and keeps the session open
+func (builder *ResponseBuilder) Reprompt(text string) *ResponseBuilder {
+	builder.response.Body.Reprompt = &alexa.Reprompt{
+		OutputSpeech: &alexa.OutputSpeech{Type: alexa.PlainText, Text: text},
+	}
+	return builder.ShouldEndSession(false)
+}
+
+func (builder *ResponseBuilder) SimpleCard(title string, content string) *ResponseBuilder {
+	builder.response.Body.Card = &alexa.Card{Type: alexa.SimpleCard, Title: title, Content: content}
+	return builder
+}
+
+// Asks the user to link their account in the Alexa app
+func (builder *ResponseBuilder) LinkAccountCard() *ResponseBuilder {
+	builder.response.Body.Card = &alexa.Card{Type: alexa.LinkAccountCard}
+	return builder
+}
+
+func (builder *ResponseBuilder) AddDirective(directive alexa.Directive) *ResponseBuilder {
+	builder.response.Body.Directives = append(builder.response.Body.Directives, directive)
+	return builder
+}
+
+func (builder *ResponseBuilder) ShouldEndSession(end bool) *ResponseBuilder {
+	builder.response.Body.ShouldEndSession = alexa.Bool(end)
+	return builder
+}
+
+// The speech set so far, whether plain text or SSML
+func (builder *ResponseBuilder) OutputSpeech() *alexa.OutputSpeech {
+	return builder.response.Body.OutputSpeech
+}
+
+func (builder *ResponseBuilder) Build() alexa.Response {
+	response := builder.response
+	if response.Body.ShouldEndSession == nil {
+		response.Body.ShouldEndSession = alexa.Bool(true)
+	}
+	return response
+}
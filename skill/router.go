@@ -0,0 +1,43 @@
+package skill
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mongodb-developer/alexa-golang-example/alexa"
+)
+
+// Serves several skills from one deployment, routing each request by the
+// applicationId it was sent to
+type Router struct {
+	skills   map[string]*Skill
+	fallback *Skill
+}
+
+func NewRouter() *Router {
+	return &Router{skills: make(map[string]*Skill)}
+}
+
+// Routes requests for applicationID to skill. An empty applicationID makes
+// skill the fallback for requests no other skill is registered for.
+func (router *Router) Handle(applicationID string, skill *Skill) *Router {
+	if applicationID == "" {
+		router.fallback = skill
+	} else {
+		router.skills[applicationID] = skill
+	}
+	return router
+}
+
+// Processes a request with the skill registered for its applicationId
+func (router *Router) Invoke(ctx context.Context, request alexa.Request) (alexa.Response, error) {
+	applicationID := request.ApplicationID()
+	skill, ok := router.skills[applicationID]
+	if !ok {
+		skill = router.fallback
+	}
+	if skill == nil {
+		return alexa.Response{}, fmt.Errorf("no skill is registered for application %q", applicationID)
+	}
+	return skill.Invoke(ctx, request)
+}
@@ -0,0 +1,67 @@
+package skill
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mongodb-developer/alexa-golang-example/alexa"
+)
+
+// A skill that answers every request with its name
+func namedSkill(name string) *Skill {
+	return New().AddRequestHandlers(FallbackHandler(func(input *HandlerInput) error {
+		input.Response.Speak(name)
+		return nil
+	}))
+}
+
+// A request sent to the application in its context and session, either of
+// which may be empty
+func requestFor(contextApplication string, sessionApplication string) alexa.Request {
+	request := alexa.Request{Body: alexa.RequestBody{Type: "LaunchRequest"}}
+	if contextApplication != "" {
+		request.Context = &alexa.Context{System: alexa.System{Application: alexa.Application{ApplicationID: contextApplication}}}
+	}
+	if sessionApplication != "" {
+		request.Session = &alexa.Session{Application: alexa.Application{ApplicationID: sessionApplication}}
+	}
+	return request
+}
+
+func TestRouterRoutesByApplication(t *testing.T) {
+	router := NewRouter().
+		Handle("amzn1.ask.skill.recipes", namedSkill("old recipes")).
+		Handle("amzn1.ask.skill.recipes", namedSkill("recipes")).
+		Handle("amzn1.ask.skill.cocktails", namedSkill("cocktails")).
+		Handle("", namedSkill("fallback"))
+	tests := []struct {
+		name    string
+		request alexa.Request
+		want    string
+	}{
+		{"from the context", requestFor("amzn1.ask.skill.cocktails", ""), "cocktails"},
+		{"from the session", requestFor("", "amzn1.ask.skill.recipes"), "recipes"},
+		{"context before session", requestFor("amzn1.ask.skill.cocktails", "amzn1.ask.skill.recipes"), "cocktails"},
+		{"unregistered", requestFor("amzn1.ask.skill.other", ""), "fallback"},
+		{"no application", requestFor("", ""), "fallback"},
+	}
+	for _, test := range tests {
+		response, err := router.Invoke(context.Background(), test.request)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if speech := response.Body.OutputSpeech; speech == nil || speech.Text != test.want {
+			t.Errorf("%s: got %+v, want %q", test.name, speech, test.want)
+		}
+	}
+}
+
+func TestRouterWithoutFallback(t *testing.T) {
+	router := NewRouter().Handle("amzn1.ask.skill.recipes", namedSkill("recipes"))
+	if _, err := router.Invoke(context.Background(), requestFor("amzn1.ask.skill.other", "")); err == nil {
+		t.Error("an unregistered application was answered without a fallback")
+	}
+	if _, err := router.Invoke(context.Background(), requestFor("", "")); err == nil {
+		t.Error("a request without an application was answered without a fallback")
+	}
+}
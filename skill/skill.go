@@ -0,0 +1,228 @@
+// Package skill is a small framework for building Alexa skills on AWS
+// Lambda. A Skill routes each request to the first handler that can handle
+// it, running request interceptors before the handler and response
+// interceptors after it, and hands errors to the first matching error handler.
+package skill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"github.com/mongodb-developer/alexa-golang-example/alexa"
+)
+
+// Returned by Invoke when no handler can handle a request
+var ErrNoHandler = errors.New("no handler can handle the request")
+
+// Spoken when a handler panics and no error handler accepts the PanicError
+const DefaultPanicSpeech = "Sorry, something went wrong. Please try again."
+
+// The message spoken instead of the panic speech when the request's messages
+// have it, so the apology is in the user's language
+const ApologyMessage = "apology"
+
+// The error a panic in a handler or interceptor is converted into
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (err *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", err.Value)
+}
+
+// Everything a handler or interceptor needs to process a single request
+type HandlerInput struct {
+	Context    context.Context
+	Request    alexa.Request
+	Attributes *AttributesManager
+	Response   *ResponseBuilder
+	// When the skill started processing the request
+	StartedAt time.Time
+	// Set by LocaleInterceptor
+	Locale   string
+	Messages Messages
+	// Set by PreferencesInterceptor
+	Preferences Preferences
+	// Set by AccountLinkingInterceptor
+	LinkedUserID string
+}
+
+// Handles the requests it reports it can handle
+type Handler interface {
+	CanHandle(input *HandlerInput) bool
+	Handle(input *HandlerInput) error
+}
+
+// Runs before the handler. Returning an error skips the handler and goes
+// straight to the error handlers.
+type RequestInterceptor interface {
+	Process(input *HandlerInput) error
+}
+
+// Runs after the handler, or the error handler, with the response that is
+// about to be returned
+type ResponseInterceptor interface {
+	Process(input *HandlerInput, response *alexa.Response) error
+}
+
+// Turns errors returned by handlers and interceptors into responses
+type ErrorHandler interface {
+	CanHandle(input *HandlerInput, err error) bool
+	Handle(input *HandlerInput, err error) error
+}
+
+// A set of handlers, interceptors and error handlers that make up a skill
+type Skill struct {
+	handlers             []Handler
+	requestInterceptors  []RequestInterceptor
+	responseInterceptors []ResponseInterceptor
+	errorHandlers        []ErrorHandler
+	persistence          PersistenceAdapter
+	panicSpeech          string
+	// Run after the request interceptors and before the response
+	// interceptors, so every skill speaks the way each user chose
+	preferences RequestInterceptor
+	speechRate  ResponseInterceptor
+}
+
+// A skill applying each user's Preferences, with PreferencesInterceptor after
+// the request interceptors added to it and SpeechRateInterceptor before the
+// response interceptors
+func New() *Skill {
+	return &Skill{panicSpeech: DefaultPanicSpeech, preferences: PreferencesInterceptor(), speechRate: SpeechRateInterceptor()}
+}
+
+// Adds handlers, which are tried in the order they were added
+func (skill *Skill) AddRequestHandlers(handlers ...Handler) *Skill {
+	skill.handlers = append(skill.handlers, handlers...)
+	return skill
+}
+
+func (skill *Skill) AddRequestInterceptors(interceptors ...RequestInterceptor) *Skill {
+	skill.requestInterceptors = append(skill.requestInterceptors, interceptors...)
+	return skill
+}
+
+func (skill *Skill) AddResponseInterceptors(interceptors ...ResponseInterceptor) *Skill {
+	skill.responseInterceptors = append(skill.responseInterceptors, interceptors...)
+	return skill
+}
+
+// Adds error handlers, which are tried in the order they were added
+func (skill *Skill) AddErrorHandlers(handlers ...ErrorHandler) *Skill {
+	skill.errorHandlers = append(skill.errorHandlers, handlers...)
+	return skill
+}
+
+// Sets where persistent attributes are loaded from and saved to
+func (skill *Skill) WithPersistenceAdapter(adapter PersistenceAdapter) *Skill {
+	skill.persistence = adapter
+	return skill
+}
+
+// Sets what is spoken when a handler panics and no error handler accepts the
+// PanicError, unless the request's messages have an ApologyMessage
+func (skill *Skill) WithPanicSpeech(speech string) *Skill {
+	skill.panicSpeech = speech
+	return skill
+}
+
+// Processes a request, suitable for passing to EventHandler or lambda.Start.
+// A panic in a handler or interceptor is logged and answered with an apology
+// rather than crashing the invocation.
+func (skill *Skill) Invoke(ctx context.Context, request alexa.Request) (alexa.Response, error) {
+	input := &HandlerInput{
+		Context:    ctx,
+		Request:    request,
+		Attributes: newAttributesManager(ctx, request, skill.persistence),
+		Response:   NewResponseBuilder(),
+		StartedAt:  time.Now(),
+	}
+
+	if err := recovered(func() error { return skill.handle(input) }); err != nil {
+		err = recovered(func() error { return skill.handleError(input, err) })
+		var panicErr *PanicError
+		if errors.As(err, &panicErr) {
+			input.Response = NewResponseBuilder().Speak(skill.apology(input))
+		} else if err != nil {
+			return alexa.Response{}, err
+		}
+	}
+
+	response := input.Response.Build()
+	attributes, err := alexa.ValueOf(input.Attributes.SessionAttributes())
+	if err != nil {
+		return alexa.Response{}, err
+	}
+	response.SessionAttributes = attributes.Object
+	err = recovered(func() error {
+		if err := skill.speechRate.Process(input, &response); err != nil {
+			return err
+		}
+		for _, interceptor := range skill.responseInterceptors {
+			if err := interceptor.Process(input, &response); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		response = NewResponseBuilder().Speak(skill.apology(input)).Build()
+	} else if err != nil {
+		return alexa.Response{}, err
+	}
+	return response, nil
+}
+
+// Runs process, converting a panic into a logged PanicError
+func recovered(process func() error) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			panicErr := &PanicError{Value: value, Stack: debug.Stack()}
+			log.Printf("recovered from %v\n%s", panicErr, panicErr.Stack)
+			err = panicErr
+		}
+	}()
+	return process()
+}
+
+func (skill *Skill) handle(input *HandlerInput) error {
+	for _, interceptor := range skill.requestInterceptors {
+		if err := interceptor.Process(input); err != nil {
+			return err
+		}
+	}
+	if err := skill.preferences.Process(input); err != nil {
+		return err
+	}
+	for _, handler := range skill.handlers {
+		if handler.CanHandle(input) {
+			return handler.Handle(input)
+		}
+	}
+	return ErrNoHandler
+}
+
+func (skill *Skill) handleError(input *HandlerInput, err error) error {
+	for _, handler := range skill.errorHandlers {
+		if handler.CanHandle(input, err) {
+			input.Response = NewResponseBuilder()
+			return handler.Handle(input, err)
+		}
+	}
+	return err
+}
+
+// What is spoken when a panic isn't handled
+func (skill *Skill) apology(input *HandlerInput) string {
+	if text, ok := input.Messages[ApologyMessage]; ok {
+		return text
+	}
+	return skill.panicSpeech
+}
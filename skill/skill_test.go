@@ -0,0 +1,37 @@
+package skill
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mongodb-developer/alexa-golang-example/alexa"
+)
+
+func TestPanicSpeaksApologyForLocale(t *testing.T) {
+	catalog := Catalog{
+		"en": {ApologyMessage: "Sorry, I had trouble with that."},
+		"de": {ApologyMessage: "Entschuldigung, das hat nicht geklappt."},
+	}
+	panicking := FallbackHandler(func(input *HandlerInput) error {
+		panic("handler bug")
+	})
+	localized := New().AddRequestInterceptors(LocaleInterceptor(catalog, "en-US")).AddRequestHandlers(panicking)
+	for locale, want := range map[string]string{"de-DE": "Entschuldigung, das hat nicht geklappt.", "en-GB": "Sorry, I had trouble with that."} {
+		response, err := localized.Invoke(context.Background(), alexa.Request{Body: alexa.RequestBody{Locale: locale}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if speech := response.Body.OutputSpeech; speech == nil || speech.Text != want {
+			t.Errorf("%s: got %+v, want %q", locale, speech, want)
+		}
+	}
+
+	// Without messages, the panic speech set on the skill
+	response, err := New().WithPanicSpeech("Oops.").AddRequestHandlers(panicking).Invoke(context.Background(), alexa.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if speech := response.Body.OutputSpeech; speech == nil || speech.Text != "Oops." {
+		t.Errorf("got %+v, want the panic speech", speech)
+	}
+}
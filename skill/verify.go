@@ -0,0 +1,196 @@
+package skill
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How far a request's timestamp may be from the current time
+const MaxRequestAge = 150 * time.Second
+
+// The name the certificate Alexa signs requests with is issued to
+const alexaCertName = "echo-api.amazon.com"
+
+// Returned when a request sent to an HTTPS endpoint can't be shown to come from Alexa
+var ErrUnverified = errors.New("the request isn't signed by Alexa")
+
+// Checks that requests sent to an HTTPS endpoint were signed by Alexa and
+// are recent, as Amazon requires of skills hosted as web services. Requests
+// Alexa sends to Lambda directly don't need this.
+type Verifier struct {
+	// Fetches the certificate chains, http.DefaultClient when nil
+	Client *http.Client
+	// The roots the chains must lead to, the system roots when nil
+	Roots *x509.CertPool
+	// The current time, time.Now when nil
+	Now func() time.Time
+
+	mutex sync.Mutex
+	// Verified signing certificates by the URL of their chain
+	certs map[string]*x509.Certificate
+}
+
+// Verifies the Signature-256 header, or the older SHA-1 Signature header, of
+// a request with the certificate chain at its SignatureCertChainUrl, and that
+// its timestamp is within MaxRequestAge
+func (verifier *Verifier) Verify(ctx context.Context, header http.Header, body []byte) error {
+	cert, err := verifier.cert(ctx, header.Get("SignatureCertChainUrl"))
+	if err != nil {
+		return err
+	}
+	hash, encoded := crypto.SHA256, header.Get("Signature-256")
+	if encoded == "" {
+		hash, encoded = crypto.SHA1, header.Get("Signature")
+	}
+	signature, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || encoded == "" {
+		return fmt.Errorf("%w: missing or malformed signature", ErrUnverified)
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: the certificate doesn't hold an RSA key", ErrUnverified)
+	}
+	if err := rsa.VerifyPKCS1v15(key, hash, digest(hash, body), signature); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnverified, err)
+	}
+
+	var probe struct {
+		Request struct {
+			Timestamp string `json:"timestamp"`
+		} `json:"request"`
+	}
+	if err := json.Unmarshal(body, &probe); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnverified, err)
+	}
+	timestamp, err := time.Parse(time.RFC3339, probe.Request.Timestamp)
+	if err != nil {
+		return fmt.Errorf("%w: missing or malformed timestamp", ErrUnverified)
+	}
+	if age := verifier.now().Sub(timestamp); math.Abs(float64(age)) > float64(MaxRequestAge) {
+		return fmt.Errorf("%w: the timestamp is %s from now", ErrUnverified, age.Round(time.Second))
+	}
+	return nil
+}
+
+func digest(hash crypto.Hash, body []byte) []byte {
+	if hash == crypto.SHA1 {
+		sum := sha1.Sum(body)
+		return sum[:]
+	}
+	sum := sha256.Sum256(body)
+	return sum[:]
+}
+
+func (verifier *Verifier) now() time.Time {
+	if verifier.Now != nil {
+		return verifier.Now()
+	}
+	return time.Now()
+}
+
+// The signing certificate of the chain at chainURL, fetched and verified the
+// first time it is used and kept until it expires
+func (verifier *Verifier) cert(ctx context.Context, chainURL string) (*x509.Certificate, error) {
+	if err := checkCertChainURL(chainURL); err != nil {
+		return nil, err
+	}
+	verifier.mutex.Lock()
+	cert, ok := verifier.certs[chainURL]
+	verifier.mutex.Unlock()
+	if ok && verifier.now().Before(cert.NotAfter) {
+		return cert, nil
+	}
+
+	cert, err := verifier.fetchChain(ctx, chainURL)
+	if err != nil {
+		return nil, err
+	}
+	verifier.mutex.Lock()
+	defer verifier.mutex.Unlock()
+	if verifier.certs == nil {
+		verifier.certs = make(map[string]*x509.Certificate)
+	}
+	verifier.certs[chainURL] = cert
+	return cert, nil
+}
+
+// Accepts only chains Amazon publishes: https://s3.amazonaws.com/echo.api/...
+func checkCertChainURL(chainURL string) error {
+	parsed, err := url.Parse(chainURL)
+	if err != nil || chainURL == "" {
+		return fmt.Errorf("%w: missing or malformed SignatureCertChainUrl", ErrUnverified)
+	}
+	if !strings.EqualFold(parsed.Scheme, "https") ||
+		!strings.EqualFold(parsed.Hostname(), "s3.amazonaws.com") ||
+		(parsed.Port() != "" && parsed.Port() != "443") ||
+		!strings.HasPrefix(path.Clean(parsed.Path), "/echo.api/") {
+		return fmt.Errorf("%w: SignatureCertChainUrl %q isn't Amazon's", ErrUnverified, chainURL)
+	}
+	return nil
+}
+
+func (verifier *Verifier) fetchChain(ctx context.Context, chainURL string) (*x509.Certificate, error) {
+	client := verifier.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, chainURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch %s: %s", chainURL, response.Status)
+	}
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var chain []*x509.Certificate
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnverified, err)
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("%w: no certificates at %s", ErrUnverified, chainURL)
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = chain[0].Verify(x509.VerifyOptions{
+		DNSName:       alexaCertName,
+		Roots:         verifier.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   verifier.now(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnverified, err)
+	}
+	return chain[0], nil
+}
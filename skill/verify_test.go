@@ -0,0 +1,173 @@
+package skill
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mongodb-developer/alexa-golang-example/alexa"
+)
+
+const testChainURL = "https://s3.amazonaws.com/echo.api/echo-api-cert.pem"
+
+// Answers every request with body, standing in for S3
+type staticTransport []byte
+
+func (body staticTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewReader(body)), Request: request}, nil
+}
+
+// A certificate authority and a certificate it issued to name, standing in for Amazon's
+type testSigner struct {
+	roots *x509.CertPool
+	key   *rsa.PrivateKey
+	chain []byte
+}
+
+func newTestSigner(t *testing.T, name string) testSigner {
+	rootKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, _ := x509.ParseCertificate(rootDER)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, root, &key.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	chain := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER})
+	return testSigner{roots: roots, key: key, chain: chain}
+}
+
+func (signer testSigner) verifier() *Verifier {
+	return &Verifier{Client: &http.Client{Transport: staticTransport(signer.chain)}, Roots: signer.roots}
+}
+
+// A request signed the way Alexa signs them, made at timestamp
+func (signer testSigner) request(t *testing.T, timestamp time.Time) *http.Request {
+	body := fmt.Sprintf(`{"version":"1.0","request":{"type":"LaunchRequest","requestId":"request","timestamp":%q}}`, timestamp.UTC().Format(time.RFC3339))
+	sum := sha256.Sum256([]byte(body))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, signer.key, crypto.SHA256, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := httptest.NewRequest(http.MethodPost, "/alexa", bytes.NewBufferString(body))
+	request.Header.Set("SignatureCertChainUrl", testChainURL)
+	request.Header.Set("Signature-256", base64.StdEncoding.EncodeToString(signature))
+	return request
+}
+
+// Serves request and returns the status and whether the skill was invoked
+func serve(verifier *Verifier, request *http.Request) (int, bool) {
+	invoked := false
+	handler := NewHTTPHandler(func(ctx context.Context, request alexa.Request) (alexa.Response, error) {
+		invoked = true
+		return alexa.Response{Version: "1.0"}, nil
+	}, verifier)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder.Code, invoked
+}
+
+func TestHTTPHandlerAcceptsSignedRequests(t *testing.T) {
+	signer := newTestSigner(t, alexaCertName)
+	if status, invoked := serve(signer.verifier(), signer.request(t, time.Now())); status != http.StatusOK || !invoked {
+		t.Fatalf("got status %d, invoked %v, want the signed request dispatched", status, invoked)
+	}
+}
+
+func TestHTTPHandlerRejectsUnverifiedRequests(t *testing.T) {
+	signer := newTestSigner(t, alexaCertName)
+	tests := map[string]func(request *http.Request){
+		"unsigned": func(request *http.Request) {
+			request.Header.Del("Signature-256")
+		},
+		"tampered": func(request *http.Request) {
+			request.Body = ioutil.NopCloser(bytes.NewBufferString(`{"version":"1.0","request":{"type":"IntentRequest"}}`))
+		},
+		"chain over http": func(request *http.Request) {
+			request.Header.Set("SignatureCertChainUrl", "http://s3.amazonaws.com/echo.api/echo-api-cert.pem")
+		},
+		"chain on another host": func(request *http.Request) {
+			request.Header.Set("SignatureCertChainUrl", "https://notamazon.com/echo.api/echo-api-cert.pem")
+		},
+		"chain outside echo.api": func(request *http.Request) {
+			request.Header.Set("SignatureCertChainUrl", "https://s3.amazonaws.com/echo.api/../invalid.path/echo-api-cert.pem")
+		},
+		"chain on another port": func(request *http.Request) {
+			request.Header.Set("SignatureCertChainUrl", "https://s3.amazonaws.com:563/echo.api/echo-api-cert.pem")
+		},
+	}
+	for name, tamper := range tests {
+		request := signer.request(t, time.Now())
+		tamper(request)
+		if status, invoked := serve(signer.verifier(), request); status != http.StatusBadRequest || invoked {
+			t.Errorf("%s: got status %d, invoked %v, want the request rejected", name, status, invoked)
+		}
+	}
+}
+
+func TestHTTPHandlerRejectsStaleRequests(t *testing.T) {
+	signer := newTestSigner(t, alexaCertName)
+	for _, age := range []time.Duration{-MaxRequestAge - time.Minute, MaxRequestAge + time.Minute} {
+		if status, invoked := serve(signer.verifier(), signer.request(t, time.Now().Add(age))); status != http.StatusBadRequest || invoked {
+			t.Errorf("timestamp %s from now: got status %d, invoked %v, want the request rejected", age, status, invoked)
+		}
+	}
+}
+
+func TestHTTPHandlerWithoutVerifierAcceptsUnsignedRequests(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/alexa", bytes.NewBufferString(`{"version":"1.0","request":{"type":"LaunchRequest"}}`))
+	if status, invoked := serve(nil, request); status != http.StatusOK || !invoked {
+		t.Fatalf("got status %d, invoked %v, want the request dispatched", status, invoked)
+	}
+}
+
+func TestHTTPHandlerRejectsCertificatesForOtherNames(t *testing.T) {
+	signer := newTestSigner(t, "example.com")
+	if status, invoked := serve(signer.verifier(), signer.request(t, time.Now())); status != http.StatusBadRequest || invoked {
+		t.Fatalf("got status %d, invoked %v, want a certificate for another name rejected", status, invoked)
+	}
+}
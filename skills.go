@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/mongodb-developer/alexa-golang-example/cocktails"
+	"github.com/mongodb-developer/alexa-golang-example/recipes"
+	"github.com/mongodb-developer/alexa-golang-example/skill"
+)
+
+// Builds a skill on top of the store its configuration points at
+type SkillBuilder func(store recipes.RecipeStore) *skill.Skill
+
+// The skills this binary knows how to serve, by name
+var skillBuilders = map[string]SkillBuilder{
+	"recipes":   recipes.NewSkill,
+	"cocktails": cocktails.NewSkill,
+}
+
+// Where a skill hosted by this deployment keeps its data, and which
+// applicationId it answers to
+type SkillConfig struct {
+	Name          string `json:"name"`
+	ApplicationID string `json:"applicationId"`
+	Database      string `json:"database"`
+	Collection    string `json:"collection"`
+	// The collection persistent attributes are stored in, "users" by default
+	Users string `json:"users"`
+	// The snapshot written by -snapshot and read when the database is unreachable
+	Snapshot string `json:"snapshot"`
+}
+
+// Reads the skills to serve from the JSON file named by SKILLS_CONFIG. Without
+// it the recipe skill is served alone, for every applicationId, from the
+// alexa.recipes collection and the snapshot at SNAPSHOT_PATH.
+func LoadSkillConfigs() ([]SkillConfig, error) {
+	snapshotPath := os.Getenv("SNAPSHOT_PATH")
+	if snapshotPath == "" {
+		snapshotPath = recipes.DefaultSnapshotPath
+	}
+	path := os.Getenv("SKILLS_CONFIG")
+	if path == "" {
+		return []SkillConfig{{Name: "recipes", Database: "alexa", Collection: "recipes", Users: "users", Snapshot: snapshotPath}}, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var configs []SkillConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("%s: no skills are configured", path)
+	}
+	fallbacks := 0
+	for i := range configs {
+		config := &configs[i]
+		if _, ok := skillBuilders[config.Name]; !ok {
+			return nil, fmt.Errorf("%s: unknown skill %q", path, config.Name)
+		}
+		if config.Database == "" || config.Collection == "" {
+			return nil, fmt.Errorf("%s: skill %q needs a database and collection", path, config.Name)
+		}
+		if config.ApplicationID == "" {
+			fallbacks++
+		}
+		if config.Users == "" {
+			config.Users = "users"
+		}
+		if config.Snapshot == "" {
+			config.Snapshot = config.Name + ".snapshot.json.gz"
+		}
+	}
+	if fallbacks > 1 {
+		return nil, fmt.Errorf("%s: only one skill can leave applicationId empty", path)
+	}
+	return configs, nil
+}
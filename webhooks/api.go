@@ -0,0 +1,189 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mongodb-developer/alexa-golang-example/recipes"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The most deliveries returned by the delivery log endpoint
+const deliveryLogLimit = 100
+
+// Serves the admin API, for requests carrying the admin token as a bearer token:
+//
+//	POST   /webhooks                  registers a subscription, answering with its secret
+//	GET    /webhooks                  lists subscriptions
+//	DELETE /webhooks/{id}             removes a subscription
+//	GET    /webhooks/{id}/deliveries  shows the delivery log of a subscription
+//	PUT    /recipes                   adds or replaces a recipe, notifying subscribers
+func NewAdminHandler(store *Store, catalog *Catalog, adminToken string) http.Handler {
+	return &adminHandler{store: store, catalog: catalog, token: adminToken}
+}
+
+type adminHandler struct {
+	store   *Store
+	catalog *Catalog
+	token   string
+}
+
+func (handler *adminHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	token := strings.TrimPrefix(request.Header.Get("Authorization"), "Bearer ")
+	if handler.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(handler.token)) != 1 {
+		writeError(writer, http.StatusUnauthorized)
+		return
+	}
+	path := strings.Trim(request.URL.Path, "/")
+	parts := strings.Split(path, "/")
+	switch {
+	case path == "webhooks" && request.Method == http.MethodPost:
+		handler.subscribe(writer, request)
+	case path == "webhooks" && request.Method == http.MethodGet:
+		handler.list(writer, request)
+	case len(parts) == 2 && parts[0] == "webhooks" && request.Method == http.MethodDelete:
+		handler.unsubscribe(writer, request, parts[1])
+	case len(parts) == 3 && parts[0] == "webhooks" && parts[2] == "deliveries" && request.Method == http.MethodGet:
+		handler.deliveries(writer, request, parts[1])
+	case path == "recipes" && request.Method == http.MethodPut:
+		handler.saveRecipe(writer, request)
+	default:
+		writeError(writer, http.StatusNotFound)
+	}
+}
+
+func (handler *adminHandler) subscribe(writer http.ResponseWriter, request *http.Request) {
+	var subscription Subscription
+	if err := json.NewDecoder(request.Body).Decode(&subscription); err != nil {
+		writeError(writer, http.StatusBadRequest)
+		return
+	}
+	if endpoint, err := url.Parse(subscription.URL); err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		writeMessage(writer, http.StatusBadRequest, "url must be an https URL")
+		return
+	}
+	for _, eventType := range subscription.Events {
+		if eventType != RecipeCreated && eventType != RecipeUpdated && eventType != "*" {
+			writeMessage(writer, http.StatusBadRequest, "unknown event type "+eventType)
+			return
+		}
+	}
+	if len(subscription.Events) == 0 {
+		subscription.Events = []string{"*"}
+	}
+	subscription, err := handler.store.Subscribe(request.Context(), subscription)
+	if err != nil {
+		internalError(writer, err)
+		return
+	}
+	writeJSON(writer, http.StatusCreated, subscription)
+}
+
+func (handler *adminHandler) list(writer http.ResponseWriter, request *http.Request) {
+	subscriptions, err := handler.store.Subscriptions(request.Context())
+	if err != nil {
+		internalError(writer, err)
+		return
+	}
+	for i := range subscriptions {
+		// Secrets are only shown when a subscription is registered
+		subscriptions[i].Secret = ""
+	}
+	if subscriptions == nil {
+		subscriptions = []Subscription{}
+	}
+	writeJSON(writer, http.StatusOK, subscriptions)
+}
+
+func (handler *adminHandler) unsubscribe(writer http.ResponseWriter, request *http.Request, hexID string) {
+	id, err := primitive.ObjectIDFromHex(hexID)
+	if err != nil {
+		writeError(writer, http.StatusNotFound)
+		return
+	}
+	if err := handler.store.Unsubscribe(request.Context(), id); err == ErrNotFound {
+		writeError(writer, http.StatusNotFound)
+	} else if err != nil {
+		internalError(writer, err)
+	} else {
+		writer.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (handler *adminHandler) deliveries(writer http.ResponseWriter, request *http.Request, hexID string) {
+	id, err := primitive.ObjectIDFromHex(hexID)
+	if err != nil {
+		writeError(writer, http.StatusNotFound)
+		return
+	}
+	deliveries, err := handler.store.Deliveries(request.Context(), id, deliveryLogLimit)
+	if err != nil {
+		internalError(writer, err)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []Delivery{}
+	}
+	writeJSON(writer, http.StatusOK, deliveries)
+}
+
+func (handler *adminHandler) saveRecipe(writer http.ResponseWriter, request *http.Request) {
+	// The fields sent, which are the ones the recipe's JSON and BSON share,
+	// so the fields left out are kept
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		writeMessage(writer, http.StatusBadRequest, "the body must be a recipe with a name")
+		return
+	}
+	var sent map[string]json.RawMessage
+	if err := json.Unmarshal(body, &sent); err != nil {
+		writeMessage(writer, http.StatusBadRequest, "the body must be a recipe with a name")
+		return
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	var recipe recipes.Recipe
+	if err := decoder.Decode(&recipe); err != nil || recipe.Name == "" {
+		writeMessage(writer, http.StatusBadRequest, "the body must be a recipe with a name")
+		return
+	}
+	var fields []string
+	for field := range sent {
+		if field != "id" {
+			fields = append(fields, field)
+		}
+	}
+	saved, err := handler.catalog.SaveRecipe(request.Context(), recipe, fields)
+	if err != nil {
+		internalError(writer, err)
+		return
+	}
+	writeJSON(writer, http.StatusOK, saved)
+}
+
+func writeJSON(writer http.ResponseWriter, status int, value interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	if err := json.NewEncoder(writer).Encode(value); err != nil {
+		log.Printf("unable to encode response: %v", err)
+	}
+}
+
+func writeMessage(writer http.ResponseWriter, status int, message string) {
+	writeJSON(writer, status, map[string]string{"message": message})
+}
+
+func writeError(writer http.ResponseWriter, status int) {
+	writeMessage(writer, status, http.StatusText(status))
+}
+
+func internalError(writer http.ResponseWriter, err error) {
+	log.Printf("admin request failed: %v", err)
+	writeError(writer, http.StatusInternalServerError)
+}
@@ -0,0 +1,169 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mongodb-developer/alexa-golang-example/mongodb"
+	"github.com/mongodb-developer/alexa-golang-example/recipes"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Writes recipes to the catalog, recording an outbox event for every change
+// in the same transaction. Transactions need a replica set, which every Atlas
+// cluster is.
+type Catalog struct {
+	recipes *mongodb.Collection
+	store   *Store
+}
+
+func NewCatalog(recipes *mongodb.Collection, store *Store) *Catalog {
+	return &Catalog{recipes: recipes, store: store}
+}
+
+// Adds the recipe, or sets the fields of the recipe with the same name that
+// are named in fields, keeping the rest, such as the nutrition stored by
+// UpdateNutrition. Fields are named as in the recipe document, and a named
+// field the recipe leaves empty is removed. Returns the recipe as stored.
+// Saving a recipe without changes records no event.
+func (catalog *Catalog) SaveRecipe(ctx context.Context, recipe recipes.Recipe, fields []string) (recipes.Recipe, error) {
+	update, err := updateOf(recipe, fields)
+	if err != nil {
+		return recipes.Recipe{}, err
+	}
+	saved, err := catalog.transact(ctx, func(ctx mongo.SessionContext, client *mongo.Client) (interface{}, error) {
+		return catalog.saveRecipe(ctx, client, recipe, update)
+	})
+	if err != nil {
+		return recipes.Recipe{}, err
+	}
+	return saved.(recipes.Recipe), nil
+}
+
+// Runs operation in a transaction on the current client, passing it the
+// session context and the client
+func (catalog *Catalog) transact(ctx context.Context, operation func(mongo.SessionContext, *mongo.Client) (interface{}, error)) (interface{}, error) {
+	var result interface{}
+	err := catalog.recipes.Client().Do(ctx, func(client *mongo.Client) error {
+		session, err := client.StartSession()
+		if err != nil {
+			return err
+		}
+		defer session.EndSession(ctx)
+		result, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+			return operation(sessionCtx, client)
+		})
+		return err
+	})
+	return result, err
+}
+
+// Saves the recipe in the transaction of ctx, which belongs to client
+func (catalog *Catalog) saveRecipe(ctx mongo.SessionContext, client *mongo.Client, recipe recipes.Recipe, update bson.M) (recipes.Recipe, error) {
+	collection := catalog.recipes.On(client)
+	result, err := collection.UpdateOne(
+		ctx,
+		bson.M{"name": recipe.Name},
+		update,
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return recipes.Recipe{}, err
+	}
+	var saved recipes.Recipe
+	if err := collection.FindOne(ctx, bson.M{"name": recipe.Name}).Decode(&saved); err != nil {
+		return recipes.Recipe{}, err
+	}
+	eventType := RecipeUpdated
+	if result.UpsertedID != nil {
+		eventType = RecipeCreated
+	} else if result.ModifiedCount == 0 {
+		return saved, nil
+	}
+	event := Event{ID: primitive.NewObjectID(), Type: eventType, OccurredAt: time.Now(), Recipe: saved}
+	return saved, catalog.store.enqueue(ctx, client, event)
+}
+
+// Stores the nutrition per serving of every recipe with quantities, computed
+// from the ingredients' nutrition, so recipes can be searched by it. A recipe
+// whose nutrition changes records a recipe.updated event in the same
+// transaction. Returns how many recipes were given nutrition.
+func (catalog *Catalog) UpdateNutrition(ctx context.Context, ingredients *mongodb.Collection) (int, error) {
+	facts, err := recipes.LoadIngredientNutrition(ctx, ingredients)
+	if err != nil {
+		return 0, err
+	}
+	cursor, err := catalog.recipes.Find(ctx, bson.M{"quantities.0": bson.M{"$exists": true}})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+	updated := 0
+	for cursor.Next(ctx) {
+		var recipe recipes.Recipe
+		if err := cursor.Decode(&recipe); err != nil {
+			return updated, err
+		}
+		update, counted := recipes.NutritionUpdate(recipe, facts)
+		_, err := catalog.transact(ctx, func(ctx mongo.SessionContext, client *mongo.Client) (interface{}, error) {
+			return nil, catalog.updateRecipe(ctx, client, recipe.ID, update)
+		})
+		if err != nil {
+			return updated, fmt.Errorf("%s: %v", recipe.Name, err)
+		}
+		if counted {
+			updated++
+		}
+	}
+	return updated, cursor.Err()
+}
+
+// Applies update to a recipe in the transaction of ctx, which belongs to
+// client, recording an event when it changes the recipe
+func (catalog *Catalog) updateRecipe(ctx mongo.SessionContext, client *mongo.Client, id primitive.ObjectID, update bson.M) error {
+	collection := catalog.recipes.On(client)
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil || result.ModifiedCount == 0 {
+		return err
+	}
+	var saved recipes.Recipe
+	if err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&saved); err != nil {
+		return err
+	}
+	event := Event{ID: primitive.NewObjectID(), Type: RecipeUpdated, OccurredAt: time.Now(), Recipe: saved}
+	return catalog.store.enqueue(ctx, client, event)
+}
+
+// The update setting the named fields of a recipe document to those of
+// recipe, and unsetting the ones it leaves empty. The _id is left to the
+// server and the name identifies the recipe.
+func updateOf(recipe recipes.Recipe, fields []string) (bson.M, error) {
+	encoded, err := bson.Marshal(recipe)
+	if err != nil {
+		return nil, err
+	}
+	var document bson.M
+	if err := bson.Unmarshal(encoded, &document); err != nil {
+		return nil, err
+	}
+	set, unset := bson.M{"name": recipe.Name}, bson.M{}
+	for _, field := range fields {
+		if field == "_id" || field == "name" {
+			continue
+		}
+		if value, ok := document[field]; ok {
+			set[field] = value
+		} else {
+			unset[field] = ""
+		}
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update, nil
+}
@@ -0,0 +1,25 @@
+package webhooks
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mongodb-developer/alexa-golang-example/recipes"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestUpdateOfSetsOnlyTheFieldsSent(t *testing.T) {
+	recipe := recipes.Recipe{Name: "Chicken Soup", Ingredients: []string{"chicken", "carrots"}, Servings: 4}
+	update, err := updateOf(recipe, []string{"name", "ingredients", "tags"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := bson.M{
+		"$set":   bson.M{"name": "Chicken Soup", "ingredients": bson.A{"chicken", "carrots"}},
+		"$unset": bson.M{"tags": ""},
+	}
+	// Servings wasn't sent and nutrition is never touched unless sent
+	if !reflect.DeepEqual(update, want) {
+		t.Fatalf("got %v, want %v", update, want)
+	}
+}
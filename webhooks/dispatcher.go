@@ -0,0 +1,185 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Relays outbox events into deliveries and posts the deliveries that are due
+type Dispatcher struct {
+	Store  *Store
+	Client *http.Client
+	// How often to look for work when there was none last time
+	PollInterval time.Duration
+	// How long a claimed event or delivery is reserved for this dispatcher
+	// before another one may take it over
+	Lease time.Duration
+	// Deliveries are given up on after this many attempts
+	MaxAttempts int
+	// The delay before the first retry, doubled for each one after it up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// A dispatcher making up to twelve attempts over about half a day, starting
+// 30 seconds apart and backing off to at most six hours
+func NewDispatcher(store *Store) *Dispatcher {
+	return &Dispatcher{
+		Store:        store,
+		Client:       &http.Client{Timeout: 10 * time.Second},
+		PollInterval: 5 * time.Second,
+		Lease:        time.Minute,
+		MaxAttempts:  12,
+		BaseDelay:    30 * time.Second,
+		MaxDelay:     6 * time.Hour,
+	}
+}
+
+// Dispatches until ctx is done
+func (dispatcher *Dispatcher) Run(ctx context.Context) {
+	for {
+		worked, err := dispatcher.RunOnce(ctx)
+		if err != nil {
+			log.Printf("webhook dispatch failed: %v", err)
+		}
+		if worked && err == nil {
+			continue
+		}
+		select {
+		case <-time.After(dispatcher.PollInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Dispatches until there is nothing left to do now, for running on a schedule.
+// Deliveries waiting to be retried later are left for a later run.
+func (dispatcher *Dispatcher) Drain(ctx context.Context) error {
+	for {
+		worked, err := dispatcher.RunOnce(ctx)
+		if err != nil || !worked {
+			return err
+		}
+	}
+}
+
+// Relays one outbox event and posts one due delivery, reporting whether there
+// was anything to do
+func (dispatcher *Dispatcher) RunOnce(ctx context.Context) (bool, error) {
+	relayed, err := dispatcher.relayNext(ctx)
+	if err != nil {
+		return relayed, err
+	}
+	delivered, err := dispatcher.deliverNext(ctx)
+	return relayed || delivered, err
+}
+
+func (dispatcher *Dispatcher) relayNext(ctx context.Context) (bool, error) {
+	event, err := dispatcher.Store.claimEvent(ctx, dispatcher.Lease)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, dispatcher.Store.relay(ctx, event)
+}
+
+// The parts of the Store deliveries go through
+type deliveryStore interface {
+	claimDelivery(ctx context.Context, lease time.Duration) (Delivery, error)
+	subscription(ctx context.Context, id primitive.ObjectID) (Subscription, error)
+	recordAttempt(ctx context.Context, delivery Delivery, attempt Attempt, status string, nextAttemptAt time.Time) error
+}
+
+func (dispatcher *Dispatcher) deliverNext(ctx context.Context) (bool, error) {
+	return dispatcher.deliverFrom(ctx, dispatcher.Store)
+}
+
+func (dispatcher *Dispatcher) deliverFrom(ctx context.Context, store deliveryStore) (bool, error) {
+	delivery, err := store.claimDelivery(ctx, dispatcher.Lease)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	subscription, err := store.subscription(ctx, delivery.SubscriptionID)
+	if err == ErrNotFound {
+		attempt := Attempt{At: time.Now(), Error: "the subscription was removed"}
+		return true, store.recordAttempt(ctx, delivery, attempt, StatusFailed, time.Time{})
+	} else if err != nil {
+		return true, err
+	}
+
+	attempt := dispatcher.post(ctx, subscription, delivery)
+	status, next := StatusDelivered, time.Time{}
+	if attempt.Error != "" {
+		status = StatusPending
+		if len(delivery.Attempts)+1 >= dispatcher.MaxAttempts {
+			status = StatusFailed
+			log.Printf("giving up on webhook delivery %s to %s: %s", delivery.ID, subscription.URL, attempt.Error)
+		} else {
+			next = time.Now().Add(dispatcher.backoff(len(delivery.Attempts)))
+		}
+	}
+	return true, store.recordAttempt(ctx, delivery, attempt, status, next)
+}
+
+// Posts a signed delivery. Any 2xx answer counts as delivered.
+func (dispatcher *Dispatcher) post(ctx context.Context, subscription Subscription, delivery Delivery) Attempt {
+	attempt := Attempt{At: time.Now()}
+	payload, err := json.Marshal(delivery.Event)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventHeader, delivery.Event.Type)
+	request.Header.Set(DeliveryHeader, delivery.ID)
+	request.Header.Set(SignatureHeader, Sign(subscription.Secret, attempt.At, payload))
+
+	response, err := dispatcher.Client.Do(request)
+	attempt.Duration = time.Since(attempt.At)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(response.Body, 64<<10))
+	attempt.StatusCode = response.StatusCode
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("the endpoint answered %s", response.Status)
+	}
+	return attempt
+}
+
+// Equal jitter backoff: a random delay between half and all of the capped
+// exponential delay, so retries spread out but never come back immediately
+func (dispatcher *Dispatcher) backoff(attempt int) time.Duration {
+	delay := dispatcher.MaxDelay
+	// Compared before shifting, since a shift that overflows can wrap round
+	// to a short delay
+	if attempt < 63 && dispatcher.BaseDelay <= dispatcher.MaxDelay>>uint(attempt) {
+		delay = dispatcher.BaseDelay << uint(attempt)
+	}
+	if delay <= 1 {
+		return delay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)))
+}
@@ -0,0 +1,152 @@
+package webhooks
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestSign(t *testing.T) {
+	got := Sign("whsec_test", time.Unix(1700000000, 0), []byte(`{"type":"recipe.created"}`))
+	want := "t=1700000000,v1=5bd7c1981238b64daf67f6c695e27a720f8a41c958baeb44844c99051b0576de"
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestBackoffStaysWithinBounds(t *testing.T) {
+	for _, dispatcher := range []*Dispatcher{
+		{BaseDelay: 30 * time.Second, MaxDelay: 6 * time.Hour},
+		// Shifted 44 times, this wraps round to under five hours
+		{BaseDelay: 1<<20 + 1, MaxDelay: 6 * time.Hour},
+	} {
+		for attempt := 0; attempt < 70; attempt++ {
+			ceiling := time.Duration(math.Min(float64(dispatcher.BaseDelay)*math.Pow(2, float64(attempt)), float64(dispatcher.MaxDelay)))
+			for i := 0; i < 100; i++ {
+				if delay := dispatcher.backoff(attempt); delay < ceiling/2 || delay >= ceiling {
+					t.Fatalf("base %v, attempt %d: got %v, want at least %v and under %v", dispatcher.BaseDelay, attempt, delay, ceiling/2, ceiling)
+				}
+			}
+		}
+	}
+}
+
+// A deliveryStore holding one delivery, which records the attempt made at it
+type stubDeliveries struct {
+	delivery   *Delivery
+	subscribed Subscription
+	// Set when the subscription was removed
+	removed bool
+
+	attempt Attempt
+	status  string
+	next    time.Time
+}
+
+func (store *stubDeliveries) claimDelivery(ctx context.Context, lease time.Duration) (Delivery, error) {
+	if store.delivery == nil {
+		return Delivery{}, mongo.ErrNoDocuments
+	}
+	return *store.delivery, nil
+}
+
+func (store *stubDeliveries) subscription(ctx context.Context, id primitive.ObjectID) (Subscription, error) {
+	if store.removed {
+		return Subscription{}, ErrNotFound
+	}
+	return store.subscribed, nil
+}
+
+func (store *stubDeliveries) recordAttempt(ctx context.Context, delivery Delivery, attempt Attempt, status string, nextAttemptAt time.Time) error {
+	store.attempt, store.status, store.next = attempt, status, nextAttemptAt
+	return nil
+}
+
+func newTestDispatcher() *Dispatcher {
+	return &Dispatcher{Client: http.DefaultClient, MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour}
+}
+
+func TestDeliverSignsTheEvent(t *testing.T) {
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		received = request
+		body, _ = ioutil.ReadAll(request.Body)
+		writer.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	store := &stubDeliveries{
+		delivery:   &Delivery{ID: "event-subscription", Event: Event{Type: RecipeCreated}},
+		subscribed: Subscription{URL: server.URL, Secret: "whsec_test"},
+	}
+
+	if worked, err := newTestDispatcher().deliverFrom(context.Background(), store); !worked || err != nil {
+		t.Fatalf("got %v, %v, want a delivery", worked, err)
+	}
+	if store.status != StatusDelivered || store.attempt.StatusCode != http.StatusAccepted || store.attempt.Error != "" {
+		t.Errorf("got %s after %+v, want delivered", store.status, store.attempt)
+	}
+	if received.Header.Get(EventHeader) != RecipeCreated || received.Header.Get(DeliveryHeader) != "event-subscription" {
+		t.Errorf("got headers %v", received.Header)
+	}
+	signature := received.Header.Get(SignatureHeader)
+	seconds, err := strconv.ParseInt(strings.TrimPrefix(strings.SplitN(signature, ",", 2)[0], "t="), 10, 64)
+	if err != nil || signature != Sign("whsec_test", time.Unix(seconds, 0), body) {
+		t.Errorf("got signature %q, which doesn't match the body", signature)
+	}
+}
+
+func TestDeliverRetriesThenGivesUp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	dispatcher := newTestDispatcher()
+	delivery := &Delivery{ID: "event-subscription"}
+	store := &stubDeliveries{delivery: delivery, subscribed: Subscription{URL: server.URL}}
+
+	for attempts := 0; attempts < dispatcher.MaxAttempts-1; attempts++ {
+		delivery.Attempts = make([]Attempt, attempts)
+		before := time.Now()
+		if _, err := dispatcher.deliverFrom(context.Background(), store); err != nil {
+			t.Fatal(err)
+		}
+		ceiling := dispatcher.BaseDelay << uint(attempts)
+		if store.status != StatusPending || store.attempt.StatusCode != http.StatusServiceUnavailable ||
+			store.next.Before(before.Add(ceiling/2)) || store.next.After(time.Now().Add(ceiling)) {
+			t.Errorf("after %d attempts got %s, next at %v after %+v, want a retry within %v", attempts, store.status, store.next.Sub(before), store.attempt, ceiling)
+		}
+	}
+
+	delivery.Attempts = make([]Attempt, dispatcher.MaxAttempts-1)
+	if _, err := dispatcher.deliverFrom(context.Background(), store); err != nil {
+		t.Fatal(err)
+	}
+	if store.status != StatusFailed || !store.next.IsZero() || !strings.Contains(store.attempt.Error, "503") {
+		t.Errorf("got %s, next at %v after %+v, want it given up on", store.status, store.next, store.attempt)
+	}
+}
+
+func TestDeliverToRemovedSubscription(t *testing.T) {
+	store := &stubDeliveries{delivery: &Delivery{ID: "event-subscription"}, removed: true}
+	if worked, err := newTestDispatcher().deliverFrom(context.Background(), store); !worked || err != nil {
+		t.Fatalf("got %v, %v, want the delivery handled", worked, err)
+	}
+	if store.status != StatusFailed || store.attempt.Error != "the subscription was removed" || !store.next.IsZero() {
+		t.Errorf("got %s after %+v, want it failed without retrying", store.status, store.attempt)
+	}
+
+	store = &stubDeliveries{}
+	if worked, err := newTestDispatcher().deliverFrom(context.Background(), store); worked || err != nil {
+		t.Errorf("with nothing to deliver got %v, %v", worked, err)
+	}
+}
@@ -0,0 +1,184 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/mongodb-developer/alexa-golang-example/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Keeps subscriptions, the outbox and the delivery log in MongoDB
+type Store struct {
+	subscriptions *mongodb.Collection
+	outbox        *mongodb.Collection
+	deliveries    *mongodb.Collection
+}
+
+// Uses the webhookSubscriptions, outbox and webhookDeliveries collections of database
+func NewStore(client *mongodb.Client, database string) *Store {
+	return &Store{
+		subscriptions: client.Collection(database, "webhookSubscriptions"),
+		outbox:        client.Collection(database, "outbox"),
+		deliveries:    client.Collection(database, "webhookDeliveries"),
+	}
+}
+
+// Registers a subscription, generating its secret when it has none
+func (store *Store) Subscribe(ctx context.Context, subscription Subscription) (Subscription, error) {
+	subscription.ID = primitive.NewObjectID()
+	subscription.CreatedAt = time.Now()
+	if subscription.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return Subscription{}, err
+		}
+		subscription.Secret = hex.EncodeToString(secret)
+	}
+	_, err := store.subscriptions.InsertOne(ctx, subscription)
+	return subscription, err
+}
+
+func (store *Store) Unsubscribe(ctx context.Context, id primitive.ObjectID) error {
+	result, err := store.subscriptions.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (store *Store) Subscriptions(ctx context.Context) ([]Subscription, error) {
+	var subscriptions []Subscription
+	cursor, err := store.subscriptions.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &subscriptions); err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (store *Store) subscription(ctx context.Context, id primitive.ObjectID) (Subscription, error) {
+	var subscription Subscription
+	err := store.subscriptions.FindOne(ctx, bson.M{"_id": id}).Decode(&subscription)
+	if err == mongo.ErrNoDocuments {
+		return Subscription{}, ErrNotFound
+	}
+	return subscription, err
+}
+
+// The most recent deliveries to a subscription, newest first
+func (store *Store) Deliveries(ctx context.Context, subscriptionID primitive.ObjectID, limit int64) ([]Delivery, error) {
+	var deliveries []Delivery
+	cursor, err := store.deliveries.Find(
+		ctx,
+		bson.M{"subscriptionId": subscriptionID},
+		options.Find().SetSort(bson.M{"event.occurredAt": -1}).SetLimit(limit),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// Records an event. Pass the session context of the transaction that made the
+// change and the client the session belongs to.
+func (store *Store) enqueue(ctx context.Context, client *mongo.Client, event Event) error {
+	_, err := store.outbox.On(client).InsertOne(ctx, bson.M{
+		"_id":        event.ID,
+		"event":      event,
+		"dispatched": false,
+	})
+	return err
+}
+
+// Leases the oldest undispatched event, so another dispatcher won't relay it
+// until the lease runs out. Returns mongo.ErrNoDocuments when there is none.
+func (store *Store) claimEvent(ctx context.Context, lease time.Duration) (Event, error) {
+	now := time.Now()
+	var entry struct {
+		Event Event `bson:"event"`
+	}
+	err := store.outbox.FindOneAndUpdate(
+		ctx,
+		bson.M{"dispatched": false, "$or": []bson.M{
+			{"lockedUntil": bson.M{"$exists": false}},
+			{"lockedUntil": bson.M{"$lte": now}},
+		}},
+		bson.M{"$set": bson.M{"lockedUntil": now.Add(lease)}},
+		options.FindOneAndUpdate().SetSort(bson.M{"event.occurredAt": 1}),
+	).Decode(&entry)
+	return entry.Event, err
+}
+
+// Creates a pending delivery of the event for every subscription that wants
+// it and marks the event dispatched. Deliveries that already exist from an
+// earlier, interrupted relay are left alone.
+func (store *Store) relay(ctx context.Context, event Event) error {
+	subscriptions, err := store.Subscriptions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, subscription := range subscriptions {
+		if !subscription.Wants(event.Type) {
+			continue
+		}
+		delivery := Delivery{
+			ID:             event.ID.Hex() + ":" + subscription.ID.Hex(),
+			SubscriptionID: subscription.ID,
+			Event:          event,
+			Status:         StatusPending,
+			NextAttemptAt:  time.Now(),
+			Attempts:       []Attempt{},
+		}
+		_, err := store.deliveries.UpdateOne(
+			ctx,
+			bson.M{"_id": delivery.ID},
+			bson.M{"$setOnInsert": delivery},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = store.outbox.UpdateOne(ctx, bson.M{"_id": event.ID}, bson.M{
+		"$set":   bson.M{"dispatched": true},
+		"$unset": bson.M{"lockedUntil": ""},
+	})
+	return err
+}
+
+// Leases the pending delivery that has waited longest for its next attempt.
+// Returns mongo.ErrNoDocuments when none is due.
+func (store *Store) claimDelivery(ctx context.Context, lease time.Duration) (Delivery, error) {
+	now := time.Now()
+	var delivery Delivery
+	err := store.deliveries.FindOneAndUpdate(
+		ctx,
+		bson.M{"status": StatusPending, "nextAttemptAt": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"nextAttemptAt": now.Add(lease)}},
+		options.FindOneAndUpdate().SetSort(bson.M{"nextAttemptAt": 1}),
+	).Decode(&delivery)
+	return delivery, err
+}
+
+// Logs an attempt and moves the delivery to its next state
+func (store *Store) recordAttempt(ctx context.Context, delivery Delivery, attempt Attempt, status string, nextAttemptAt time.Time) error {
+	_, err := store.deliveries.UpdateOne(ctx, bson.M{"_id": delivery.ID}, bson.M{
+		"$set":  bson.M{"status": status, "nextAttemptAt": nextAttemptAt},
+		"$push": bson.M{"attempts": attempt},
+	})
+	return err
+}
@@ -0,0 +1,99 @@
+// Package webhooks tells partners when recipes are added or changed. Catalog
+// writes record an event in an outbox collection in the same transaction as
+// the recipe, so no change is lost if the process dies. A Dispatcher turns
+// each event into a delivery per subscription and posts it, signed with the
+// subscription's secret, retrying with backoff and logging every attempt.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/mongodb-developer/alexa-golang-example/recipes"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The event types a subscription can ask for
+const (
+	RecipeCreated = "recipe.created"
+	RecipeUpdated = "recipe.updated"
+)
+
+// Delivery states
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// The headers sent with every delivery
+const (
+	SignatureHeader = "X-Recipe-Signature"
+	EventHeader     = "X-Recipe-Event"
+	DeliveryHeader  = "X-Recipe-Delivery"
+)
+
+// Returned when a subscription doesn't exist
+var ErrNotFound = errors.New("webhook subscription not found")
+
+// A partner endpoint that wants to hear about some event types
+type Subscription struct {
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	URL       string             `bson:"url" json:"url"`
+	Secret    string             `bson:"secret" json:"secret,omitempty"`
+	Events    []string           `bson:"events" json:"events"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// Reports whether the subscription asked for events of the type
+func (subscription Subscription) Wants(eventType string) bool {
+	for _, wanted := range subscription.Events {
+		if wanted == eventType || wanted == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// A change to the catalog, as written to the outbox and posted to partners
+type Event struct {
+	ID         primitive.ObjectID `bson:"_id" json:"id"`
+	Type       string             `bson:"type" json:"type"`
+	OccurredAt time.Time          `bson:"occurredAt" json:"occurredAt"`
+	Recipe     recipes.Recipe     `bson:"recipe" json:"recipe"`
+}
+
+// An event on its way to one subscription, with the log of every attempt
+type Delivery struct {
+	// The event ID and subscription ID, so relaying an event twice can't
+	// deliver it twice
+	ID             string             `bson:"_id" json:"id"`
+	SubscriptionID primitive.ObjectID `bson:"subscriptionId" json:"subscriptionId"`
+	Event          Event              `bson:"event" json:"event"`
+	Status         string             `bson:"status" json:"status"`
+	NextAttemptAt  time.Time          `bson:"nextAttemptAt" json:"nextAttemptAt,omitempty"`
+	Attempts       []Attempt          `bson:"attempts" json:"attempts"`
+}
+
+// One try at posting a delivery
+type Attempt struct {
+	At         time.Time     `bson:"at" json:"at"`
+	StatusCode int           `bson:"statusCode,omitempty" json:"statusCode,omitempty"`
+	Error      string        `bson:"error,omitempty" json:"error,omitempty"`
+	Duration   time.Duration `bson:"durationNanos" json:"durationNanos"`
+}
+
+// Computes the signature header for a payload sent at timestamp. Receivers
+// should recompute it over "<t>.<body>" with their secret, compare it in
+// constant time, and reject old timestamps to prevent replays.
+func Sign(secret string, timestamp time.Time, payload []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t + "."))
+	mac.Write(payload)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}